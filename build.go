@@ -26,13 +26,13 @@ func debugf(on bool, format string, args ...interface{}) {
 func listCommands(data *pkg.ParsedData) {
 	fmt.Println("Available commands:")
 	for _, cmd := range data.Commands {
-		if cmd.Name == "_" || pkg.IsLazyName(cmd.Name) {
+		if cmd.Name == "_" || cmd.Private || pkg.IsLazyName(cmd.Name) {
 			continue
 		}
 		if cmd.IsDefault {
-			fmt.Printf("  %s (default)\n", cmd.Name)
+			fmt.Printf("  %s (default)\n", cmd.Label())
 		} else {
-			fmt.Printf("  %s\n", cmd.Name)
+			fmt.Printf("  %s\n", cmd.Label())
 		}
 		if cmd.IsService {
 			port := ""
@@ -46,7 +46,7 @@ func listCommands(data *pkg.ParsedData) {
 				fmt.Printf("    %s\n", l)
 			}
 		}
-		if cmd.WorkDir != "" {
+		if cmd.WorkDir != "" && cmd.WorkDir != cmd.Package {
 			fmt.Printf("    Working dir: %s\n", cmd.WorkDir)
 		}
 		if cmd.Timeout != "" {
@@ -61,7 +61,9 @@ func listCommands(data *pkg.ParsedData) {
 				if i > 0 {
 					fmt.Printf(", ")
 				}
-				if arg.IsOptional {
+				if arg.IsVariadic {
+					fmt.Printf("[%s...]", arg.Name)
+				} else if arg.IsOptional {
 					fmt.Printf("[%s]", arg.Name)
 				} else {
 					fmt.Printf("%s", arg.Name)
@@ -69,15 +71,78 @@ func listCommands(data *pkg.ParsedData) {
 			}
 			fmt.Println()
 		}
-		if len(cmd.Prereqs) > 0 {
-			fmt.Printf("    Depends on: %s\n", cmd.Prereqs)
+		if cmd.Matrix != nil {
+			fmt.Printf("    Matrix: %s\n", strings.Join(cmd.Prereqs, " "))
+		}
+		if pres := publicPrereqs(data, cmd.HeaderPrereqs()); len(pres) > 0 {
+			deps := make([]string, len(pres))
+			for i, pre := range pres {
+				deps[i] = pre
+				if dep, err := data.GetCommand(pre); err == nil && dep != nil {
+					deps[i] = dep.Label()
+				}
+			}
+			fmt.Printf("    Depends on: %s\n", deps)
+		}
+	}
+	if len(data.Profiles) > 0 {
+		fmt.Println("Profiles:")
+		listProfiles(data)
+	}
+}
+
+// listProfiles prints the declared profiles, marking the active one.
+func listProfiles(data *pkg.ParsedData) {
+	for _, name := range data.ProfileNames() {
+		if name == data.Profile {
+			fmt.Printf("  %s (active)\n", name)
+		} else {
+			fmt.Printf("  %s\n", name)
 		}
 	}
 }
 
+func listProfilesJSON(data *pkg.ParsedData) {
+	type profileInfo struct {
+		Name   string            `json:"name"`
+		Vars   map[string]string `json:"vars"`
+		Active bool              `json:"active,omitempty"`
+	}
+	out := []profileInfo{}
+	for _, prof := range data.Profiles {
+		out = append(out, profileInfo{Name: prof.Name, Vars: prof.Vars, Active: prof.Name == data.Profile})
+	}
+	b, _ := json.MarshalIndent(out, "", "  ")
+	fmt.Println(string(b))
+}
+
+// packageDefault is the default command of a workspace package, or "".
+func packageDefault(data *pkg.ParsedData, pkgPath string) string {
+	for _, cmd := range data.Commands {
+		if cmd.Package == pkgPath && cmd.IsDefault {
+			return cmd.Name
+		}
+	}
+	return ""
+}
+
+// publicPrereqs is prereqs without private commands, which listings hide
+// like the commands themselves.
+func publicPrereqs(data *pkg.ParsedData, prereqs []string) []string {
+	var out []string
+	for _, pre := range prereqs {
+		if dep, err := data.GetCommand(pre); err == nil && dep != nil && dep.Private {
+			continue
+		}
+		out = append(out, pre)
+	}
+	return out
+}
+
 func listCommandsJSON(data *pkg.ParsedData) {
 	type cmdInfo struct {
 		Name        string          `json:"name"`
+		Label       string          `json:"label,omitempty"`
 		Description string          `json:"description,omitempty"`
 		Arguments   []*pkg.Argument `json:"arguments,omitempty"`
 		Prereqs     []string        `json:"prereqs,omitempty"`
@@ -88,16 +153,21 @@ func listCommandsJSON(data *pkg.ParsedData) {
 		IsService   bool            `json:"is_service,omitempty"`
 		Port        string          `json:"port,omitempty"`
 	}
-	var out []cmdInfo
+	out := []cmdInfo{}
 	for _, cmd := range data.Commands {
-		if cmd.Name == "_" || pkg.IsLazyName(cmd.Name) {
+		if cmd.Name == "_" || cmd.Private || pkg.IsLazyName(cmd.Name) {
 			continue
 		}
+		label := ""
+		if cmd.Package != "" {
+			label = cmd.Label()
+		}
 		out = append(out, cmdInfo{
 			Name:        cmd.Name,
+			Label:       label,
 			Description: cmd.Description,
 			Arguments:   cmd.Arguments,
-			Prereqs:     cmd.Prereqs,
+			Prereqs:     publicPrereqs(data, cmd.Prereqs),
 			WorkDir:     cmd.WorkDir,
 			Timeout:     cmd.Timeout,
 			Produces:    cmd.Produces,
@@ -110,25 +180,30 @@ func listCommandsJSON(data *pkg.ParsedData) {
 	fmt.Println(string(b))
 }
 
+// dryRunf prints a --dry-run line with secret values masked.
+func dryRunf(format string, args ...any) {
+	fmt.Print(pkg.MaskSecrets(fmt.Sprintf(format, args...)))
+}
+
 func printDryRunBody(body []pkg.BodyStatement, indent int) {
 	prefix := strings.Repeat("  ", indent+1)
 	for _, stmt := range body {
 		switch stmt.Type {
 		case pkg.StmtIf:
-			fmt.Printf("%sif %s {\n", prefix, stmt.Cond)
+			dryRunf("%sif %s {\n", prefix, stmt.Cond)
 			printDryRunBody(stmt.ThenBody, indent+1)
 			elseBody := stmt.ElseBody
 			for len(elseBody) == 1 && elseBody[0].Type == pkg.StmtIf {
 				inner := elseBody[0]
-				fmt.Printf("%s} else if %s {\n", prefix, inner.Cond)
+				dryRunf("%s} else if %s {\n", prefix, inner.Cond)
 				printDryRunBody(inner.ThenBody, indent+1)
 				elseBody = inner.ElseBody
 			}
 			if len(elseBody) > 0 {
-				fmt.Printf("%s} else {\n", prefix)
+				dryRunf("%s} else {\n", prefix)
 				printDryRunBody(elseBody, indent+1)
 			}
-			fmt.Printf("%s}\n", prefix)
+			dryRunf("%s}\n", prefix)
 		case pkg.StmtFor:
 			loopVar := stmt.LoopVar
 			if stmt.LoopIndex != "" {
@@ -141,39 +216,39 @@ func printDryRunBody(body []pkg.BodyStatement, indent int) {
 					keyword = fmt.Sprintf("parallel<%d>", stmt.ParallelJobs)
 				}
 			}
-			fmt.Printf("%s%s %s in %s {\n", prefix, keyword, loopVar, stmt.LoopItems)
+			dryRunf("%s%s %s in %s {\n", prefix, keyword, loopVar, stmt.LoopItems)
 			printDryRunBody(stmt.LoopBody, indent+1)
-			fmt.Printf("%s}\n", prefix)
+			dryRunf("%s}\n", prefix)
 		case pkg.StmtSwitch:
 			mod := ""
 			if stmt.Modifier != "" {
 				mod = "<" + stmt.Modifier + ">"
 			}
-			fmt.Printf("%sswitch%s %s {\n", prefix, mod, stmt.SwitchExpr)
+			dryRunf("%sswitch%s %s {\n", prefix, mod, stmt.SwitchExpr)
 			for _, c := range stmt.Cases {
 				if c.IsDefault {
-					fmt.Printf("%s  default {\n", prefix)
+					dryRunf("%s  default {\n", prefix)
 				} else {
-					fmt.Printf("%s  case %s {\n", prefix, strings.Join(c.Values, ", "))
+					dryRunf("%s  case %s {\n", prefix, strings.Join(c.Values, ", "))
 				}
 				printDryRunBody(c.Body, indent+2)
-				fmt.Printf("%s  }\n", prefix)
+				dryRunf("%s  }\n", prefix)
 			}
-			fmt.Printf("%s}\n", prefix)
+			dryRunf("%s}\n", prefix)
 		case pkg.StmtInDir:
-			fmt.Printf("%sin %s {\n", prefix, stmt.Shell)
+			dryRunf("%sin %s {\n", prefix, stmt.Shell)
 			printDryRunBody(stmt.ThenBody, indent+1)
-			fmt.Printf("%s}\n", prefix)
+			dryRunf("%s}\n", prefix)
 		case pkg.StmtLock:
 			mod := ""
 			if stmt.Modifier != "" {
 				mod = "<" + stmt.Modifier + ">"
 			}
-			fmt.Printf("%slock%s %q {\n", prefix, mod, stmt.Shell)
+			dryRunf("%slock%s %q {\n", prefix, mod, stmt.Shell)
 			printDryRunBody(stmt.ThenBody, indent+1)
-			fmt.Printf("%s}\n", prefix)
+			dryRunf("%s}\n", prefix)
 		case pkg.StmtState:
-			fmt.Printf("%sstate %s = %s\n", prefix, stmt.Shell, stmt.Message)
+			dryRunf("%sstate %s = %s\n", prefix, stmt.Shell, stmt.Message)
 		case pkg.StmtBuiltin:
 			args := stmt.BuiltinArgs
 			if stmt.Tolerant {
@@ -186,33 +261,50 @@ func printDryRunBody(body []pkg.BodyStatement, indent int) {
 			if stmt.Modifier != "" {
 				name = fmt.Sprintf("%s<%s>", name, stmt.Modifier)
 			}
-			fmt.Printf("%s%s %s\n", prefix, name, args)
+			dryRunf("%s%s %s\n", prefix, name, args)
 		case pkg.StmtConfirm:
-			fmt.Printf("%sconfirm %q\n", prefix, stmt.Message)
+			dryRunf("%sconfirm %q\n", prefix, stmt.Message)
 		case pkg.StmtPrompt:
-			fmt.Printf("%sprompt %q\n", prefix, stmt.Message)
+			dryRunf("%sprompt %q\n", prefix, stmt.Message)
 		case pkg.StmtInput:
-			fmt.Printf("%sinput %s %q\n", prefix, stmt.Shell, stmt.Message)
+			dryRunf("%sinput %s %q\n", prefix, stmt.Shell, stmt.Message)
 		case pkg.StmtContinue, pkg.StmtBreak:
-			fmt.Printf("%s%s\n", prefix, stmt.Type)
+			dryRunf("%s%s\n", prefix, stmt.Type)
 		case pkg.StmtPort:
-			fmt.Printf("%sport %s\n", prefix, stmt.Shell)
+			dryRunf("%sport %s\n", prefix, stmt.Shell)
+		case pkg.StmtReady, pkg.StmtHealth:
+			keyword := stmt.Type
+			if stmt.Modifier != "" {
+				keyword = fmt.Sprintf("%s<%s>", keyword, stmt.Modifier)
+			}
+			if stmt.BuiltinArgs == "http" {
+				dryRunf("%s%s http %q\n", prefix, keyword, stmt.Shell)
+			} else {
+				dryRunf("%s%s $ %s\n", prefix, keyword, stmt.Shell)
+			}
+		case pkg.StmtStop:
+			spec := &pkg.StopSpec{Signal: stmt.Shell, After: stmt.Timeout}
+			dryRunf("%sstop %s\n", prefix, spec)
+		case pkg.StmtOnStop:
+			dryRunf("%son_stop {\n", prefix)
+			printDryRunBody(stmt.ThenBody, indent+1)
+			dryRunf("%s}\n", prefix)
 		case pkg.StmtInvoke:
-			fmt.Printf("%sinvoke %s\n", prefix, stmt.Shell)
+			dryRunf("%sinvoke %s\n", prefix, stmt.Shell)
 		case pkg.StmtEnv:
-			fmt.Printf("%senv { %s }\n", prefix, strings.Join(stmt.Env, ", "))
+			dryRunf("%senv { %s }\n", prefix, strings.Join(stmt.Env, ", "))
 		case pkg.StmtFail:
-			fmt.Printf("%sfail %q\n", prefix, stmt.Message)
+			dryRunf("%sfail %q\n", prefix, stmt.Message)
 		case pkg.StmtOnFail:
-			fmt.Printf("%sonfail {\n", prefix)
+			dryRunf("%sonfail {\n", prefix)
 			printDryRunBody(stmt.OnFailBody, indent+1)
-			fmt.Printf("%s}\n", prefix)
+			dryRunf("%s}\n", prefix)
 		default:
 			shell := stmt.Shell
 			if stmt.Timeout != "" {
 				shell = fmt.Sprintf("timeout %s %s", stmt.Timeout, shell)
 			}
-			fmt.Printf("%s%s\n", prefix, shell)
+			dryRunf("%s%s\n", prefix, shell)
 		}
 	}
 }
@@ -254,15 +346,20 @@ func executeBuild(inputs *ConstructInput, o *options, runCtx context.Context) ([
 	if err != nil {
 		return nil, err
 	}
+	if inputs.Package != "" {
+		inputs.Package = data.PackageOf(inputs.Package)
+		for i, t := range inputs.Commands {
+			if inputs.Package != "" && !pkg.IsLabel(t) {
+				inputs.Commands[i] = "//" + inputs.Package + ":" + t
+			}
+		}
+	}
+	if inputs.Commands, err = data.ExpandTargets(inputs.Commands); err != nil {
+		return nil, err
+	}
 
-	flagSet := flag.NewFlagSet("construct", flag.ContinueOnError)
-	flagSet.SetOutput(io.Discard)
-	defineFlags(flagSet, &options{})
 	executor := pkg.NewExecutor(data, o.concurrent, o.debug)
-	executor.RegisterArgumentFlags(flagSet)
-
-	flagSet.ParseErrorsWhitelist.UnknownFlags = false
-	if err := flagSet.Parse(os.Args[1:]); err != nil {
+	if err := bindArgumentFlags(executor, inputs.Commands, o); err != nil {
 		return nil, err
 	}
 
@@ -280,6 +377,7 @@ func executeBuild(inputs *ConstructInput, o *options, runCtx context.Context) ([
 	executor.SetJobs(o.jobs)
 	executor.SetTiming(o.timing)
 	executor.SetNoCache(o.noCache)
+	executor.SetNoDeps(o.noDeps)
 	executor.SetKeepGoing(o.keepGoing)
 	executor.SetQuiet(o.quiet)
 	executor.SetExplain(o.explain)
@@ -290,37 +388,19 @@ func executeBuild(inputs *ConstructInput, o *options, runCtx context.Context) ([
 	executor.SetGithubActions(o.ghActions)
 	executor.SetRecordRuns(true)
 
-	for _, ov := range o.overrides {
-		before, after, ok := strings.Cut(ov, "=")
-		if !ok {
-			return nil, fmt.Errorf("invalid override %q (expected key=value)", ov)
-		}
-
-		key := strings.TrimSpace(before)
-		val := after
-		overridden := false
-
-		for _, v := range data.Variables {
-			if v.Name == key {
-				overridden = true
-				break
-			}
-		}
-
-		data.SetVariable(key, "global", val)
-		if o.debug {
-			if overridden {
-				debugf(o.debug, "Override: %s = %s\n", key, val)
-			} else {
-				debugf(o.debug, "Override (new): %s = %s\n", key, val)
-			}
-		}
+	if err := applyOverrides(data, o); err != nil {
+		return nil, err
 	}
 
 	if o.showList {
-		if o.json {
+		switch {
+		case o.listProfiles && o.json:
+			listProfilesJSON(data)
+		case o.listProfiles:
+			listProfiles(data)
+		case o.json:
 			listCommandsJSON(data)
-		} else {
+		default:
 			listCommands(data)
 		}
 		return nil, nil
@@ -334,6 +414,14 @@ func executeBuild(inputs *ConstructInput, o *options, runCtx context.Context) ([
 		inputs.Commands = chosen
 	}
 
+	if len(inputs.Commands) == 0 && inputs.Package != "" {
+		def := packageDefault(data, inputs.Package)
+		if def == "" {
+			return nil, fmt.Errorf("package //%s has no default command; name a target", inputs.Package)
+		}
+		inputs.Commands = []string{def}
+	}
+
 	if o.since != "" {
 		proceed, err := applySince(inputs, o, data)
 		if err != nil {
@@ -351,15 +439,15 @@ func executeBuild(inputs *ConstructInput, o *options, runCtx context.Context) ([
 				continue
 			}
 			if len(inputs.Commands) == 0 || slices.Contains(inputs.Commands, cmd.Name) {
-				fmt.Printf("  %s\n", cmd.Name)
+				dryRunf("  %s\n", cmd.Name)
 				if cmd.WorkDir != "" {
-					fmt.Printf("    (in %s)\n", cmd.WorkDir)
+					dryRunf("    (in %s)\n", cmd.WorkDir)
 				}
 				if len(cmd.Produces) > 0 {
-					fmt.Printf("    (produces: %s)\n", strings.Join(cmd.Produces, ", "))
+					dryRunf("    (produces: %s)\n", strings.Join(cmd.Produces, ", "))
 				}
 				if len(cmd.FileDeps) > 0 {
-					fmt.Printf("    (deps: %s)\n", strings.Join(cmd.FileDeps, ", "))
+					dryRunf("    (deps: %s)\n", strings.Join(cmd.FileDeps, ", "))
 				}
 				printDryRunBody(cmd.Body, 1)
 			}
@@ -460,3 +548,55 @@ func targetLabelFor(inputs *ConstructInput) string {
 func trimDuration(d time.Duration) string {
 	return d.Round(100 * time.Millisecond).String()
 }
+
+// bindArgumentFlags parses the command line again with targets' argument
+// flags (--deploy:env prod) defined, and binds the passthrough words after
+// `--` to their variadic arguments.
+func bindArgumentFlags(executor *pkg.Executor, targets []string, o *options) error {
+	flagSet := flag.NewFlagSet("construct", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+	defineFlags(flagSet, &options{})
+	executor.RegisterArgumentFlags(flagSet)
+
+	flagSet.ParseErrorsWhitelist.UnknownFlags = false
+	if err := flagSet.Parse(os.Args[1:]); err != nil {
+		return err
+	}
+	return executor.BindPassthrough(targets, o.passthrough)
+}
+
+// applyOverrides sets each -e key=value as a global variable.
+func applyOverrides(data *pkg.ParsedData, o *options) error {
+	for _, ov := range o.overrides {
+		before, after, ok := strings.Cut(ov, "=")
+		if !ok {
+			return fmt.Errorf("invalid override %q (expected key=value)", ov)
+		}
+
+		key := strings.TrimSpace(before)
+		val := after
+		overridden, secret := false, false
+
+		for _, v := range data.Variables {
+			if v.Name == key {
+				overridden = true
+				secret = secret || v.Secret
+			}
+		}
+
+		// An override of a secret is as secret as the value it replaces.
+		if secret {
+			pkg.RegisterSecret(val)
+			pkg.RegisterSecret(strings.Trim(val, `"`))
+		}
+		data.SetVariable(key, "global", val)
+		if o.debug {
+			if overridden {
+				debugf(o.debug, "Override: %s = %s\n", key, pkg.MaskSecrets(val))
+			} else {
+				debugf(o.debug, "Override (new): %s = %s\n", key, val)
+			}
+		}
+	}
+	return nil
+}
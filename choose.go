@@ -250,7 +250,7 @@ func termSize(lastW, lastH int) (int, int) {
 func chooseTargets(data *pkg.ParsedData) ([]string, error) {
 	var items []chooseItem
 	for _, cmd := range data.Commands {
-		if cmd.Name == "_" || pkg.IsLazyName(cmd.Name) {
+		if cmd.Name == "_" || cmd.Private || pkg.IsLazyName(cmd.Name) {
 			continue
 		}
 		desc := ""
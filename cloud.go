@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/ed25519"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -64,14 +65,16 @@ jobs:
 `
 
 func cloudUsage() error {
-	fmt.Fprintln(os.Stderr, "usage: construct cloud <list|pull|push|submit|status|logs|cancel|init-actions>")
+	fmt.Fprintln(os.Stderr, "usage: construct cloud <list|pull|push|keygen|submit|status|logs|cancel|artifacts|init-actions>")
 	fmt.Fprintln(os.Stderr, "  list                   list definitions in the cloud file")
 	fmt.Fprintln(os.Stderr, "  pull [names...]        write cloud definitions into construct-cloud.json")
-	fmt.Fprintln(os.Stderr, "  push [names...]        upload local command bodies into the cloud file")
-	fmt.Fprintln(os.Stderr, "  submit [targets...]    dispatch a GitHub Actions run (--wait to follow it)")
+	fmt.Fprintln(os.Stderr, "  push [names...]        upload local command bodies into the cloud file (--sign KEY)")
+	fmt.Fprintln(os.Stderr, "  keygen <key-file>      create an ed25519 key for push --sign")
+	fmt.Fprintln(os.Stderr, "  submit [targets...]    dispatch a GitHub Actions run or GitLab pipeline (--wait to follow it)")
 	fmt.Fprintln(os.Stderr, "  status <run-id>        show a run's status")
 	fmt.Fprintln(os.Stderr, "  logs <run-id>          print a run's job logs")
 	fmt.Fprintln(os.Stderr, "  cancel <run-id>        cancel a run")
+	fmt.Fprintln(os.Stderr, "  artifacts <run-id>     download and unpack a run's artifacts (--into DIR)")
 	fmt.Fprintln(os.Stderr, "  init-actions           create .github/workflows/construct.yml")
 	return exitAt(2, "")
 }
@@ -101,9 +104,9 @@ func runCloud(args []string, o *options, inputs *ConstructInput) error {
 			fmt.Println("no cloud definitions")
 			return nil
 		}
-		fmt.Printf("%-20s %s\n", "name", "statements")
+		fmt.Printf("%-20s %-10s %s\n", "name", "statements", "signature")
 		for _, en := range entries {
-			fmt.Printf("%-20s %d\n", en.Name, en.BodyStmts)
+			fmt.Printf("%-20s %-10d %s\n", en.Name, en.BodyStmts, en.Signature)
 		}
 	case "pull":
 		executor := pkg.NewExecutor(&pkg.ParsedData{}, o.debug, false)
@@ -130,11 +133,32 @@ func runCloud(args []string, o *options, inputs *ConstructInput) error {
 		} else if data != nil {
 			executor.SetParsedData(data)
 		}
-		n, err := executor.CloudPush(rest, o.fileName)
+		var key ed25519.PrivateKey
+		if o.sign != "" {
+			var err error
+			if key, err = pkg.LoadSigningKey(o.sign); err != nil {
+				return exitAt(1, "cloud push --sign: %v", err)
+			}
+		}
+		n, err := executor.CloudPush(rest, o.fileName, key)
 		if err != nil {
 			return err
 		}
-		fmt.Printf("pushed %d command(s) into the cloud file\n", n)
+		if key != nil {
+			fmt.Printf("pushed %d signed command(s) into the cloud file\n", n)
+		} else {
+			fmt.Printf("pushed %d command(s) into the cloud file\n", n)
+		}
+	case "keygen":
+		if len(rest) != 1 {
+			return cloudUsage()
+		}
+		pub, err := pkg.GenerateSigningKey(rest[0])
+		if err != nil {
+			return exitAt(1, "cloud keygen: %v", err)
+		}
+		fmt.Printf("wrote private key %s (keep it out of the repository)\n", rest[0])
+		fmt.Printf("trust it by adding this line to %s next to the Constfile:\n%s\n", pkg.TrustedKeysFile, pub)
 	case "submit":
 		return runCloudSubmit(rest, o)
 	case "status":
@@ -143,6 +167,8 @@ func runCloud(args []string, o *options, inputs *ConstructInput) error {
 		return runCloudLogs(rest, o)
 	case "cancel":
 		return runCloudCancel(rest, o)
+	case "artifacts":
+		return runCloudArtifacts(rest, o)
 	case "init-actions":
 		return runCloudInitActions(rest)
 	default:
@@ -186,6 +212,56 @@ func actionsClient(o *options) (*pkg.GHClient, error) {
 	return pkg.NewGHClient(repo, token, os.Getenv("CONSTRUCT_GITHUB_API")), nil
 }
 
+// resolveCloudBackend names the backend cloud runs go to: --backend, else
+// the one serving the git remote's host, else GitHub Actions.
+func resolveCloudBackend(o *options) (string, error) {
+	switch o.backend {
+	case "github", "gitlab":
+		return o.backend, nil
+	case "":
+	default:
+		return "", exitAt(2, "unknown cloud backend %q (want github or gitlab)", o.backend)
+	}
+	host, _, err := pkg.GitRemote()
+	if err != nil {
+		return "github", nil
+	}
+	if b := pkg.BackendForHost(host); b != "" {
+		return b, nil
+	}
+	if o.repo != "" {
+		return "github", nil
+	}
+	return "", fmt.Errorf("cannot tell which CI service hosts %s; pass --backend github|gitlab", host)
+}
+
+// cloudBackend connects to the resolved backend. workflow is the GitHub
+// Actions workflow file that submit dispatches; other commands pass "".
+func cloudBackend(o *options, workflow string) (pkg.CloudBackend, error) {
+	backend, err := resolveCloudBackend(o)
+	if err != nil {
+		return nil, err
+	}
+	if backend == "github" {
+		client, err := actionsClient(o)
+		if err != nil {
+			return nil, err
+		}
+		return pkg.NewGitHubBackend(client, workflow), nil
+	}
+	token := pkg.GLToken()
+	if token == "" {
+		return nil, errors.New("no GitLab token found; set GITLAB_TOKEN (or CONSTRUCT_GITLAB_TOKEN) to a token with the api scope")
+	}
+	host, project, err := pkg.GitRemote()
+	if o.repo != "" {
+		project = o.repo
+	} else if err != nil {
+		return nil, fmt.Errorf("%w; pass --repo group/project to override", err)
+	}
+	return pkg.NewGLClient(project, token, os.Getenv("CONSTRUCT_GITLAB_API"), host), nil
+}
+
 func actionsWorkflowPath(workflow string) (string, error) {
 	if workflow == "" || workflow == "." || workflow == ".." ||
 		strings.ContainsAny(workflow, `/\`) {
@@ -213,7 +289,7 @@ func ensureActionsWorkflow(workflow string) (bool, error) {
 }
 
 func runCloudSubmit(args []string, o *options) error {
-	repo, ref, workflow := o.repo, o.ref, o.workflow
+	repo, ref, workflow, backend := o.repo, o.ref, o.workflow, o.backend
 	wait, jsonOut, noInit, force := o.wait, o.json, o.noInit, o.force
 	var targets, extra []string
 	for i := 0; i < len(args); i++ {
@@ -244,6 +320,12 @@ func runCloudSubmit(args []string, o *options) error {
 			if v, ok := next(); ok {
 				workflow = v
 			}
+		case strings.HasPrefix(a, "--backend="):
+			backend = strings.TrimPrefix(a, "--backend=")
+		case a == "--backend":
+			if v, ok := next(); ok {
+				backend = v
+			}
 		case a == "--wait" || a == "-w":
 			wait = true
 		case a == "--json":
@@ -264,8 +346,18 @@ func runCloudSubmit(args []string, o *options) error {
 	if workflow == "" {
 		workflow = "construct.yml"
 	}
-	if repo == "" {
-		var err error
+	o.repo, o.backend = repo, backend
+	backend, err := resolveCloudBackend(o)
+	if err != nil {
+		return err
+	}
+	gitlab := backend == "gitlab"
+	if repo == "" && gitlab {
+		_, repo, err = pkg.GitRemote()
+		if err != nil {
+			return fmt.Errorf("%w; pass --repo group/project to override", err)
+		}
+	} else if repo == "" {
 		repo, err = gitRemoteRepo()
 		if err != nil {
 			return err
@@ -286,7 +378,11 @@ func runCloudSubmit(args []string, o *options) error {
 		envArgs = append(envArgs, "-e "+ov)
 		if key, val, ok := strings.Cut(ov, "="); ok && pkg.IsSecretName(key) {
 			redact = append(redact, val)
-			fmt.Printf("(!) %s looks like a secret; workflow inputs are visible to repo collaborators — prefer a GitHub repo secret\n", key)
+			if gitlab {
+				fmt.Printf("(!) %s looks like a secret; pipeline variables are visible to project members — prefer a masked CI/CD variable\n", key)
+			} else {
+				fmt.Printf("(!) %s looks like a secret; workflow inputs are visible to repo collaborators — prefer a GitHub repo secret\n", key)
+			}
 		}
 	}
 	argsLine := strings.Join(append(extra, envArgs...), " ")
@@ -295,7 +391,7 @@ func runCloudSubmit(args []string, o *options) error {
 	if err != nil {
 		return err
 	}
-	if _, err := os.Stat(wfPath); err != nil {
+	if _, err := os.Stat(wfPath); err != nil && !gitlab {
 		if noInit {
 			return fmt.Errorf("%s not found (create it with `construct cloud init-actions`)", wfPath)
 		}
@@ -310,7 +406,7 @@ func runCloudSubmit(args []string, o *options) error {
 	}
 
 	o.repo = repo
-	client, err := actionsClient(o)
+	client, err := cloudBackend(o, workflow)
 	if err != nil {
 		return err
 	}
@@ -323,21 +419,30 @@ func runCloudSubmit(args []string, o *options) error {
 	}
 
 	t0 := time.Now().Add(-2 * time.Minute)
-	if err := client.Dispatch(ctx, workflow, ref, inputs); err != nil {
+	run, err := client.Dispatch(ctx, ref, inputs)
+	if err != nil {
 		return err
 	}
 
 	if jsonOut {
-		out, err := json.Marshal(map[string]any{
+		res := map[string]any{
 			"submitted": true,
+			"backend":   backend,
 			"repo":      repo,
-			"workflow":  workflow,
 			"ref":       ref,
-		})
+		}
+		if gitlab {
+			res["run_id"] = run.ID
+		} else {
+			res["workflow"] = workflow
+		}
+		out, err := json.Marshal(res)
 		if err != nil {
 			return err
 		}
 		fmt.Println(string(out))
+	} else if gitlab {
+		fmt.Printf("triggered pipeline #%d on %s (%s @ %s): %s\n", run.ID, repo, ref, strings.Join(targets, " "), run.HTMLURL)
 	} else {
 		fmt.Printf("dispatched %s on %s (%s @ %s)\n", workflow, repo, ref, strings.Join(targets, " "))
 	}
@@ -345,10 +450,13 @@ func runCloudSubmit(args []string, o *options) error {
 	if !wait {
 		return nil
 	}
+	if gitlab {
+		return waitCloudRun(ctx, client, run.ID, redact, jsonOut)
+	}
 
 	var runID int64
 	for range 12 {
-		r, err := client.LatestDispatchRun(ctx, workflow, t0)
+		r, err := client.LatestRun(ctx, t0)
 		if err == nil {
 			runID = r.ID
 			break
@@ -364,10 +472,10 @@ func runCloudSubmit(args []string, o *options) error {
 	}
 
 	fmt.Printf("run #%d: https://github.com/%s/actions/runs/%d\n", runID, repo, runID)
-	return waitActionsRun(ctx, client, runID, redact, jsonOut)
+	return waitCloudRun(ctx, client, runID, redact, jsonOut)
 }
 
-func waitActionsRun(ctx context.Context, client *pkg.GHClient, runID int64, redact []string, jsonOut bool) error {
+func waitCloudRun(ctx context.Context, client pkg.CloudBackend, runID int64, redact []string, jsonOut bool) error {
 	seen := make(map[int64]int64)
 	errStreak := 0
 	for {
@@ -388,27 +496,16 @@ func waitActionsRun(ctx context.Context, client *pkg.GHClient, runID int64, reda
 		jobs, err := client.Jobs(ctx, runID)
 		if err == nil {
 			for _, j := range jobs {
-				if j.Status == "queued" || j.Status == "in_progress" || (j.Conclusion == "" && j.Status == "completed") {
+				if j.Status == "queued" || (j.Status == "in_progress" && !client.LiveLogs()) || (j.Conclusion == "" && j.Status == "completed") {
 					continue
 				}
 				logs, lerr := client.JobLogs(ctx, j.ID)
 				if lerr != nil {
 					continue
 				}
-
-				text := string(logs)
-				if len(redact) > 0 {
-					text = pkg.RedactValues(text, redact)
-				}
-
-				if int64(len(text)) < seen[j.ID] {
-					seen[j.ID] = 0
-				}
-
-				if int64(len(text)) > seen[j.ID] {
-					fmt.Print(text[seen[j.ID]:])
-					seen[j.ID] = int64(len(text))
-				}
+				var delta string
+				delta, seen[j.ID] = jobLogDelta(string(logs), j.Status != "completed", redact, seen[j.ID])
+				fmt.Print(delta)
 			}
 		}
 
@@ -439,6 +536,24 @@ func waitActionsRun(ctx context.Context, client *pkg.GHClient, runID int64, reda
 	}
 }
 
+// jobLogDelta redacts a job's log and returns the part past seen, with the
+// new offset. A running job's log is cut at its last newline: redaction
+// works on whole lines, and a secret split across two reads would
+// otherwise print its first half.
+func jobLogDelta(logs string, running bool, redact []string, seen int64) (string, int64) {
+	if running {
+		logs = logs[:strings.LastIndexByte(logs, '\n')+1]
+	}
+	text := pkg.MaskSecrets(logs)
+	if len(redact) > 0 {
+		text = pkg.RedactValues(text, redact)
+	}
+	if int64(len(text)) < seen {
+		seen = 0
+	}
+	return text[seen:], int64(len(text))
+}
+
 func runCloudStatus(args []string, o *options) error {
 	if len(args) < 1 {
 		return cloudUsage()
@@ -449,7 +564,7 @@ func runCloudStatus(args []string, o *options) error {
 		return exitAt(2, "invalid run id %q", args[0])
 	}
 
-	client, err := actionsClient(o)
+	client, err := cloudBackend(o, "")
 	if err != nil {
 		return err
 	}
@@ -483,7 +598,7 @@ func runCloudLogs(args []string, o *options) error {
 	if err != nil {
 		return exitAt(2, "invalid run id %q", args[0])
 	}
-	client, err := actionsClient(o)
+	client, err := cloudBackend(o, "")
 	if err != nil {
 		return err
 	}
@@ -504,7 +619,7 @@ func runCloudLogs(args []string, o *options) error {
 		if err != nil {
 			continue
 		}
-		text := string(logs)
+		text := pkg.MaskSecrets(string(logs))
 		if len(secrets) > 0 {
 			text = pkg.RedactValues(text, secrets)
 		}
@@ -525,7 +640,7 @@ func runCloudCancel(args []string, o *options) error {
 	if err != nil {
 		return exitAt(2, "invalid run id %q", args[0])
 	}
-	client, err := actionsClient(o)
+	client, err := cloudBackend(o, "")
 	if err != nil {
 		return err
 	}
@@ -536,6 +651,35 @@ func runCloudCancel(args []string, o *options) error {
 	return nil
 }
 
+func runCloudArtifacts(args []string, o *options) error {
+	if len(args) < 1 {
+		return cloudUsage()
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return exitAt(2, "invalid run id %q", args[0])
+	}
+	client, err := cloudBackend(o, "")
+	if err != nil {
+		return err
+	}
+	into := o.into
+	if into == "" {
+		into = fmt.Sprintf("cloud-run-%d", id)
+	}
+	dirs, err := pkg.DownloadCloudArtifacts(context.Background(), client, id, into)
+	for _, d := range dirs {
+		fmt.Printf("unpacked %s\n", d)
+	}
+	if err != nil {
+		return err
+	}
+	if len(dirs) == 0 {
+		fmt.Printf("run #%d has no artifacts (not uploaded, or expired)\n", id)
+	}
+	return nil
+}
+
 func runCloudInitActions(args []string) error {
 	workflow := "construct.yml"
 	for _, a := range args {
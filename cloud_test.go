@@ -0,0 +1,27 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJobLogDeltaHoldsPartialLines(t *testing.T) {
+	redact := []string{"hunter2"}
+	var printed strings.Builder
+	var seen int64
+	for _, read := range []struct {
+		log     string
+		running bool
+	}{
+		{"building...\ntoken hun", true},
+		{"building...\ntoken hunter2 leaked\nmore", true},
+		{"building...\ntoken hunter2 leaked\nmore output", false},
+	} {
+		var delta string
+		delta, seen = jobLogDelta(read.log, read.running, redact, seen)
+		printed.WriteString(delta)
+	}
+	if got := printed.String(); strings.Contains(got, "hun") || !strings.HasSuffix(got, "leaked\nmore output") {
+		t.Errorf("printed = %q", got)
+	}
+}
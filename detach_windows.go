@@ -0,0 +1,15 @@
+//go:build windows
+
+package main
+
+import (
+	"os/exec"
+
+	"golang.org/x/sys/windows"
+)
+
+// detachProcess starts cmd without a console, so closing the terminal that
+// started it does not stop it.
+func detachProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &windows.SysProcAttr{CreationFlags: windows.DETACHED_PROCESS | windows.CREATE_NEW_PROCESS_GROUP}
+}
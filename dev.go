@@ -8,6 +8,7 @@ import (
 	"os/signal"
 	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -143,6 +144,13 @@ func runDev(args []string, o *options) error {
 	if err := rejectSubcommandFlags(rest, "dev"); err != nil {
 		return err
 	}
+	if len(rest) > 0 && slices.Contains(pkg.DevControlVerbs, rest[0]) {
+		return runDevControl(filepath.Dir(fileName), rest, o.follow)
+	}
+	detached := os.Getenv(devDetachedEnv) == "1"
+	if detached {
+		readDetachedPassphrase()
+	}
 	p, err := pkg.NewParser(fileName)
 	if err != nil {
 		return err
@@ -157,8 +165,15 @@ func runDev(args []string, o *options) error {
 	if err != nil {
 		return err
 	}
+	if o.detach && !detached {
+		return startDetachedDev(baseDir, fileName)
+	}
 
 	regulars, aggregator := plan.regulars, plan.aggregator
+	ports, err := allocateDevPorts(data, plan.services)
+	if err != nil {
+		return err
+	}
 
 	if len(regulars) > 0 {
 		fmt.Printf("(dev: running %d prerequisite command(s): %s)\n", len(regulars), strings.Join(regulars, ", "))
@@ -177,42 +192,77 @@ func runDev(args []string, o *options) error {
 		ex.SetBaseDir(baseDir)
 		ex.SetShell(o.shell)
 		ex.SetYes(o.yes)
+		exposePorts(ex, data, aggregator, ports)
 		if err := ex.RunServiceBody(aggregator); err != nil {
 			return fmt.Errorf("dev setup (%s): %w", aggregator.Name, err)
 		}
 	}
 
+	services := plan.services
+	ready := map[string]chan struct{}{}
+	var svcs []*devService
+	for _, s := range services {
+		ready[s] = make(chan struct{})
+		cmd, _ := data.GetCommand(s)
+		svcs = append(svcs, newDevService(cmd))
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	// Ctrl-C and `dev down` stop services dependents-first, each given its
+	// stop signal's grace period.
+	shutdown := sync.OnceFunc(func() {
+		go stopServices(ctx, devStopOrder(data, svcs), cancel)
+	})
 	sigCh := make(chan os.Signal, 2)
 	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
 	defer signal.Stop(sigCh)
 	go func() {
 		<-sigCh
-		fmt.Println("\n(dev) stopping services...")
-		cancel()
-		<-sigCh // a service is ignoring TERM; force
+		fmt.Println("\n(dev) stopping services... (Ctrl-C again to force)")
+		shutdown()
+		<-sigCh // a service is ignoring its stop signal; force
 		os.Exit(130)
 	}()
 
-	services := plan.services
-	ready := map[string]chan struct{}{}
-	for _, s := range services {
-		ready[s] = make(chan struct{})
+	if detached {
+		cleanup, err := detachedSession(baseDir, svcs)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+	}
+	ctl, err := startDevControl(baseDir, svcs)
+	switch {
+	case err != nil && detached:
+		return err // nothing could reach a background session without it
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "(dev: control socket disabled: %v)\n", err)
+	default:
+		defer ctl.Close()
+		ctl.down = func() {
+			fmt.Println("(dev) stopping services...")
+			shutdown()
+		}
 	}
-	fmt.Printf("(dev: starting %d service(s): %s — Ctrl-C stops all)\n", len(services), strings.Join(services, ", "))
+	printDevPorts(services, ports)
+	stopHint := "Ctrl-C stops all"
+	if detached {
+		stopHint = "construct dev down stops all"
+	}
+	fmt.Printf("(dev: starting %d service(s): %s — %s)\n", len(services), strings.Join(services, ", "), stopHint)
 	var wg sync.WaitGroup
-	for _, name := range services {
+	for i, name := range services {
 		cmd, _ := data.GetCommand(name)
 		wg.Add(1)
-		go superviseService(&wg, data, cmd, baseDir, o, ctx, ready)
+		go superviseService(&wg, data, cmd, svcs[i], baseDir, o, ctx, ready, ports)
 	}
 	wg.Wait()
 	fmt.Println("(dev) stopped")
 	return nil
 }
 
-func superviseService(wg *sync.WaitGroup, data *pkg.ParsedData, cmd *pkg.Command, baseDir string, o *options, globalCtx context.Context, ready map[string]chan struct{}) {
+func superviseService(wg *sync.WaitGroup, data *pkg.ParsedData, cmd *pkg.Command, svc *devService, baseDir string, o *options, globalCtx context.Context, ready map[string]chan struct{}, ports map[string]devPort) {
 	defer wg.Done()
 	name := cmd.Name
 
@@ -221,6 +271,10 @@ func superviseService(wg *sync.WaitGroup, data *pkg.ParsedData, cmd *pkg.Command
 		if ch, ok := ready[pre]; ok {
 			select {
 			case <-ch:
+			case <-svc.stopReq: // `dev down` stops dependents first
+				svc.setState("stopped")
+				<-globalCtx.Done()
+				return
 			case <-globalCtx.Done():
 				return
 			}
@@ -231,12 +285,27 @@ func superviseService(wg *sync.WaitGroup, data *pkg.ParsedData, cmd *pkg.Command
 	ex.SetBaseDir(baseDir)
 	ex.SetShell(o.shell)
 	ex.SetYes(o.yes)
+	ex.SetObserver(svc)
+	exposePorts(ex, data, cmd, ports)
 
 	restart := make(chan struct{}, 1)
 	if len(cmd.OnChange) > 0 {
 		go watchOnchange(baseDir, cmd.OnChange, restart, globalCtx)
 	}
 
+	// stopped parks a service stopped from `construct dev stop` until it is
+	// restarted; false means construct dev is shutting down.
+	stopped := func() bool {
+		svc.setState("stopped")
+		fmt.Printf("[%s] stopped\n", name)
+		select {
+		case <-svc.restartReq:
+			return true
+		case <-globalCtx.Done():
+			return false
+		}
+	}
+
 	first := true
 	backoff := time.Second
 	for {
@@ -252,22 +321,49 @@ func superviseService(wg *sync.WaitGroup, data *pkg.ParsedData, cmd *pkg.Command
 		ex.SetRunContext(runCtx)
 		done := make(chan error, 1)
 		start := time.Now()
+		svc.startRun(!first, cmd.Ready != nil || cmd.Port != "")
 		go func() { done <- ex.RunServiceBody(cmd) }()
 
+		up := make(chan struct{}) // closed once this run is ready
+		upResult := make(chan bool, 1)
+		go func() {
+			ok := waitReady(runCtx, ex, cmd)
+			if ok {
+				svc.setReady(true)
+				close(up)
+			}
+			upResult <- ok
+		}()
 		if first {
 			first = false
-			if cmd.Port != "" {
-				if waitPort(globalCtx, cmd.Port, 90*time.Second) {
-					fmt.Printf("[%s] ready on port %s\n", name, cmd.Port)
-				} else if globalCtx.Err() == nil {
-					fmt.Fprintf(os.Stderr, "[%s] port %s not ready after 90s; starting dependents anyway\n", name, cmd.Port)
-				}
+			ok, stopping := false, false
+			select {
+			case ok = <-upResult:
+			case <-svc.stopReq: // handled below, once dependents may start
+				stopping = true
+				signalDev(svc.stopReq)
+			}
+			switch {
+			case stopping:
+			case cmd.Ready != nil && ok:
+				fmt.Printf("[%s] ready (%s)\n", name, cmd.Ready)
+			case cmd.Ready != nil && globalCtx.Err() == nil:
+				fmt.Fprintf(os.Stderr, "[%s] %s not ready after %s; starting dependents anyway\n", name, cmd.Ready, cmd.Ready.ReadyTimeout())
+			case cmd.Port != "" && ok:
+				fmt.Printf("[%s] ready on port %s\n", name, cmd.Port)
+			case cmd.Port != "" && globalCtx.Err() == nil:
+				fmt.Fprintf(os.Stderr, "[%s] port %s not ready after 90s; starting dependents anyway\n", name, cmd.Port)
 			}
 			close(ready[name])
 		}
 
+		unhealthy := make(chan struct{}, 1)
+		if cmd.Health != nil {
+			go monitorHealth(runCtx, ex, cmd, svc, up, unhealthy)
+		}
+
 		var runErr error
-		restarted := false
+		restarted, failedHealth, stopRequested := false, false, false
 		select {
 		case runErr = <-done:
 		case <-restart:
@@ -275,8 +371,25 @@ func superviseService(wg *sync.WaitGroup, data *pkg.ParsedData, cmd *pkg.Command
 			fmt.Printf("[%s] inputs changed; restarting\n", name)
 			stopRun()
 			runErr = <-done
+		case <-svc.restartReq:
+			restarted = true
+			fmt.Printf("[%s] restart requested\n", name)
+			stopRun()
+			runErr = <-done
+		case <-svc.stopReq:
+			stopRequested = true
+			fmt.Printf("[%s] stopping (%s)\n", name, pkg.StopSpecFor(cmd))
+			stopRun()
+			<-done
+		case <-unhealthy:
+			failedHealth = true
+			stopRun()
+			<-done
 		}
 		stopRun()
+		if err := ex.RunServiceOnStop(cmd); err != nil {
+			fmt.Fprintf(os.Stderr, "[%s] on_stop failed: %s\n", name, pkg.MaskSecrets(err.Error()))
+		}
 
 		if globalCtx.Err() != nil {
 			return
@@ -287,14 +400,33 @@ func superviseService(wg *sync.WaitGroup, data *pkg.ParsedData, cmd *pkg.Command
 		if restarted {
 			continue
 		}
-		code := 1
-		if ce, ok := runErr.(interface{ ExitCode() int }); ok {
-			code = ce.ExitCode()
+		if stopRequested {
+			if !stopped() {
+				return
+			}
+			backoff = time.Second
+			continue
+		}
+		if failedHealth {
+			fmt.Fprintf(os.Stderr, "[%s] unhealthy after %d failed checks; restarting in %s\n", name, cmd.Health.FailureLimit(), backoff)
+		} else {
+			code := 1
+			if ce, ok := runErr.(interface{ ExitCode() int }); ok {
+				code = ce.ExitCode()
+			}
+			fmt.Fprintf(os.Stderr, "[%s] exited (code %d); restarting in %s\n", name, code, backoff)
 		}
-		fmt.Fprintf(os.Stderr, "[%s] exited (code %d); restarting in %s\n", name, code, backoff)
+		svc.setState("backoff")
 		select {
 		case <-time.After(backoff):
 		case <-restart:
+		case <-svc.restartReq:
+		case <-svc.stopReq:
+			if !stopped() {
+				return
+			}
+			backoff = time.Second
+			continue
 		case <-globalCtx.Done():
 			return
 		}
@@ -302,6 +434,126 @@ func superviseService(wg *sync.WaitGroup, data *pkg.ParsedData, cmd *pkg.Command
 	}
 }
 
+// devPort is a service's port under construct dev; auto ports were picked
+// at startup.
+type devPort struct {
+	port string
+	auto bool
+}
+
+// allocateDevPorts gives each `port auto` service a free port, rewriting
+// its Port so readiness waits on it, and returns every service's port.
+func allocateDevPorts(data *pkg.ParsedData, services []string) (map[string]devPort, error) {
+	ports := map[string]devPort{}
+	var held []net.Listener // keep each port taken until all are picked
+	defer func() {
+		for _, ln := range held {
+			ln.Close()
+		}
+	}()
+	for _, name := range services {
+		cmd, err := data.GetCommand(name)
+		if err != nil || cmd.Port == "" {
+			continue
+		}
+		if cmd.Port != pkg.PortAuto {
+			ports[name] = devPort{port: cmd.Port}
+			continue
+		}
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return nil, fmt.Errorf("dev: cannot allocate a port for %s: %w", name, err)
+		}
+		held = append(held, ln)
+		cmd.Port = strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+		ports[name] = devPort{port: cmd.Port, auto: true}
+	}
+	return ports, nil
+}
+
+// exposePorts gives cmd's body the ports of itself and its direct
+// prerequisites, as &name.port and @NAME_PORT.
+func exposePorts(ex *pkg.Executor, data *pkg.ParsedData, cmd *pkg.Command, ports map[string]devPort) {
+	for _, name := range append([]string{cmd.Name}, cmd.Prereqs...) {
+		name = strings.TrimSpace(name)
+		if p, ok := ports[name]; ok {
+			data.SetVariable(name+".port", cmd.Name, p.port)
+			ex.Setenv(portEnvName(name), p.port)
+		}
+	}
+}
+
+// portEnvName is the @ENV name of a service's port: api -> API_PORT,
+// //web:dev-server -> WEB_DEV_SERVER_PORT.
+func portEnvName(service string) string {
+	upper := strings.ToUpper(strings.TrimPrefix(service, "//"))
+	return strings.Map(func(r rune) rune {
+		if r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, upper) + "_PORT"
+}
+
+func printDevPorts(services []string, ports map[string]devPort) {
+	if len(ports) == 0 {
+		return
+	}
+	fmt.Printf("%-20s %s\n", "service", "port")
+	for _, name := range services {
+		if p, ok := ports[name]; ok {
+			note := ""
+			if p.auto {
+				note = " (auto)"
+			}
+			fmt.Printf("%-20s %s%s\n", name, p.port, note)
+		}
+	}
+}
+
+// devStopOrder orders services dependents-first, the reverse of the order
+// their readiness gates them in.
+func devStopOrder(data *pkg.ParsedData, svcs []*devService) []*devService {
+	byName := map[string]*devService{}
+	for _, s := range svcs {
+		byName[s.name] = s
+	}
+	var order []*devService
+	seen := map[string]bool{}
+	var visit func(name string)
+	visit = func(name string) {
+		s, ok := byName[name]
+		if !ok || seen[name] {
+			return
+		}
+		seen[name] = true
+		if cmd, err := data.GetCommand(name); err == nil {
+			for _, pre := range cmd.Prereqs {
+				visit(strings.TrimSpace(pre))
+			}
+		}
+		order = append(order, s)
+	}
+	for _, s := range svcs {
+		visit(s.name)
+	}
+	slices.Reverse(order)
+	return order
+}
+
+// stopServices stops each service in order, waiting for one to stop before
+// the next, then ends the session.
+func stopServices(ctx context.Context, order []*devService, done func()) {
+	defer done()
+	for _, s := range order {
+		if s.status().State == "stopped" {
+			continue
+		}
+		signalDev(s.stopReq)
+		s.waitStopped(ctx)
+	}
+}
+
 func waitPort(ctx context.Context, port string, timeout time.Duration) bool {
 	addr := net.JoinHostPort("127.0.0.1", port)
 	deadline := time.Now().Add(timeout)
@@ -320,6 +572,74 @@ func waitPort(ctx context.Context, port string, timeout time.Duration) bool {
 	return false
 }
 
+// waitProbe polls a probe until it passes, the timeout expires, or ctx is
+// done.
+func waitProbe(ctx context.Context, ex *pkg.Executor, cmd *pkg.Command, probe *pkg.Probe, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if ex.RunProbe(ctx, cmd, probe) == nil {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+	return false
+}
+
+// waitReady waits for a service's ready probe, or else its port; services
+// with neither are ready as soon as they start.
+func waitReady(ctx context.Context, ex *pkg.Executor, cmd *pkg.Command) bool {
+	switch {
+	case cmd.Ready != nil:
+		return waitProbe(ctx, ex, cmd, cmd.Ready, cmd.Ready.ReadyTimeout())
+	case cmd.Port != "":
+		return waitPort(ctx, cmd.Port, 90*time.Second)
+	}
+	return true
+}
+
+// monitorHealth runs a service's health probe for one run of the service,
+// once up is closed, and signals unhealthy after the probe's failure limit
+// of consecutive failures.
+func monitorHealth(ctx context.Context, ex *pkg.Executor, cmd *pkg.Command, svc *devService, up <-chan struct{}, unhealthy chan<- struct{}) {
+	name, probe := cmd.Name, cmd.Health
+	select {
+	case <-up:
+	case <-ctx.Done():
+		return
+	}
+	failures, limit := 0, probe.FailureLimit()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(probe.Interval()):
+		}
+		err := ex.RunProbe(ctx, cmd, probe)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			if failures > 0 {
+				fmt.Printf("[%s] healthy again\n", name)
+				svc.setReady(true)
+			}
+			failures = 0
+			continue
+		}
+		failures++
+		svc.setReady(false)
+		fmt.Fprintf(os.Stderr, "[%s] health check failed (%d/%d): %s\n", name, failures, limit, pkg.MaskSecrets(err.Error()))
+		if failures >= limit {
+			unhealthy <- struct{}{}
+			return
+		}
+	}
+}
+
 func watchOnchange(baseDir string, patterns []string, restart chan struct{}, ctx context.Context) {
 	snapshot := func() map[string]int64 {
 		files := []string{}
@@ -2,11 +2,14 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"io"
 	"net"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -112,6 +115,49 @@ func TestWaitPort(t *testing.T) {
 	}
 }
 
+func TestMonitorHealthSignalsUnhealthy(t *testing.T) {
+	dir := devDir(t, `service api {
+    ready $ test -f up
+    health<20ms, 2> $ test -f up
+    $ sleep 100
+}
+`)
+	p, err := pkg.NewParser(filepath.Join(dir, "Constfile"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd, _ := data.GetCommand("api")
+	ex := pkg.NewExecutor(data, false, false)
+	ex.SetBaseDir(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if waitProbe(ctx, ex, cmd, cmd.Ready, 100*time.Millisecond) {
+		t.Fatal("ready probe passed before the service was up")
+	}
+	os.WriteFile(filepath.Join(dir, "up"), nil, 0644)
+
+	up := make(chan struct{})
+	close(up)
+	unhealthy := make(chan struct{}, 1)
+	go monitorHealth(ctx, ex, cmd, newDevService(cmd), up, unhealthy)
+	select {
+	case <-unhealthy:
+		t.Fatal("healthy service reported unhealthy")
+	case <-time.After(150 * time.Millisecond):
+	}
+	os.Remove(filepath.Join(dir, "up"))
+	select {
+	case <-unhealthy:
+	case <-time.After(5 * time.Second):
+		t.Fatal("failing health checks never signaled a restart")
+	}
+}
+
 func TestWatchOnchangeSignalsRestart(t *testing.T) {
 	dir := t.TempDir()
 	os.MkdirAll(filepath.Join(dir, "src"), 0755)
@@ -146,3 +192,162 @@ func TestDevSupervisionApplies(t *testing.T) {
 		t.Error("no services: construct dev must fall through to the plain command")
 	}
 }
+
+func TestDevControlSocket(t *testing.T) {
+	dir := devDir(t, "service api {\n    $ echo hello-from-api\n    $ sleep 100\n}\n")
+	p, err := pkg.NewParser(filepath.Join(dir, "Constfile"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd, _ := data.GetCommand("api")
+	svc := newDevService(cmd)
+	ctl, err := startDevControl(dir, []*devService{svc})
+	if err != nil {
+		t.Skipf("cannot listen on a unix socket: %v", err)
+	}
+	defer ctl.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+	wg.Add(1)
+	go superviseService(&wg, data, cmd, svc, dir, &options{}, ctx, map[string]chan struct{}{"api": make(chan struct{})}, nil)
+
+	waitFor := func(what string, cond func(devStatus) bool) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+			if cond(svc.status()) {
+				return
+			}
+		}
+		t.Fatalf("timed out waiting for %s: %+v", what, svc.status())
+	}
+	request := func(req devRequest) string {
+		t.Helper()
+		conn, err := net.Dial("unix", devSocketPath(dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		json.NewEncoder(conn).Encode(req)
+		out, _ := io.ReadAll(conn)
+		return string(out)
+	}
+
+	waitFor("the sleep", func(s devStatus) bool { return s.PID > 0 && s.Ready })
+	if out := request(devRequest{Op: "status"}); !strings.Contains(out, `"name":"api","state":"running"`) {
+		t.Errorf("status = %s", out)
+	}
+	if out := request(devRequest{Op: "logs", Service: "api"}); !strings.HasSuffix(out, "\nhello-from-api\n") {
+		t.Errorf("logs = %q", out)
+	}
+	if err := runDevControl(dir, []string{"restart", "web"}, false); err == nil || !strings.Contains(err.Error(), `no service "web"`) {
+		t.Errorf("restart of an unknown service err = %v", err)
+	}
+
+	pid := svc.status().PID
+	if err := runDevControl(dir, []string{"restart", "api"}, false); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("the restart", func(s devStatus) bool { return s.Restarts == 1 && s.PID > 0 && s.PID != pid })
+
+	if err := runDevControl(dir, []string{"stop", "api"}, false); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("the stop", func(s devStatus) bool { return s.State == "stopped" && s.PID == 0 })
+	if err := runDevControl(dir, []string{"stop", "api"}, false); err == nil {
+		t.Error("stopping a stopped service should fail")
+	}
+	if err := runDevControl(dir, []string{"restart", "api"}, false); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("the start after a stop", func(s devStatus) bool { return s.State == "running" && s.PID > 0 })
+}
+
+func TestDevStopOrderIsReverseDependencyOrder(t *testing.T) {
+	dir := devDir(t, "service db {\n  $ s\n}\n\nservice api < db {\n  $ s\n}\n\nservice web < api {\n  $ s\n}\n\nservice admin < db {\n  $ s\n}\n")
+	p, _ := pkg.NewParser(filepath.Join(dir, "Constfile"))
+	data, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := planDev(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var svcs []*devService
+	for _, name := range plan.services {
+		cmd, _ := data.GetCommand(name)
+		svcs = append(svcs, newDevService(cmd))
+	}
+	var got []string
+	for _, s := range devStopOrder(data, svcs) {
+		got = append(got, s.name)
+	}
+	if strings.Join(got, " ") != "web api admin db" {
+		t.Errorf("stop order = %v", got)
+	}
+}
+
+func TestDevAutoPorts(t *testing.T) {
+	dir := devDir(t, `service api {
+    port auto
+    $ echo api=&api.port env=@API_PORT
+}
+
+service web < api {
+    port 5173
+    $ echo web=&web.port api=&api.port env=@API_PORT
+}
+`)
+	p, _ := pkg.NewParser(filepath.Join(dir, "Constfile"))
+	data, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ports, err := allocateDevPorts(data, []string{"api", "web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	api, _ := data.GetCommand("api")
+	if !ports["api"].auto || api.Port == pkg.PortAuto || ports["api"].port != api.Port {
+		t.Fatalf("api port = %+v, Port %q", ports["api"], api.Port)
+	}
+	if ports["web"] != (devPort{port: "5173"}) {
+		t.Errorf("web port = %+v", ports["web"])
+	}
+
+	for _, tc := range []struct{ name, want string }{
+		{"api", "api=" + api.Port + " env=" + api.Port},
+		{"web", "web=5173 api=" + api.Port + " env=" + api.Port},
+	} {
+		var out strings.Builder
+		cmd, _ := data.GetCommand(tc.name)
+		ex := pkg.NewExecutor(data, false, false)
+		ex.SetBaseDir(dir)
+		ex.SetStdoutSink(&out)
+		exposePorts(ex, data, cmd, ports)
+		if err := ex.RunServiceBody(cmd); err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.TrimSpace(strings.TrimPrefix(out.String(), "["+tc.name+"] ")); got != tc.want {
+			t.Errorf("%s output = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+	if got := portEnvName("//web:dev-server"); got != "WEB_DEV_SERVER_PORT" {
+		t.Errorf("portEnvName = %q", got)
+	}
+}
+
+func TestDevLogName(t *testing.T) {
+	for name, want := range map[string]string{"api": "api.log", "//svc/web:api": "svc_web_api.log"} {
+		if got := devLogName(name); got != want {
+			t.Errorf("devLogName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nicklvsa/construct/pkg"
+)
+
+const (
+	devSocketName = "dev.sock"
+	devLogLines   = 1000 // per service, for `construct dev logs`
+	devUsage      = "usage: construct dev [status | restart SERVICE | stop SERVICE | logs SERVICE [--follow] | attach | down]"
+)
+
+// devService is the supervisor's view of one running service, shared with
+// the control socket. It observes the service's executor for the pid and
+// output of each run.
+type devService struct {
+	name       string
+	port       string
+	restartReq chan struct{} // `construct dev restart`
+	stopReq    chan struct{} // `construct dev stop`
+
+	mu        sync.Mutex
+	state     string // starting, running, backoff, stopped
+	pid       int
+	started   time.Time
+	restarts  int
+	ready     bool
+	logs      []string
+	logFile   *os.File // detached sessions: .construct-cache/dev/<name>.log
+	followers map[chan string]bool
+}
+
+func newDevService(cmd *pkg.Command) *devService {
+	return &devService{
+		name:       cmd.Name,
+		port:       cmd.Port,
+		restartReq: make(chan struct{}, 1),
+		stopReq:    make(chan struct{}, 1),
+		state:      "starting",
+		followers:  map[chan string]bool{},
+	}
+}
+
+func (s *devService) CommandStarted(string)                 {}
+func (s *devService) CommandFinished(string, pkg.RunRecord) {}
+
+func (s *devService) ProcessStarted(_ string, pid int) {
+	s.mu.Lock()
+	s.pid = pid
+	s.mu.Unlock()
+}
+
+func (s *devService) OutputWriter(string) io.Writer {
+	return &devLogWriter{svc: s}
+}
+
+// startRun records a new run; probed services are not ready until their
+// ready probe or port says so.
+func (s *devService) startRun(restart, probed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if restart {
+		s.restarts++
+	}
+	s.state, s.ready, s.pid, s.started = "running", !probed, 0, time.Now()
+	if probed {
+		s.state = "starting"
+	}
+}
+
+func (s *devService) setReady(ready bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ready = ready
+	if ready && s.state == "starting" {
+		s.state = "running"
+	}
+}
+
+func (s *devService) setState(state string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state, s.ready, s.pid = state, false, 0
+}
+
+func (s *devService) status() devStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := devStatus{Name: s.name, State: s.state, PID: s.pid, Restarts: s.restarts, Port: s.port, Ready: s.ready}
+	if s.state == "starting" || s.state == "running" {
+		st.Started = s.started
+	}
+	return st
+}
+
+// waitStopped waits until the supervisor has parked the service after a
+// stop request.
+func (s *devService) waitStopped(ctx context.Context) {
+	for s.status().State != "stopped" {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+func (s *devService) appendLog(line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logs = append(s.logs, line)
+	if s.logFile != nil {
+		fmt.Fprintln(s.logFile, line)
+	}
+	if len(s.logs) > devLogLines {
+		s.logs = slices.Delete(s.logs, 0, len(s.logs)-devLogLines)
+	}
+	for ch := range s.followers {
+		select {
+		case ch <- line:
+		default: // a slow follower drops lines rather than stall the service
+		}
+	}
+}
+
+// follow returns the buffered log lines and a channel of new ones until
+// unfollow is called.
+func (s *devService) follow() (backlog []string, lines chan string, unfollow func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lines = make(chan string, 256)
+	s.followers[lines] = true
+	return slices.Clone(s.logs), lines, func() {
+		s.mu.Lock()
+		delete(s.followers, lines)
+		s.mu.Unlock()
+	}
+}
+
+// devLogWriter prints a service's output with its `[name]` prefix and keeps
+// each line for `construct dev logs`. Each stream gets its own writer, so
+// stdout and stderr never split each other's lines.
+type devLogWriter struct {
+	svc *devService
+	buf []byte
+}
+
+func (w *devLogWriter) Write(b []byte) (int, error) {
+	w.buf = append(w.buf, b...)
+	for {
+		line, rest, ok := strings.Cut(string(w.buf), "\n")
+		if !ok {
+			break
+		}
+		w.buf = []byte(rest)
+		fmt.Printf("[%s] %s\n", w.svc.name, line)
+		w.svc.appendLog(line)
+	}
+	return len(b), nil
+}
+
+type devStatus struct {
+	Name     string    `json:"name"`
+	State    string    `json:"state"`
+	PID      int       `json:"pid,omitempty"`
+	Started  time.Time `json:"started,omitzero"`
+	Restarts int       `json:"restarts"`
+	Port     string    `json:"port,omitempty"`
+	Ready    bool      `json:"ready"`
+}
+
+type devRequest struct {
+	Op      string `json:"op"`
+	Service string `json:"service,omitempty"`
+	Follow  bool   `json:"follow,omitempty"`
+}
+
+// devResponse is the first line of every reply; `logs` streams raw lines
+// after it.
+type devResponse struct {
+	Error    string      `json:"error,omitempty"`
+	Message  string      `json:"message,omitempty"`
+	Services []devStatus `json:"services,omitempty"`
+}
+
+func devSocketPath(baseDir string) string {
+	return filepath.Join(baseDir, pkg.CacheDirName(), devSocketName)
+}
+
+// devControl serves the control socket of a running `construct dev`.
+type devControl struct {
+	path     string
+	ln       net.Listener
+	services []*devService
+	down     func() // `construct dev down`
+	done     chan struct{}
+}
+
+func startDevControl(baseDir string, services []*devService) (*devControl, error) {
+	path := devSocketPath(baseDir)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("another construct dev session is listening on %s", path)
+	}
+	os.Remove(path) // stale socket from a session that did not shut down cleanly
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	c := &devControl{path: path, ln: ln, services: services, done: make(chan struct{})}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go c.handle(conn)
+		}
+	}()
+	return c, nil
+}
+
+func (c *devControl) Close() {
+	close(c.done)
+	c.ln.Close()
+	os.Remove(c.path)
+}
+
+func (c *devControl) service(name string) *devService {
+	for _, s := range c.services {
+		if s.name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func (c *devControl) handle(conn net.Conn) {
+	defer conn.Close()
+	var req devRequest
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+	enc := json.NewEncoder(conn)
+	switch req.Op {
+	case "down":
+		enc.Encode(devResponse{Message: "(dev) stopping services..."})
+		if c.down != nil {
+			c.down()
+		}
+		return
+	case "status":
+		var resp devResponse
+		for _, s := range c.services {
+			resp.Services = append(resp.Services, s.status())
+		}
+		enc.Encode(resp)
+		return
+	}
+	svc := c.service(req.Service)
+	if svc == nil {
+		enc.Encode(devResponse{Error: fmt.Sprintf("no service %q in this dev session", req.Service)})
+		return
+	}
+	switch req.Op {
+	case "restart":
+		verb := "restarting"
+		if svc.status().State == "stopped" {
+			verb = "starting"
+		}
+		signalDev(svc.restartReq)
+		enc.Encode(devResponse{Message: verb + " " + svc.name})
+	case "stop":
+		if svc.status().State == "stopped" {
+			enc.Encode(devResponse{Error: svc.name + " is already stopped"})
+			return
+		}
+		signalDev(svc.stopReq)
+		enc.Encode(devResponse{Message: "stopping " + svc.name})
+	case "logs":
+		backlog, lines, unfollow := svc.follow()
+		defer unfollow()
+		if enc.Encode(devResponse{}) != nil {
+			return
+		}
+		for _, line := range backlog {
+			if _, err := fmt.Fprintln(conn, line); err != nil {
+				return
+			}
+		}
+		if !req.Follow {
+			return
+		}
+		gone := make(chan struct{}) // the client hung up
+		go func() {
+			io.Copy(io.Discard, conn)
+			close(gone)
+		}()
+		for {
+			select {
+			case line := <-lines:
+				if _, err := fmt.Fprintln(conn, line); err != nil {
+					return
+				}
+			case <-gone:
+				return
+			case <-c.done:
+				return
+			}
+		}
+	default:
+		enc.Encode(devResponse{Error: fmt.Sprintf("unknown dev operation %q", req.Op)})
+	}
+}
+
+func signalDev(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// runDevControl talks to the `construct dev` session started from the same
+// Constfile directory.
+func runDevControl(baseDir string, args []string, follow bool) error {
+	req := devRequest{Op: args[0], Follow: follow}
+	switch {
+	case req.Op == "attach" && len(args) == 1:
+		return attachDev(baseDir)
+	case (req.Op == "status" || req.Op == "down") && len(args) == 1:
+	case len(args) == 2 && req.Op != "status" && req.Op != "down" && req.Op != "attach":
+		req.Service = args[1]
+	default:
+		return exitAt(2, devUsage)
+	}
+
+	path := devSocketPath(baseDir)
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return fmt.Errorf("no construct dev session is listening on %s", path)
+	}
+	defer conn.Close()
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return err
+	}
+	r := bufio.NewReader(conn)
+	header, err := r.ReadBytes('\n')
+	if err != nil {
+		return fmt.Errorf("dev session closed the connection: %w", err)
+	}
+	var resp devResponse
+	if err := json.Unmarshal(header, &resp); err != nil {
+		return fmt.Errorf("bad reply from dev session: %w", err)
+	}
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+	switch req.Op {
+	case "status":
+		printDevStatus(resp.Services)
+	case "logs":
+		_, err := io.Copy(os.Stdout, r)
+		return err
+	case "down":
+		fmt.Println(resp.Message)
+		for devSessionRunning(baseDir) {
+			time.Sleep(100 * time.Millisecond)
+		}
+		fmt.Println("(dev) stopped")
+	default:
+		fmt.Println(resp.Message)
+	}
+	return nil
+}
+
+func printDevStatus(services []devStatus) {
+	fmt.Printf("%-20s %-9s %8s %10s %8s %6s  %s\n", "service", "state", "pid", "uptime", "restarts", "port", "ready")
+	for _, s := range services {
+		pid, uptime, port, ready := "-", "-", "-", "no"
+		if s.PID > 0 {
+			pid = fmt.Sprint(s.PID)
+		}
+		if !s.Started.IsZero() {
+			uptime = time.Since(s.Started).Round(time.Second).String()
+		}
+		if s.Port != "" {
+			port = s.Port
+		}
+		if s.Ready {
+			ready = "yes"
+		}
+		fmt.Printf("%-20s %-9s %8s %10s %8d %6s  %s\n", s.Name, s.State, pid, uptime, s.Restarts, port, ready)
+	}
+}
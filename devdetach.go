@@ -0,0 +1,180 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nicklvsa/construct/pkg"
+)
+
+// devDetachedEnv marks the background process `construct dev -d` starts.
+const devDetachedEnv = "CONSTRUCT_DEV_DETACHED"
+
+// devStateDir holds a detached session's pidfile, its interleaved output
+// (dev.log), and one log per service.
+func devStateDir(baseDir string) string {
+	return filepath.Join(baseDir, pkg.CacheDirName(), "dev")
+}
+
+func devSessionRunning(baseDir string) bool {
+	conn, err := net.Dial("unix", devSocketPath(baseDir))
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// startDetachedDev re-runs this command line without -d as a background
+// process whose output goes to dev.log, and returns once its control socket
+// answers.
+func startDetachedDev(baseDir, fileName string) error {
+	if devSessionRunning(baseDir) {
+		return fmt.Errorf("a dev session is already running here (construct dev down stops it)")
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("could not resolve construct executable: %w", err)
+	}
+	dir := devStateDir(baseDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	logPath := filepath.Join(dir, "dev.log")
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	var args []string
+	for _, a := range os.Args[1:] {
+		if a != "-d" && a != "--detach" && a != "--detach=true" {
+			args = append(args, a)
+		}
+	}
+	cmd := exec.Command(exe, args...)
+	cmd.Stdout, cmd.Stderr = logFile, logFile
+	cmd.Env = append(os.Environ(), devDetachedEnv+"=1")
+	// The background process has no terminal to prompt on: unlock the
+	// secrets store here and hand it the passphrase on stdin, which its
+	// services do not see the way they would an environment variable.
+	if fileExists(filepath.Join(filepath.Dir(fileName), pkg.SecretsFileName)) {
+		if stdinIsTerminal() {
+			pkg.SetSecretsPrompt(func() (string, error) { return readPassphrase("Passphrase for " + pkg.SecretsFileName + ": ") })
+		}
+		if pass, err := pkg.SecretsPassphrase(); err == nil {
+			cmd.Stdin = strings.NewReader(pass)
+		}
+	}
+	detachProcess(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	fmt.Printf("(dev: started in the background, pid %d; output in %s)\n", cmd.Process.Pid, logPath)
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	for !devSessionRunning(baseDir) {
+		select {
+		case <-exited:
+			tail, _ := os.ReadFile(logPath)
+			os.Stderr.Write(tail)
+			return fmt.Errorf("dev session exited during startup")
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	fmt.Println("(dev: running — construct dev attach | status | down)")
+	return nil
+}
+
+// readDetachedPassphrase runs in the background process before the
+// Constfile is parsed: it takes the passphrase startDetachedDev wrote to
+// stdin, if any, as the secrets store's.
+func readDetachedPassphrase() {
+	if stdinIsTerminal() {
+		return
+	}
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil || len(data) == 0 {
+		return
+	}
+	pass := string(data)
+	pkg.RegisterSecret(pass)
+	pkg.SetSecretsPrompt(func() (string, error) { return pass, nil })
+}
+
+// detachedSession runs in the background process: it writes the pidfile
+// and opens each service's log file. The returned func cleans up.
+func detachedSession(baseDir string, svcs []*devService) (func(), error) {
+	os.Unsetenv(devDetachedEnv) // services must not think they are detached
+	dir := devStateDir(baseDir)
+	pidPath := filepath.Join(dir, "dev.pid")
+	if err := os.WriteFile(pidPath, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644); err != nil {
+		return nil, err
+	}
+	var files []*os.File
+	for _, s := range svcs {
+		f, err := os.Create(filepath.Join(dir, devLogName(s.name)))
+		if err != nil {
+			return nil, err
+		}
+		s.logFile = f
+		files = append(files, f)
+	}
+	return func() {
+		for _, f := range files {
+			f.Close()
+		}
+		os.Remove(pidPath)
+	}, nil
+}
+
+// devLogName maps a service (possibly a //pkg:name label) to a file name.
+func devLogName(name string) string {
+	return strings.NewReplacer("/", "_", ":", "_", "\\", "_").Replace(strings.TrimPrefix(name, "//")) + ".log"
+}
+
+// attachDev streams a detached session's output from the end of dev.log
+// until the session stops. Ctrl-C leaves the session running.
+func attachDev(baseDir string) error {
+	if !devSessionRunning(baseDir) {
+		return fmt.Errorf("no construct dev session is listening on %s", devSocketPath(baseDir))
+	}
+	logPath := filepath.Join(devStateDir(baseDir), "dev.log")
+	f, err := os.Open(logPath)
+	if err != nil {
+		return fmt.Errorf("the dev session was not started with -d (%w)", err)
+	}
+	defer f.Close()
+	// Replay the recent past, starting on a line boundary.
+	const replay = 64 << 10
+	if info, err := f.Stat(); err == nil && info.Size() > replay {
+		f.Seek(info.Size()-replay, io.SeekStart)
+		skip := make([]byte, 1)
+		for {
+			if _, err := f.Read(skip); err != nil || skip[0] == '\n' {
+				break
+			}
+		}
+	}
+	for {
+		if _, err := io.Copy(os.Stdout, f); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		if !devSessionRunning(baseDir) {
+			io.Copy(os.Stdout, f)
+			fmt.Println("(dev session ended)")
+			return nil
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"slices"
 	"strings"
 
 	"github.com/nicklvsa/construct/pkg"
@@ -46,6 +47,12 @@ func runDoctor(o *options, inputs *ConstructInput) error {
 	} else {
 		pass("cloud file: %s (not present)", cloudPath)
 	}
+	keysPath := filepath.Join(filepath.Dir(inputs.FileName), pkg.TrustedKeysFile)
+	if tk, err := pkg.LoadTrustedKeys(keysPath); err != nil {
+		fail("trusted keys: %v", err)
+	} else if fileExists(keysPath) {
+		pass("trusted keys: %d key(s), policy %s", len(tk.Keys), tk.Policy)
+	}
 
 	envPath := o.envFile
 	if envPath == "" {
@@ -103,12 +110,13 @@ func runDoctor(o *options, inputs *ConstructInput) error {
 		}
 	}
 	referenced := make(map[string]bool)
-	for _, cmd := range data.Commands {
+	for _, cmd := range append(slices.Clone(data.Commands), data.Instances()...) {
 		if cmd.IsDefault {
 			referenced[cmd.Name] = true
 		}
 		for _, p := range cmd.Prereqs {
 			referenced[p] = true
+			referenced[pkg.PrereqBase(p)] = true
 		}
 	}
 	hadWarning := false
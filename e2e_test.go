@@ -1,6 +1,8 @@
 package main
 
 import (
+	"archive/zip"
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -8,6 +10,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
 	"testing"
@@ -323,6 +326,262 @@ func TestE2EDryRunAndList(t *testing.T) {
 	}
 }
 
+func TestE2EListMatrixPrereqs(t *testing.T) {
+	dir := e2eConstfile(t, "gen {\n    echo gen\n}\nbuild matrix os in linux, darwin < gen {\n    echo &os\n}\n")
+	out, code := e2eRun(t, dir, nil, "--list")
+	if code != 0 || !strings.Contains(out, "Matrix: build[linux] build[darwin]") || !strings.Contains(out, "Depends on: [gen]") {
+		t.Errorf("--list of a matrix command: exit %d: %s", code, out)
+	}
+}
+
+func TestE2EListProfiles(t *testing.T) {
+	dir := e2eConstfile(t, "var region = west\nprofile prod {\n    var region = east\n}\nbuild {\n    echo &region\n}\n")
+	out, code := e2eRun(t, dir, nil, "--list", "--json")
+	var cmds []map[string]any
+	if err := json.Unmarshal([]byte(out), &cmds); code != 0 || err != nil || len(cmds) != 1 || cmds[0]["name"] != "build" {
+		t.Errorf("--list --json should stay an array of commands: exit %d, %v: %s", code, err, out)
+	}
+	out, code = e2eRun(t, dir, []string{"CONSTRUCT_PROFILE=prod"}, "--list-profiles", "--json")
+	var profiles []struct {
+		Name   string            `json:"name"`
+		Vars   map[string]string `json:"vars"`
+		Active bool              `json:"active"`
+	}
+	if err := json.Unmarshal([]byte(out), &profiles); code != 0 || err != nil || len(profiles) != 1 ||
+		profiles[0].Name != "prod" || profiles[0].Vars["region"] != "east" || !profiles[0].Active {
+		t.Errorf("--list-profiles --json: exit %d, %v: %s", code, err, out)
+	}
+	if out, code := e2eRun(t, dir, nil, "--list-profiles"); code != 0 || strings.TrimSpace(out) != "prod" {
+		t.Errorf("--list-profiles: exit %d: %q", code, out)
+	}
+}
+
+func TestE2EPrivateHidden(t *testing.T) {
+	dir := e2eConstfile(t, `import "lib.constfile" as lib
+
+private setup {
+    touch ready
+}
+gen {
+    echo generated
+}
+build < setup, lib.compile, gen {
+    echo built
+}
+`)
+	e2eWrite(t, dir, "lib.constfile", "private helper {\n    echo helping\n}\ncompile < helper {\n    echo compiled\n}\n")
+	for _, args := range [][]string{{"--list"}, {"--list", "--json"}, {"__targets"}} {
+		out, code := e2eRun(t, dir, nil, args...)
+		if code != 0 {
+			t.Fatalf("%v exit %d: %s", args, code, out)
+		}
+		if !strings.Contains(out, "build") || strings.Contains(out, "setup") || strings.Contains(out, "helper") {
+			t.Errorf("%v output = %q", args, out)
+		}
+	}
+	if out, _ := e2eRun(t, dir, nil, "--list"); !strings.Contains(out, "Depends on: [lib.compile gen]") {
+		t.Errorf("--list should still show public prerequisites: %q", out)
+	}
+	if out, code := e2eRun(t, dir, nil, "build"); code != 0 {
+		t.Errorf("build exit %d: %s", code, out)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ready")); err != nil {
+		t.Errorf("private prereq did not run: %v", err)
+	}
+}
+
+func TestE2EWorkspace(t *testing.T) {
+	dir := e2eConstfile(t, "workspace\n")
+	for pkgPath, body := range map[string]string{
+		"libs/core":    "build {\n    pwd > built.txt\n}\n",
+		"services/api": "test < //libs/core:build {\n    pwd > tested.txt\n}\n",
+	} {
+		os.MkdirAll(filepath.Join(dir, pkgPath), 0755)
+		os.WriteFile(filepath.Join(dir, pkgPath, "Constfile"), []byte(body), 0644)
+	}
+	out, code := e2eRun(t, filepath.Join(dir, "services", "api"), nil, "//...:test")
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, out)
+	}
+	for _, f := range []string{"libs/core/built.txt", "services/api/tested.txt"} {
+		got, err := os.ReadFile(filepath.Join(dir, f))
+		if err != nil || !strings.HasSuffix(strings.TrimSpace(string(got)), filepath.Dir(f)) {
+			t.Errorf("%s = %q, %v (commands should run in their package)", f, got, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".construct-cache", "run-state.json")); err != nil {
+		t.Errorf("run history should live at the workspace root: %v", err)
+	}
+
+	// A package Constfile that uses labels still works from its own
+	// directory: bare targets there are the package's.
+	api := filepath.Join(dir, "services", "api")
+	os.Remove(filepath.Join(api, "tested.txt"))
+	if out, code := e2eRun(t, api, nil, "--list"); code != 0 || !strings.Contains(out, "//services/api:test") {
+		t.Errorf("--list from a package: exit %d: %s", code, out)
+	}
+	if out, code := e2eRun(t, api, nil, "--no-cache", "test"); code != 0 {
+		t.Errorf("bare target from a package: exit %d: %s", code, out)
+	}
+	if _, err := os.Stat(filepath.Join(api, "tested.txt")); err != nil {
+		t.Errorf("package target did not run: %v", err)
+	}
+
+	// A package Constfile without labels runs on its own, as outside a
+	// workspace.
+	core := filepath.Join(dir, "libs", "core")
+	if out, code := e2eRun(t, core, nil, "--list"); code != 0 || strings.Contains(out, "//") || strings.Contains(out, "services") {
+		t.Errorf("--list from a label-free package: exit %d: %s", code, out)
+	}
+	if out, code := e2eRun(t, core, nil, "--list", "//services/api:test"); code != 0 || !strings.Contains(out, "//services/api:test") {
+		t.Errorf("a label from a label-free package: exit %d: %s", code, out)
+	}
+}
+
+func TestE2ESecrets(t *testing.T) {
+	dir := e2eConstfile(t, `secret token
+secret pass = @E2E_PASS
+
+deploy {
+    $ echo token=&token pass=&pass
+}
+`)
+	key := []string{"CONSTRUCT_SECRETS_KEY=e2e-key", "E2E_PASS=pw-55521"}
+	if out, code := e2eRun(t, dir, key, "secrets", "set", "token", "tk-31337"); code != 0 {
+		t.Fatalf("secrets set exit %d: %s", code, out)
+	}
+	if out, _ := e2eRun(t, dir, key, "secrets", "list"); strings.TrimSpace(out) != "token" {
+		t.Errorf("secrets list = %q", out)
+	}
+	for _, args := range [][]string{{"deploy"}, {"--debug", "deploy"}, {"--explain", "deploy"}} {
+		out, code := e2eRun(t, dir, key, args...)
+		if code != 0 || !strings.Contains(out, "token=***** pass=*****") || strings.Contains(out, "tk-31337") || strings.Contains(out, "pw-55521") {
+			t.Errorf("%v exit %d: %s", args, code, out)
+		}
+	}
+	out, code := e2eRun(t, dir, key, "--debug", "-e", "pass=hunter-7741", "deploy")
+	if code != 0 || !strings.Contains(out, "pass=*****") || strings.Contains(out, "hunter-7741") {
+		t.Errorf("overridden secret: exit %d: %s", code, out)
+	}
+	out, code = e2eRun(t, dir, []string{"CONSTRUCT_SECRETS_KEY="}, "deploy")
+	if code == 0 || !strings.Contains(out, "locked") {
+		t.Errorf("locked store: exit %d: %s", code, out)
+	}
+	if out, code := e2eRun(t, dir, []string{"CONSTRUCT_SECRETS_KEY="}, "--list"); code != 0 {
+		t.Errorf("--list with a locked store: exit %d: %s", code, out)
+	}
+}
+
+func TestE2EDevDetachedKeepsPassphraseOut(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("dev -d uses a unix control socket")
+	}
+	dir := e2eConstfile(t, `secret token
+
+service api {
+    $ env > env.txt
+    $ echo &token > token.txt
+    $ sleep 100
+}
+`)
+	if out, code := e2eRun(t, dir, []string{"CONSTRUCT_SECRETS_KEY=e2e-dev-key"}, "secrets", "set", "token", "tk-31337"); code != 0 {
+		t.Fatalf("secrets set exit %d: %s", code, out)
+	}
+	e2eWrite(t, dir, "key.txt", "e2e-dev-key\n")
+	noKey := []string{"CONSTRUCT_SECRETS_KEY="}
+	if out, code := e2eRun(t, dir, noKey, "--secrets-key-file", "key.txt", "dev", "-d"); code != 0 {
+		t.Fatalf("dev -d exit %d: %s", code, out)
+	}
+	defer e2eRun(t, dir, noKey, "dev", "down")
+
+	var token []byte
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		if token, _ = os.ReadFile(filepath.Join(dir, "token.txt")); len(token) > 0 {
+			break
+		}
+	}
+	if strings.TrimSpace(string(token)) != "tk-31337" {
+		t.Fatalf("service saw token %q, want the unlocked secret", token)
+	}
+	env, _ := os.ReadFile(filepath.Join(dir, "env.txt"))
+	if strings.Contains(string(env), "e2e-dev-key") {
+		t.Error("the service environment carries the secrets passphrase")
+	}
+}
+
+func TestE2EDevDetachedSession(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("dev -d uses a unix control socket")
+	}
+	dir := e2eConstfile(t, `service api {
+    $ echo hello-from-api
+    $ sleep 100
+}
+
+service worker {
+    $ echo hello-from-worker
+    $ sleep 100
+}
+`)
+	if out, code := e2eRun(t, dir, nil, "dev", "-d"); code != 0 {
+		t.Fatalf("dev -d exit %d: %s", code, out)
+	}
+	stopped := false
+	defer func() {
+		if !stopped {
+			e2eRun(t, dir, nil, "dev", "down")
+		}
+	}()
+
+	state := filepath.Join(dir, ".construct-cache", "dev")
+	for name, want := range map[string]string{"api.log": "hello-from-api\n", "worker.log": "hello-from-worker\n"} {
+		var got []byte
+		for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+			if got, _ = os.ReadFile(filepath.Join(state, name)); len(got) > 0 {
+				break
+			}
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(state, "dev.pid")); err != nil {
+		t.Errorf("no pidfile while the session runs: %v", err)
+	}
+
+	var attached bytes.Buffer
+	attach := exec.Command(constructBin, "dev", "attach")
+	attach.Dir = dir
+	attach.Stdout, attach.Stderr = &attached, &attached
+	if err := attach.Start(); err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- attach.Wait() }()
+
+	out, code := e2eRun(t, dir, nil, "dev", "down")
+	stopped = true
+	if code != 0 || !strings.Contains(out, "(dev) stopping services...") || !strings.HasSuffix(out, "(dev) stopped\n") {
+		t.Errorf("dev down exit %d: %s", code, out)
+	}
+	select {
+	case err := <-exited:
+		got := attached.String()
+		if err != nil || !strings.Contains(got, "hello-from-api") || !strings.Contains(got, "hello-from-worker") || !strings.HasSuffix(got, "(dev session ended)\n") {
+			t.Errorf("dev attach err = %v: %s", err, got)
+		}
+	case <-time.After(10 * time.Second):
+		attach.Process.Kill()
+		t.Error("dev attach kept running after dev down")
+	}
+	if _, err := os.Stat(filepath.Join(state, "dev.pid")); !os.IsNotExist(err) {
+		t.Errorf("pidfile left behind after dev down: %v", err)
+	}
+	if out, code := e2eRun(t, dir, nil, "dev", "attach"); code == 0 || !strings.Contains(out, "no construct dev session") {
+		t.Errorf("attach without a session: exit %d: %s", code, out)
+	}
+}
+
 func TestE2ECloud(t *testing.T) {
 	dir := e2eConstfile(t, `|remote| {
     $ echo local-marker
@@ -382,6 +641,53 @@ use {
 	}
 }
 
+func TestE2ECloudSignedDefinitions(t *testing.T) {
+	dir := e2eConstfile(t, `|remote| {
+    $ echo local-marker
+}
+
+use {
+    invoke remote
+}
+`)
+	cloudFile := filepath.Join(dir, "cloud.json")
+	env := []string{"CONSTRUCT_CLOUD_FILE=" + cloudFile}
+
+	out, code := e2eRun(t, dir, nil, "cloud", "keygen", "signing.pem")
+	if code != 0 {
+		t.Fatalf("cloud keygen exit %d: %s", code, out)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	pub := lines[len(lines)-1]
+	e2eWrite(t, dir, ".construct-trusted-keys", pub+" release-bot\n")
+
+	out, code = e2eRun(t, dir, nil, "cloud", "push", "--sign", "signing.pem", "--file", cloudFile)
+	if code != 0 || !strings.Contains(out, "pushed 1 signed command(s)") {
+		t.Fatalf("cloud push --sign exit %d: %s", code, out)
+	}
+	out, code = e2eRun(t, dir, env, "cloud", "list")
+	if code != 0 || !strings.Contains(out, "trusted") {
+		t.Errorf("cloud list exit %d: %s", code, out)
+	}
+	out, code = e2eRun(t, dir, env, "--no-cache", "use")
+	if code != 0 {
+		t.Fatalf("signed definition exit %d: %s", code, out)
+	}
+
+	data, _ := os.ReadFile(cloudFile)
+	os.WriteFile(cloudFile, []byte(strings.Replace(string(data), "echo local-marker", "echo tampered", 1)), 0644)
+	out, code = e2eRun(t, dir, env, "--no-cache", "use")
+	if code == 0 || strings.Contains(out, "tampered\n") || !strings.Contains(out, "modified after signing") {
+		t.Errorf("tampered definition exit %d: %s", code, out)
+	}
+
+	e2eWrite(t, dir, ".construct-trusted-keys", "policy warn\n"+pub+"\n")
+	out, code = e2eRun(t, dir, env, "--no-cache", "use")
+	if code != 0 || !strings.Contains(out, "warning: cloud definition \"remote\"") || !strings.Contains(out, "tampered") {
+		t.Errorf("policy warn exit %d: %s", code, out)
+	}
+}
+
 func TestE2ECloudSubmit(t *testing.T) {
 	dir := e2eConstfile(t, `build {
     $ echo "local build"
@@ -462,6 +768,134 @@ func TestE2ECloudSubmit(t *testing.T) {
 	}
 }
 
+func TestE2ECloudSubmitGitLab(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := e2eConstfile(t, "build {\n    $ echo hi\n}\n")
+	for _, args := range [][]string{{"init", "-q"}, {"remote", "add", "origin", "git@gitlab.com:group/proj.git"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	var variables []map[string]string
+	mux := http.NewServeMux()
+	const project = "/projects/group%2Fproj"
+	mux.HandleFunc("POST "+project+"/pipeline", func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Variables []map[string]string }
+		json.NewDecoder(r.Body).Decode(&body)
+		variables = body.Variables
+		w.WriteHeader(201)
+		json.NewEncoder(w).Encode(map[string]any{"id": 42, "status": "created", "web_url": "https://gitlab.example/group/proj/-/pipelines/42"})
+	})
+	mux.HandleFunc("GET "+project+"/pipelines/{id}", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"id": 42, "status": "failed", "web_url": "https://gitlab.example/group/proj/-/pipelines/42"})
+	})
+	mux.HandleFunc("GET "+project+"/pipelines/{id}/jobs", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]any{{"id": 7, "name": "construct", "status": "failed"}})
+	})
+	mux.HandleFunc("GET "+project+"/jobs/{id}/trace", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "remote: building...\nremote: token hunter2 leaked\n")
+	})
+	mux.HandleFunc("POST "+project+"/pipelines/{id}/cancel", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"id": 42, "status": "canceled"})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	env := []string{"GITLAB_TOKEN=test-token", "CONSTRUCT_GITLAB_API=" + srv.URL}
+
+	// The gitlab.com remote selects the backend; no workflow file is needed.
+	out, code := e2eRun(t, dir, env, "cloud", "submit", "--wait", "--ref", "main", "-e", "API_TOKEN=hunter2", "build")
+	if code != 1 {
+		t.Fatalf("submit of a failed pipeline exit %d, want 1: %s", code, out)
+	}
+	for _, want := range []string{"triggered pipeline #42 on group/proj", "remote: building...", "*****", "run #42 concluded: failure"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("submit output missing %q: %q", want, out)
+		}
+	}
+	if strings.Contains(out, "hunter2") {
+		t.Errorf("secret leaked in output: %q", out)
+	}
+	if len(variables) != 2 {
+		t.Errorf("pipeline variables = %v", variables)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".github")); err == nil {
+		t.Error("a GitHub workflow was created for a GitLab remote")
+	}
+
+	out, code = e2eRun(t, dir, env, "cloud", "status", "42")
+	if code != 0 || !strings.Contains(out, "run #42: completed (failure)") {
+		t.Errorf("status exit %d: %q", code, out)
+	}
+	out, code = e2eRun(t, dir, env, "cloud", "cancel", "--backend", "gitlab", "--repo", "group/proj", "42")
+	if code != 0 || !strings.Contains(out, "cancelled run #42") {
+		t.Errorf("cancel exit %d: %q", code, out)
+	}
+}
+
+func TestE2ECloudArtifactsAndRunsFromCloud(t *testing.T) {
+	dir := e2eConstfile(t, "test {\n    $ echo local-pass\n}\n")
+	if out, code := e2eRun(t, dir, nil, "test"); code != 0 {
+		t.Fatalf("local run exit %d: %s", code, out)
+	}
+
+	state, _ := json.Marshal(map[string][]map[string]any{
+		"test": {{"status": "failed", "exit": 1, "end": time.Now().Add(-time.Hour), "log": "cloud-fail\n"}},
+	})
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	w, _ := zw.Create("run-state.json")
+	w.Write(state)
+	zw.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/o/r/actions/runs/{id}/artifacts", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"artifacts": []map[string]any{{"id": 9, "name": "construct-cache"}}})
+	})
+	mux.HandleFunc("GET /repos/o/r/actions/artifacts/{id}/zip", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(archive.Bytes())
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	env := []string{"GITHUB_TOKEN=test-token", "CONSTRUCT_GITHUB_API=" + srv.URL}
+
+	out, code := e2eRun(t, dir, env, "cloud", "artifacts", "--repo", "o/r", "--into", "ci", "42")
+	if code != 0 {
+		t.Fatalf("artifacts exit %d: %s", code, out)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ci", "construct-cache", "run-state.json")); err != nil {
+		t.Fatalf("artifact not unpacked: %v\n%s", err, out)
+	}
+
+	// The cloud failure is older than the local pass, so it is run 2.
+	out, code = e2eRun(t, dir, env, "runs", "--from-cloud", "42", "--repo", "o/r", "diff", "test")
+	if code != 0 {
+		t.Fatalf("runs --from-cloud exit %d: %s", code, out)
+	}
+	for _, want := range []string{"merged 1 record(s) from cloud run #42", "run 2 [cloud run #42] (older)", "- cloud-fail", "+ local-pass"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("runs output missing %q: %q", want, out)
+		}
+	}
+	// Cloud records stay out of the local history --resume and stats read.
+	local, _ := os.ReadFile(filepath.Join(dir, ".construct-cache", "run-state.json"))
+	cloud, _ := os.ReadFile(filepath.Join(dir, ".construct-cache", "cloud-runs.json"))
+	if strings.Contains(string(local), "cloud-fail") || !strings.Contains(string(cloud), "cloud-fail") {
+		t.Errorf("run-state.json = %s\ncloud-runs.json = %s", local, cloud)
+	}
+	if out, _ := e2eRun(t, dir, nil, "stats"); !regexp.MustCompile(`(?m)^test\s+1\s`).MatchString(out) {
+		t.Errorf("stats should count only the local run: %s", out)
+	}
+	out, _ = e2eRun(t, dir, env, "runs", "--from-cloud", "42", "--repo", "o/r", "show", "test", "2")
+	if !strings.Contains(out, "merged 0 record(s)") || !strings.Contains(out, "source: cloud run #42") {
+		t.Errorf("re-merge output = %q", out)
+	}
+}
+
 func TestE2ECloudSubmitInitsWorkflow(t *testing.T) {
 	dir := e2eConstfile(t, "build {\n    $ echo hi\n}\n")
 	env := []string{"GITHUB_TOKEN=test-token", "CONSTRUCT_GITHUB_API=https://127.0.0.1:1"}
@@ -499,3 +933,18 @@ deploy (env, opt region) {
 		t.Errorf("override output = %q", out)
 	}
 }
+
+func TestE2EVariadicPassthrough(t *testing.T) {
+	dir := e2eConstfile(t, `
+test (pkgs...) {
+    $ printf '<%s>' &pkgs
+}
+`)
+	out, code := e2eRun(t, dir, nil, "--no-cache", "test", "--", "-run", "TestFoo Bar", "a=b")
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, out)
+	}
+	if !strings.Contains(out, "<-run><TestFoo Bar><a=b>") {
+		t.Errorf("passthrough output = %q", out)
+	}
+}
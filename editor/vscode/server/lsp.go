@@ -725,15 +725,16 @@ func (s *server) handleDefinition(params json.RawMessage) (interface{}, error) {
 
 	if _, name := refAtPosition(line, char); name != "" {
 		for i, l := range lines {
-			trimmed := strings.TrimSpace(l)
-			if strings.HasPrefix(trimmed, "var ") {
-				declName := extractVarDeclName(trimmed)
+			trimmed := strings.TrimPrefix(strings.TrimSpace(l), "private ")
+			if kw, rest, ok := strings.Cut(trimmed, " "); ok && (kw == "var" || kw == "secret") {
+				declName := extractVarDeclName("var " + rest)
 				if declName == name {
+					col := strings.Index(l, kw+" ") + len(kw) + 1
 					return location{
 						URI: p.TextDocument.URI,
 						Range: range_{
-							Start: position{Line: i, Character: 4},
-							End:   position{Line: i, Character: 4 + len(name)},
+							Start: position{Line: i, Character: col},
+							End:   position{Line: i, Character: col + len(name)},
 						},
 					}, nil
 				}
@@ -906,7 +907,7 @@ var statementKeywords = []string{
 	"confirm", "prompt", "input", "timeout<30s>", "service", "port",
 	"cp", "rm", "mkdir", "touch", "download", "extract",
 	"for", "if", "matrix", "env", "invoke", "fail", "global", "parallel",
-	"require_env", "retry", "onfail", "continue", "break",
+	"require_env", "retry", "onfail", "continue", "break", "stop", "on_stop",
 }
 
 var builtinFunctions = []string{
@@ -1368,6 +1369,7 @@ func isIdentRune(r rune) bool {
 }
 
 func commandNameAtLine(line string) (string, bool) {
+	line, _ = pkg.StripPrivate(line)
 	line, _ = pkg.StripManual(line)
 	line, _ = pkg.StripService(line)
 	name := pkg.ParseCommandName(line)
@@ -1660,7 +1662,7 @@ func isPrereqListLine(line string) bool {
 	if strings.HasPrefix(trimmed, "$") {
 		return false
 	}
-	for _, kw := range []string{"var ", "import ", "if ", "for ", "matrix ", "env ", "invoke ", "onfail ", "fail ", "global ", "require_env ", "retry ", "else", "continue", "break", "switch ", "case ", "default", "in ", "lock ", "state ", "confirm ", "prompt ", "input ", "timeout<", "cp ", "rm ", "mkdir ", "touch ", "download ", "extract "} {
+	for _, kw := range []string{"var ", "private ", "secret ", "import ", "workspace ", "profile ", "if ", "for ", "matrix ", "env ", "invoke ", "onfail ", "fail ", "global ", "require_env ", "ready ", "health ", "stop ", "on_stop ", "retry ", "else", "continue", "break", "switch ", "case ", "default", "in ", "lock ", "state ", "confirm ", "prompt ", "input ", "timeout<", "cp ", "rm ", "mkdir ", "touch ", "download ", "extract "} {
 		if strings.HasPrefix(trimmed, kw) {
 			return false
 		}
@@ -1798,7 +1800,7 @@ func keywordHover(word string) (string, bool) {
 	case "var":
 		return "`var name = value`\n\nDeclares a variable; reference it as `&name`. Values support expressions (`[a, b]`, `1 + 2`), `@ENV` refs, and `state(\"name\")`.", true
 	case "import":
-		return "`import \"lib.constfile\" as lib`\n\nMerges another file's commands and variables, optionally under a namespace (`lib.cmd`, `&lib.var`). `import git \"repo\"` fetches remote recipes (pinned in `.construct.lock`), and a trailing `if <cond>` or `on darwin, linux` loads the import conditionally.", true
+		return "`import \"lib.constfile\" as lib`\n\nMerges another file's commands and variables, optionally under a namespace (`lib.cmd`, `&lib.var`). `import git \"repo\"` and `import url \"https://.../x.tar.gz#sha256=...\"` fetch remote recipes (pinned in `.construct.lock`), and a trailing `if <cond>` or `on darwin, linux` loads the import conditionally.", true
 	case "produces":
 		return "`produces <files>`\n\nDeclares the command's outputs. While the artifacts exist and are newer than the command's file dependencies, the command is skipped as up to date.", true
 	case "manual":
@@ -1811,6 +1813,10 @@ func keywordHover(word string) (string, bool) {
 		return "`service name { ... }`\n\nDeclares a long-running process. `construct dev` runs non-service prerequisites first, then supervises services in dependency order — restarting on crash and on `onchange` edits. Ctrl-C stops all.", true
 	case "port":
 		return "`port 8080`\n\nA service's readiness port: `construct dev` starts dependents once the port accepts connections (90s timeout).", true
+	case "stop":
+		return "`stop SIGINT after 30s`\n\nHow `construct dev` stops a service: the signal sent to its process group, and how long it may take to exit before it is killed. Default: `SIGTERM after 10s`.", true
+	case "on_stop":
+		return "`on_stop { ... }`\n\nRuns under `construct dev` each time the service exits — stopped, restarted, or crashed.", true
 	}
 	return "", false
 }
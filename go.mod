@@ -5,6 +5,7 @@ go 1.26
 require (
 	github.com/spf13/pflag v1.0.5
 	golang.org/x/term v0.45.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require golang.org/x/sys v0.47.0
@@ -24,6 +24,7 @@ const version = "0.2.0"
 type ConstructInput struct {
 	FileName string
 	Commands []string
+	Package  string // workspace directory construct was started in, if it ran from the root
 }
 
 func getPlatformConstfile() string {
@@ -79,7 +80,7 @@ func exitAt(code int, format string, args ...any) error {
 
 func exitError(err error) {
 	if msg := err.Error(); msg != "" {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error: %s\n", pkg.MaskSecrets(msg))
 	}
 	if ee, ok := err.(interface{ ExitCode() int }); ok {
 		os.Exit(ee.ExitCode())
@@ -87,7 +88,7 @@ func exitError(err error) {
 	os.Exit(1)
 }
 
-var subcommandNames = []string{"init", "import", "shell", "doctor", "stats", "cloud", "clean", "lint", "graph", "completion", "fmt", "ui", "runs", "mcp", "learn", "install"}
+var subcommandNames = []string{"init", "import", "export", "ci", "shell", "doctor", "stats", "cloud", "clean", "lint", "graph", "completion", "fmt", "ui", "runs", "mcp", "learn", "install", "secrets"}
 
 func isSubcommandName(s string) bool {
 	return slices.Contains(subcommandNames, s)
@@ -132,6 +133,16 @@ func main() {
 		os.Exit(1)
 	}
 
+	pkg.SetFrozenLockfile(o.frozenLockfile)
+	if o.profile == "" {
+		o.profile = os.Getenv("CONSTRUCT_PROFILE")
+	}
+	pkg.SetProfile(o.profile)
+	pkg.SetSecretsKeyFile(o.secretsKeyFile)
+	if o.listProfiles {
+		o.showList = true
+	}
+
 	if o.showHelp {
 		printUsage()
 		os.Exit(0)
@@ -142,9 +153,16 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Words after `--` are passthrough for a target's variadic argument.
+	args := flagSet.Args()
+	if dash := flagSet.ArgsLenAtDash(); dash >= 0 {
+		args, o.passthrough = args[:dash], args[dash:]
+	}
+
 	var positionals []string
-	for _, a := range flagSet.Args() {
-		if strings.Contains(a, "=") && !fileExists(a) {
+	for _, a := range args {
+		// k=v is an override; build(os=linux) is a parameterized target.
+		if k, _, ok := strings.Cut(a, "="); ok && !strings.Contains(k, "(") && !fileExists(a) {
 			o.overrides = append(o.overrides, a)
 		} else {
 			positionals = append(positionals, a)
@@ -183,6 +201,7 @@ func main() {
 	}
 
 	if len(positionals) > 0 && isSubcommandName(positionals[0]) && !commandExistsInConstfile(positionals[0]) {
+		positionals = append(positionals, o.passthrough...)
 		inputs := &ConstructInput{FileName: defaultConstfileName()}
 		if len(positionals) > 1 && fileExists(positionals[1]) {
 			inputs.FileName = positionals[1]
@@ -193,6 +212,10 @@ func main() {
 			err = runInit(positionals[1:], &o)
 		case "import":
 			err = runImport(positionals[1:], &o)
+		case "export":
+			err = runExport(positionals[1:], &o)
+		case "ci":
+			err = runCI(positionals[1:], &o)
 		case "shell":
 			err = runShellCmd(positionals[1:], &o)
 		case "doctor":
@@ -221,6 +244,8 @@ func main() {
 			err = runLearn(positionals[1:], &o)
 		case "install":
 			err = runInstall(positionals[1:], &o)
+		case "secrets":
+			err = runSecrets(positionals[1:], &o)
 		}
 		if err != nil {
 			exitError(err)
@@ -228,7 +253,31 @@ func main() {
 		return
 	}
 
-	runBuildMain(&o, determineInputs(positionals))
+	// Labels address the whole workspace, so run from the root when the user
+	// names one or the Constfile here uses them. Bare targets then address
+	// the package construct was started in (see executeBuild).
+	var startPkg string
+	if root, pkgPath, usesLabels, ok := pkg.WorkspacePackage("."); ok && !namesConstfile(positionals) &&
+		(slices.ContainsFunc(positionals, pkg.IsLabel) || pkgPath != "" && usesLabels) {
+		if err := os.Chdir(root); err != nil {
+			exitError(err)
+		}
+		startPkg = pkgPath
+	}
+
+	inputs := determineInputs(positionals)
+	inputs.Package = startPkg
+	runBuildMain(&o, inputs)
+}
+
+// namesConstfile reports whether the first positional is a Constfile path
+// rather than a target.
+func namesConstfile(positionals []string) bool {
+	if len(positionals) == 0 {
+		return false
+	}
+	info, err := os.Stat(positionals[0])
+	return err == nil && !info.IsDir()
 }
 
 func runBuildMain(o *options, inputs *ConstructInput) {
@@ -259,6 +308,14 @@ func runBuildMain(o *options, inputs *ConstructInput) {
 	}
 
 	// Load environment variables: --env-file, or .env next to the Constfile.
+	// A profile's .env.<profile> loads first: earlier files win, so its
+	// values take precedence.
+	var envPaths []string
+	if o.profile != "" {
+		if candidate := filepath.Join(filepath.Dir(inputs.FileName), ".env."+o.profile); fileExists(candidate) {
+			envPaths = append(envPaths, candidate)
+		}
+	}
 	envPath := o.envFile
 	if envPath == "" {
 		candidate := filepath.Join(filepath.Dir(inputs.FileName), ".env")
@@ -267,14 +324,25 @@ func runBuildMain(o *options, inputs *ConstructInput) {
 		}
 	}
 	if envPath != "" {
-		if err := pkg.LoadEnvFile(envPath); err != nil {
-			fmt.Fprintf(os.Stderr, "Error loading %s: %v\n", envPath, err)
+		envPaths = append(envPaths, envPath)
+	}
+	for _, path := range envPaths {
+		if err := pkg.LoadEnvFile(path); err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading %s: %v\n", path, err)
 			os.Exit(1)
 		}
 	}
 
 	o.concurrent = o.concurrent || o.jobs > 0
 
+	// Only a run that executes commands asks for the secrets passphrase;
+	// --list and --dry-run work with the store locked.
+	if !o.showList && !o.dryRun && stdinIsTerminal() {
+		pkg.SetSecretsPrompt(func() (string, error) {
+			return readPassphrase("Passphrase for " + pkg.SecretsFileName + ": ")
+		})
+	}
+
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
 	defer signal.Stop(sigCh)
@@ -319,7 +387,7 @@ func runBuildMain(o *options, inputs *ConstructInput) {
 				notifySummary(inputs, err, time.Since(runStart))
 			}
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				fmt.Fprintf(os.Stderr, "Error: %s\n", pkg.MaskSecrets(err.Error()))
 				files = []string{inputs.FileName}
 			}
 			if !waitForChange(files, &interrupted) {
@@ -336,7 +404,7 @@ func runBuildMain(o *options, inputs *ConstructInput) {
 			_, err := executeBuild(inputs, o, runCtx)
 			if err != nil {
 				failures++
-				fmt.Fprintf(os.Stderr, "run %d/%d failed: %v\n", i, o.repeat, err)
+				fmt.Fprintf(os.Stderr, "run %d/%d failed: %s\n", i, o.repeat, pkg.MaskSecrets(err.Error()))
 			}
 		}
 		if o.notify {
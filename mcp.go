@@ -11,6 +11,8 @@ import (
 	"os/exec"
 	"strings"
 	"time"
+
+	"github.com/nicklvsa/construct/pkg"
 )
 
 const mcpOutputCap = 100_000
@@ -41,6 +43,9 @@ type mcpServer struct {
 
 func runMCP(args []string) error {
 	fileName, _ := splitConstfileArgs(args)
+	// Parsing registers the Constfile's secrets, so run output and errors
+	// are masked here as well as in the construct runs that produce them.
+	_, _ = parseConstfileOptional(fileName)
 	exe, err := os.Executable()
 	if err != nil {
 		return err
@@ -154,12 +159,16 @@ func (s *mcpServer) callTool(req rpcRequest) *rpcResponse {
 			}
 		}
 		text, callErr := t.Call(s, args)
+		if t.RunOutput {
+			text = pkg.MaskSecrets(text)
+		}
 		if callErr != nil {
 			// Keep the tool's partial output but don't lose the error itself.
+			msg := pkg.MaskSecrets(callErr.Error())
 			if strings.TrimSpace(text) == "" {
-				text = callErr.Error()
-			} else if !strings.Contains(text, callErr.Error()) {
-				text += "\n(error: " + callErr.Error() + ")"
+				text = msg
+			} else if !strings.Contains(text, msg) {
+				text += "\n(error: " + msg + ")"
 			}
 		}
 		return s.ok(req, map[string]any{
@@ -191,6 +200,9 @@ type mcpTool struct {
 	Description string
 	Schema      map[string]any
 	Call        func(s *mcpServer, args mcpToolArgs) (string, error)
+	// RunOutput marks tools that return command output, which is masked
+	// for secrets; the others return Constfile structure, left verbatim.
+	RunOutput bool
 }
 
 func schema(props map[string]any, required ...string) map[string]any {
@@ -257,6 +269,7 @@ var mcpTools = []mcpTool{
 			}
 			return s.run(a, timeout, argv...)
 		},
+		RunOutput: true,
 	},
 	{
 		Name:        "graph",
@@ -308,6 +321,7 @@ var mcpTools = []mcpTool{
 			}
 			return s.run(a, 30*time.Second, argv...)
 		},
+		RunOutput: true,
 	},
 }
 
@@ -76,8 +76,9 @@ func runGitCmdRaw(dir string, args ...string) ([]byte, error) {
 
 // AffectedCommands returns the commands affected by changed (absolute paths,
 // typically from GitChangedFiles): a changed file matches a command's file
-// deps, onchange globs, produces, or declaring Constfile, or any prerequisite
-// is affected. baseDir is the Constfile's directory (absolute).
+// deps, onchange globs, produces, or declaring Constfile, lies in the
+// command's workspace package, or any prerequisite is affected. baseDir is
+// the Constfile's directory (absolute).
 func AffectedCommands(data *ParsedData, changed map[string]bool, baseDir string) map[string]bool {
 	affected := map[string]bool{}
 	visiting := map[string]bool{}
@@ -85,28 +86,40 @@ func AffectedCommands(data *ParsedData, changed map[string]bool, baseDir string)
 	// Deleted files no longer expand from disk, so match globs against the
 	// changed paths directly too.
 	changedRels := make([]string, 0, len(changed))
+	changedPkgs := map[string]bool{}
 	for p := range changed {
 		if rel, err := filepath.Rel(baseDir, p); err == nil && !strings.HasPrefix(rel, "..") {
 			changedRels = append(changedRels, filepath.ToSlash(rel))
+			if pkg := packageOf(filepath.ToSlash(rel), data.Packages); pkg != "" {
+				changedPkgs[pkg] = true
+			}
 		}
 	}
 
 	direct := func(cmd *Command) bool {
+		if cmd.Package != "" && changedPkgs[cmd.Package] {
+			return true
+		}
 		if cmd.SourceFile != "" {
 			if matchesChangedPath(absPath(baseDir, cmd.SourceFile), changed) {
 				return true
 			}
 		}
+		// Package commands declare their file deps relative to the package.
+		dir, prefix := baseDir, ""
+		if cmd.Package != "" {
+			dir, prefix = filepath.Join(baseDir, filepath.FromSlash(cmd.Package)), cmd.Package+"/"
+		}
 		patterns := append(append([]string{}, cmd.FileDeps...), cmd.OnChange...)
 		patterns = append(patterns, cmd.Produces...)
 		for _, pattern := range patterns {
-			for _, f := range expandFileDeps([]string{pattern}, baseDir) {
-				if matchesChangedPath(absPath(baseDir, f), changed) {
+			for _, f := range expandFileDeps([]string{pattern}, dir) {
+				if matchesChangedPath(absPath(dir, f), changed) {
 					return true
 				}
 			}
 			for _, rel := range changedRels {
-				if globMatches(pattern, rel) {
+				if rel, ok := strings.CutPrefix(rel, prefix); ok && globMatches(pattern, rel) {
 					return true
 				}
 			}
@@ -134,6 +147,9 @@ func AffectedCommands(data *ParsedData, changed map[string]bool, baseDir string)
 			return true
 		}
 		for _, pre := range cmd.Prereqs {
+			if cmd.PrereqOrderOnly[pre] {
+				continue // ordering only; a change there never makes this command stale
+			}
 			if visit(strings.TrimSpace(pre)) {
 				affected[name] = true
 				return true
@@ -144,6 +160,9 @@ func AffectedCommands(data *ParsedData, changed map[string]bool, baseDir string)
 	for _, cmd := range data.Commands {
 		visit(cmd.Name)
 	}
+	for _, cmd := range data.Instances() {
+		visit(cmd.Name)
+	}
 	return affected
 }
 
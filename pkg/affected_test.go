@@ -45,6 +45,22 @@ func TestAffectedCommands(t *testing.T) {
 	}
 }
 
+func TestAffectedCommandsOrderOnly(t *testing.T) {
+	base := t.TempDir()
+	data := affectedData()
+	data.Commands[2].Prereqs = []string{"build", "docs"}
+	data.Commands[2].PrereqOrderOnly = map[string]bool{"docs": true}
+
+	changed := map[string]bool{filepath.Join(base, "docs", "intro.md"): true}
+	affected := AffectedCommands(data, changed, base)
+	if !affected["docs"] {
+		t.Error("docs should be affected by docs/intro.md")
+	}
+	if affected["deploy"] {
+		t.Error("an order-only prerequisite should not make deploy affected")
+	}
+}
+
 func TestAffectedCommandsSourceFile(t *testing.T) {
 	base := t.TempDir()
 	data := affectedData()
@@ -1,6 +1,8 @@
 package pkg
 
 import (
+	"fmt"
+	"maps"
 	"slices"
 	"strings"
 )
@@ -80,7 +82,11 @@ func (p *Parser) classifyPrereqs() error {
 			}
 			if _, err := p.Data.GetCommand(prereq); err == nil {
 				cmdDeps = append(cmdDeps, prereq)
-			} else if IsFileDep(prereq) {
+			} else if base, _, ok, _ := parsePrereqCall(prereq); ok && p.hasCommand(base) {
+				cmdDeps = append(cmdDeps, prereq) // instantiated by instantiatePrereqCalls
+			} else if p.isMatrixCell(prereq) {
+				cmdDeps = append(cmdDeps, prereq) // checked once expandMatrices runs
+			} else if IsFileDep(prereq) && !cmd.PrereqOrderOnly[prereq] {
 				fileDeps = append(fileDeps, prereq)
 			} else {
 				return &MissingDependencyError{
@@ -97,6 +103,109 @@ func (p *Parser) classifyPrereqs() error {
 	return nil
 }
 
+func (p *Parser) hasCommand(name string) bool {
+	_, err := p.Data.GetCommand(name)
+	return err == nil
+}
+
+func (p *Parser) isMatrixCell(name string) bool {
+	open := strings.IndexByte(name, '[')
+	if open <= 0 || !strings.HasSuffix(name, "]") {
+		return false
+	}
+	base, err := p.Data.GetCommand(name[:open])
+	return err == nil && base.Matrix != nil
+}
+
+// instantiatePrereqCalls creates a node for every parameterized prerequisite
+// (build(os=linux)). It runs after computeCacheGlobals so instances inherit
+// their base command's cache globals.
+func (p *Parser) instantiatePrereqCalls() error {
+	for _, cmd := range p.Data.Commands {
+		for _, prereq := range cmd.Prereqs {
+			if !strings.HasSuffix(prereq, ")") {
+				continue
+			}
+			if _, err := p.Data.Instance(prereq); err != nil {
+				return NewParseError(cmd.SourceFile, cmd.SourceLine, 1, fmt.Sprintf("command '%s': %v", cmd.Name, err), prereq)
+			}
+		}
+	}
+	return nil
+}
+
+// expandMatrices turns each `matrix` command into a group node whose
+// prerequisites are its cells, build[linux,amd64] and so on. Each cell is an
+// instance carrying the command's body, original prerequisites, and its axis
+// values as bound variables, so it schedules, caches, and records on its own.
+func (p *Parser) expandMatrices() error {
+	for _, cmd := range p.Data.Commands {
+		if cmd.Matrix == nil {
+			continue
+		}
+		m := p.resolveMatrixValues(cmd.Matrix)
+		var cells []string
+		for _, binding := range m.Cells() {
+			name := m.CellName(cmd.Name, binding)
+			for _, v := range binding {
+				if strings.ContainsAny(v, ",[] ") {
+					return NewParseError(cmd.SourceFile, cmd.SourceLine, 1, fmt.Sprintf("matrix value %q cannot contain spaces, commas, or brackets", v), cmd.Name)
+				}
+			}
+			cell := *cmd
+			cell.Name = name
+			cell.BaseName = cmd.Name
+			cell.BoundArgs = binding
+			cell.Matrix = nil
+			cell.IsDefault = false
+			cell.Prereqs = slices.Clone(cmd.Prereqs)
+			cell.PrereqOutput, cell.NamedOutput, cell.PrereqCmds = nil, nil, nil
+			if err := p.Data.addInstance(&cell); err != nil {
+				return NewParseError(cmd.SourceFile, cmd.SourceLine, 1, err.Error(), cmd.Name)
+			}
+			cells = append(cells, name)
+		}
+		if len(cells) == 0 {
+			return NewParseError(cmd.SourceFile, cmd.SourceLine, 1, fmt.Sprintf("matrix for '%s' excludes every combination", cmd.Name), cmd.Name)
+		}
+		cmd.matrixPrereqs = append([]string{}, cmd.Prereqs...)
+		cmd.Prereqs = cells
+	}
+
+	for _, cmd := range append(slices.Clone(p.Data.Commands), p.Data.Instances()...) {
+		for _, prereq := range cmd.Prereqs {
+			if !p.hasCommand(prereq) {
+				return &MissingDependencyError{Command: cmd.Name, PrereqName: prereq, File: cmd.SourceFile, Line: cmd.SourceLine}
+			}
+		}
+	}
+	return nil
+}
+
+// resolveMatrixValues expands `&list` axis values from global variables.
+func (p *Parser) resolveMatrixValues(m *Matrix) *Matrix {
+	out := *m
+	out.Axes = make([]MatrixAxis, len(m.Axes))
+	for i, axis := range m.Axes {
+		var values []string
+		for _, v := range axis.Values {
+			if name, ok := strings.CutPrefix(v, "&"); ok {
+				if val, found := LookupVariableIndexed(p.Data, name, "global"); found {
+					if val.IsList {
+						values = append(values, val.L...)
+					} else {
+						values = append(values, splitTopLevel(val.S, ',')...)
+					}
+					continue
+				}
+			}
+			values = append(values, v)
+		}
+		out.Axes[i] = MatrixAxis{Name: axis.Name, Values: values}
+	}
+	return &out
+}
+
 func (p *Parser) collectIndexedOutputRefs() {
 	refs := p.Data.computeIndexedOutputRefs()
 	p.Data.mu.Lock()
@@ -157,6 +266,131 @@ func (p *Parser) computeCacheGlobals() {
 	}
 }
 
+// checkPrivateRefs rejects references that cross a file boundary into a
+// `private` command or global. Inside the declaring file private names
+// behave like any other.
+func (p *Parser) checkPrivateRefs() error {
+	privateCmds := map[string]*Command{}
+	for _, c := range p.Data.Commands {
+		if c.Private {
+			privateCmds[c.Name] = c
+		}
+	}
+	privateVars := map[string]*Variable{}
+	for _, v := range p.Data.Variables {
+		if v.Private && v.Scope == "global" {
+			privateVars[v.Name] = v
+		}
+	}
+	if len(privateCmds) == 0 && len(privateVars) == 0 {
+		return nil
+	}
+	root := importBaseDir(p.InputFile)
+
+	// owner finds the private declaration a reference reaches: the name
+	// itself or a dotted prefix of it (&lib.build.out reaches lib.build).
+	owner := func(ref string) (kind, name, file string) {
+		for name := ref; name != ""; {
+			if v := privateVars[name]; v != nil {
+				return "variable", name, v.file
+			}
+			if c := privateCmds[name]; c != nil {
+				return "command", c.Label(), c.SourceFile
+			}
+			dot := strings.LastIndexByte(name, '.')
+			if dot < 0 {
+				break
+			}
+			name = name[:dot]
+		}
+		return "", "", ""
+	}
+	crossing := func(from, ref string) error {
+		kind, name, file := owner(ref)
+		if kind == "" || file == from {
+			return nil
+		}
+		return fmt.Errorf("%s %q is private to %s", kind, name, relOrAbs(root, file))
+	}
+
+	for _, v := range p.Data.Variables {
+		for _, ref := range v.refs {
+			if err := crossing(v.file, ref); err != nil {
+				return fmt.Errorf("variable %q: %w", v.Name, err)
+			}
+		}
+	}
+	for _, ov := range p.Data.importOverrides {
+		for _, ref := range ov.refs {
+			if err := crossing(ov.file, ref); err != nil {
+				return NewParseError(ov.file, ov.line, 1, fmt.Sprintf("import %q: with %s: %v", ov.importPath, ov.key, err), ov.key)
+			}
+		}
+	}
+
+	shadows := commandShadowSets(p.Data)
+	for _, cmd := range p.Data.Commands {
+		fail := func(err error) error {
+			return NewParseError(cmd.SourceFile, cmd.SourceLine, 1, fmt.Sprintf("command '%s': %v", cmd.Name, err), cmd.Name)
+		}
+		for _, prereq := range cmd.Prereqs {
+			name := PrereqBase(strings.TrimSpace(prereq))
+			if c := privateCmds[name]; c != nil && c.SourceFile != cmd.SourceFile {
+				return fail(fmt.Errorf("command %q is private to %s", c.Label(), relOrAbs(root, c.SourceFile)))
+			}
+		}
+		for _, target := range invokeTargets(cmd.Body, nil) {
+			if c := privateCmds[target]; c != nil && c.SourceFile != cmd.SourceFile {
+				return fail(fmt.Errorf("command %q is private to %s", c.Label(), relOrAbs(root, c.SourceFile)))
+			}
+		}
+
+		refs := map[string]bool{}
+		collectStmtRefs(cmd.Body, refs)
+		headers := append(append(slices.Clone(cmd.Produces), cmd.OnChange...), cmd.WorkDir, cmd.Guard)
+		headers = append(headers, cmd.Prereqs...)
+		for _, cond := range cmd.PrereqConds {
+			headers = append(headers, cond)
+		}
+		if cmd.Matrix != nil {
+			for _, axis := range cmd.Matrix.Axes {
+				headers = append(headers, axis.Values...)
+			}
+		}
+		for _, str := range headers {
+			for _, n := range VarRefNames(str) {
+				refs[n] = true
+			}
+		}
+		for _, ref := range slices.Sorted(maps.Keys(refs)) {
+			if shadows[cmd.Name][firstIdent(ref)] {
+				continue
+			}
+			if err := crossing(cmd.SourceFile, ref); err != nil {
+				return fail(err)
+			}
+		}
+	}
+	return nil
+}
+
+// invokeTargets appends the command names a statement tree invokes.
+func invokeTargets(stmts []BodyStatement, out []string) []string {
+	for _, stmt := range stmts {
+		if stmt.Type == StmtInvoke {
+			out = append(out, strings.TrimSpace(stmt.Shell))
+		}
+		for _, c := range stmt.Cases {
+			out = invokeTargets(c.Body, out)
+		}
+		out = invokeTargets(stmt.ThenBody, out)
+		out = invokeTargets(stmt.ElseBody, out)
+		out = invokeTargets(stmt.LoopBody, out)
+		out = invokeTargets(stmt.OnFailBody, out)
+	}
+	return out
+}
+
 // collectStmtRefs gathers every &name referenced by a statement tree.
 func collectStmtRefs(stmts []BodyStatement, out map[string]bool) {
 	collectStmtRefsWith(stmts, out, VarRefNames)
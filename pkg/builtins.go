@@ -255,7 +255,13 @@ func (e *Executor) builtinExtract(ctx *execContext, args []string) error {
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return err
 	}
-	lower := strings.ToLower(archive)
+	return extractArchive(archive, archive, dir)
+}
+
+// extractArchive unpacks archive into dir, choosing the format from name's
+// extension (name differs from the path for downloads kept under a temp name).
+func extractArchive(archive, name, dir string) error {
+	lower := strings.ToLower(name)
 	switch {
 	case strings.HasSuffix(lower, ".zip"):
 		return extractZip(archive, dir)
@@ -7,9 +7,11 @@ import (
 	"fmt"
 	"io"
 	"maps"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -34,8 +36,23 @@ func (e *Executor) statePath() string {
 	return filepath.Join(e.cacheDirFor(), "state.json")
 }
 
+const (
+	runStateFile  = "run-state.json"
+	cloudRunsFile = "cloud-runs.json" // merged by `runs --from-cloud`; only `runs` reads it
+)
+
 func LoadRunHistory(dir string) map[string][]RunRecord {
-	data, err := os.ReadFile(filepath.Join(dir, "run-state.json"))
+	return loadRunHistoryFile(filepath.Join(dir, runStateFile))
+}
+
+// LoadCloudRunHistory returns the cloud run records merged into dir. They
+// stay out of run-state.json so --resume and stats only see local runs.
+func LoadCloudRunHistory(dir string) map[string][]RunRecord {
+	return loadRunHistoryFile(filepath.Join(dir, cloudRunsFile))
+}
+
+func loadRunHistoryFile(path string) map[string][]RunRecord {
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil
 	}
@@ -57,11 +74,46 @@ func LastRecord(hist map[string][]RunRecord) map[string]RunRecord {
 }
 
 func SaveRunHistory(dir string, hist map[string][]RunRecord) {
+	saveRunHistoryFile(dir, runStateFile, hist)
+}
+
+func SaveCloudRunHistory(dir string, hist map[string][]RunRecord) {
+	saveRunHistoryFile(dir, cloudRunsFile, hist)
+}
+
+func saveRunHistoryFile(dir, name string, hist map[string][]RunRecord) {
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return
 	}
 	data, _ := json.MarshalIndent(hist, "", "  ")
-	_ = os.WriteFile(filepath.Join(dir, "run-state.json"), data, 0644)
+	_ = os.WriteFile(filepath.Join(dir, name), data, 0644)
+}
+
+// MergeRunHistory adds src's records to hist, tagged with source, keeping
+// each command's records in End order and its newest 50. Records already in
+// hist (same end time and status) are skipped, so merging twice is harmless.
+// It returns how many records were added.
+func MergeRunHistory(hist, src map[string][]RunRecord, source string) int {
+	added := 0
+	for name, recs := range src {
+		merged := hist[name]
+		for _, r := range recs {
+			if slices.ContainsFunc(merged, func(m RunRecord) bool { return m.End.Equal(r.End) && m.Status == r.Status }) {
+				continue
+			}
+			if r.Source == "" {
+				r.Source = source
+			}
+			merged = append(merged, r)
+			added++
+		}
+		sort.SliceStable(merged, func(i, j int) bool { return merged[i].End.Before(merged[j].End) })
+		if len(merged) > 50 {
+			merged = merged[len(merged)-50:]
+		}
+		hist[name] = merged
+	}
+	return added
 }
 
 func (e *Executor) recordRun(name string, rec RunRecord) {
@@ -71,6 +123,8 @@ func (e *Executor) recordRun(name string, rec RunRecord) {
 	if e.recordLogs {
 		rec.Log = e.takeRunLog(name)
 	}
+	rec.Profile = e.StructuredParse.Profile
+	rec.Error = MaskSecrets(rec.Error)
 	e.mu.Lock()
 	if e.runRecords == nil {
 		e.runRecords = make(map[string]RunRecord)
@@ -105,6 +159,55 @@ func (e *Executor) saveRunRecords() {
 	SaveRunHistory(dir, hist)
 }
 
+// outputsPath is where a command's `as name` outputs are kept between
+// runs: one file per command, so CI jobs that each ran one command can
+// pass theirs along as artifacts without overwriting each other's.
+func (e *Executor) outputsPath(name string) string {
+	return filepath.Join(e.cacheDirFor(), "outputs", url.QueryEscape(name)+".json")
+}
+
+// saveNamedOutputs records a successful command's `as name` outputs.
+func (e *Executor) saveNamedOutputs(cmd *Command) {
+	if !e.recordRuns {
+		return
+	}
+	e.mu.Lock()
+	outputs := make(map[string]string, len(cmd.NamedOutput))
+	for name, v := range cmd.NamedOutput {
+		outputs[name] = MaskSecrets(v)
+	}
+	e.mu.Unlock()
+	path := e.outputsPath(cmd.Name)
+	if len(outputs) == 0 {
+		os.Remove(path)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	data, _ := json.MarshalIndent(outputs, "", "  ")
+	_ = os.WriteFile(path, data, 0644)
+}
+
+// restoreNamedOutputs gives prerequisites skipped by --no-deps the outputs
+// their last successful run saved, for seedPrereqOutputs to expose.
+func (e *Executor) restoreNamedOutputs(cmds []*Command) {
+	for _, cmd := range cmds {
+		data, err := os.ReadFile(e.outputsPath(cmd.Name))
+		if err != nil {
+			continue
+		}
+		var outputs map[string]string
+		if err := json.Unmarshal(data, &outputs); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: ignoring corrupt outputs file %s: %v\n", e.outputsPath(cmd.Name), err)
+			continue
+		}
+		e.mu.Lock()
+		cmd.NamedOutput = outputs
+		e.mu.Unlock()
+	}
+}
+
 func (e *Executor) loadState() {
 	e.mu.Lock()
 	defer e.mu.Unlock()
@@ -311,21 +414,28 @@ func (e *Executor) cacheKey(cmd *Command) string {
 	parts := []string{name}
 	for _, arg := range cmd.Arguments {
 		v := arg.Default
-		if e.flagSet != nil {
-			v, _ = e.flagSet.GetString(cmd.Name + ":" + arg.Name)
+		if bound, ok := cmd.BoundArgs[arg.Name]; ok && !arg.IsVariadic {
+			v = bound
+		} else if arg.IsVariadic {
+			v = strings.Join(e.variadicWords(cmd, arg), "\x00")
+		} else if e.flagSet != nil {
+			v, _ = e.flagSet.GetString(cmd.flagScope() + ":" + arg.Name)
 		}
 		parts = append(parts, arg.Name+"="+v)
 	}
 	snapshot := e.StructuredParse.GlobalVariableSnapshot()
 	if cmd.cacheGlobalsExact {
 		for _, g := range cmd.cacheGlobals {
-			parts = append(parts, "var:"+g+"="+snapshot[g])
+			parts = append(parts, "var:"+g+"="+secretDigest(snapshot[g]))
 		}
 	} else {
 		for name, val := range snapshot {
-			parts = append(parts, "var:"+name+"="+val)
+			parts = append(parts, "var:"+name+"="+secretDigest(val))
 		}
 	}
+	if profile := e.StructuredParse.Profile; profile != "" {
+		parts = append(parts, "profile="+profile)
+	}
 	sort.Strings(parts[1:])
 	return strings.Join(parts, "|")
 }
@@ -0,0 +1,322 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ciJob is one job of a generated pipeline: a command, or a matrix command
+// whose cells become the CI matrix.
+type ciJob struct {
+	id      string
+	command string
+	cells   []string
+	needs   []string
+	outputs []string // declared produces, relative to the Constfile
+}
+
+// ciGitLabKeywords are top-level .gitlab-ci.yml keys a job cannot be named.
+var ciGitLabKeywords = map[string]bool{
+	"default": true, "include": true, "stages": true, "variables": true, "workflow": true,
+	"image": true, "services": true, "cache": true, "before_script": true, "after_script": true,
+	"types": true, "pages": true,
+}
+
+// GenerateCI renders targets (the default command when none are given) as a
+// GitHub Actions workflow or a GitLab CI pipeline. Every command in the
+// prerequisite closure is a job that needs its prerequisites' jobs; a matrix
+// command is one job with a CI matrix over its cells. Each job restores and
+// saves a .construct-cache of its own, runs only its own command (construct
+// --no-deps) after downloading its needs' artifacts, and uploads its declared
+// outputs and `as` outputs (.construct-cache/outputs, one file per command,
+// so artifacts merge without overwriting each other) for its dependents.
+// overrides (key=value) are passed to every job as -e flags.
+func GenerateCI(data *ParsedData, provider string, targets, overrides []string) (string, error) {
+	if provider != "github" && provider != "gitlab" {
+		return "", fmt.Errorf("unknown CI provider %q (want github or gitlab)", provider)
+	}
+	var names []string
+	for _, t := range targets {
+		names = append(names, data.MatrixTargets(t)...)
+	}
+	if len(names) == 0 {
+		def, err := data.GetDefaultCommand()
+		if err != nil || def == nil {
+			return "", errors.New("no commands requested and no default ('_') command defined")
+		}
+		names = []string{def.Name}
+	}
+
+	g := &ciGraph{data: data, provider: provider, needs: map[string][]string{}, taken: map[string]bool{}}
+	for _, name := range names {
+		g.visit(name)
+	}
+	if len(g.problems) > 0 {
+		return "", fmt.Errorf("cannot generate a %s pipeline:\n  %s", provider, strings.Join(g.problems, "\n  "))
+	}
+
+	var flags []string
+	for _, ov := range overrides {
+		flags = append(flags, "-e", ov)
+	}
+	header := fmt.Sprintf("# Generated by `construct ci generate %s` for %s. Regenerate it when the graph changes.\n", provider, strings.Join(names, ", "))
+	if provider == "github" {
+		return header + githubWorkflow(g.jobs, flags), nil
+	}
+	return header + gitlabPipeline(g.jobs, flags), nil
+}
+
+type ciGraph struct {
+	data     *ParsedData
+	provider string
+	jobs     []*ciJob
+	needs    map[string][]string // command name -> the job ids its dependents need
+	taken    map[string]bool
+	problems []string
+}
+
+// visit adds name's job after its prerequisites' and returns the job ids a
+// dependent needs. A command without a body gets no job of its own: its
+// dependents need its prerequisites' jobs instead.
+func (g *ciGraph) visit(name string) []string {
+	if ids, ok := g.needs[name]; ok {
+		return ids
+	}
+	g.needs[name] = nil
+	cmd, err := g.data.Instance(name)
+	if err != nil {
+		g.problems = append(g.problems, err.Error())
+		return nil
+	}
+	if cmd.IsService {
+		g.problems = append(g.problems, fmt.Sprintf("%s (line %d): services run until stopped and cannot be a CI job", cmd.Name, cmd.SourceLine))
+		return nil
+	}
+	job := &ciJob{command: cmd.Name}
+	prereqs := cmd.Prereqs
+	if cmd.Matrix != nil && cmd.BaseName == "" {
+		prereqs = cmd.matrixPrereqs
+		job.cells = cmd.Prereqs
+	}
+	job.outputs = ciOutputs(cmd)
+	for _, cell := range job.cells {
+		if c, err := g.data.Instance(cell); err == nil {
+			for _, out := range ciOutputs(c) {
+				if !slices.Contains(job.outputs, out) {
+					job.outputs = append(job.outputs, out)
+				}
+			}
+		}
+	}
+	for _, p := range prereqs {
+		for _, id := range g.visit(p) {
+			if !slices.Contains(job.needs, id) {
+				job.needs = append(job.needs, id)
+			}
+		}
+	}
+	if len(cmd.Body) == 0 && job.cells == nil {
+		g.needs[name] = job.needs
+		return job.needs
+	}
+	job.id = g.jobID(cmd.Name)
+	g.needs[name] = []string{job.id}
+	g.jobs = append(g.jobs, job)
+	return g.needs[name]
+}
+
+// ciOutputs lists cmd's produces paths as a job uploads them: joined to a
+// literal `in` directory, with the leading ./ dropped.
+func ciOutputs(cmd *Command) []string {
+	var outs []string
+	for _, p := range cmd.Produces {
+		p = filepath.ToSlash(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		if cmd.WorkDir != "" && !strings.Contains(cmd.WorkDir, "&") && !path.IsAbs(p) {
+			p = path.Join(filepath.ToSlash(cmd.WorkDir), p)
+		}
+		outs = append(outs, strings.TrimPrefix(p, "./"))
+	}
+	return outs
+}
+
+// jobID returns name's slug, kept unique and clear of GitLab's reserved keys.
+func (g *ciGraph) jobID(name string) string {
+	id := ciSlug(name)
+	if g.provider == "gitlab" && ciGitLabKeywords[id] {
+		id += "-job"
+	}
+	base := id
+	for n := 2; g.taken[id]; n++ {
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
+	g.taken[id] = true
+	return id
+}
+
+// ciSlug maps a command name onto the characters both providers accept in a
+// job key: [A-Za-z0-9_-], not starting with a digit or dash.
+func ciSlug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, c := range name {
+		if c == '_' || c == '-' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') {
+			b.WriteRune(c)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	id := strings.TrimRight(b.String(), "-")
+	if id == "" || (id[0] >= '0' && id[0] <= '9') {
+		id = "_" + id
+	}
+	return id
+}
+
+func githubWorkflow(jobs []*ciJob, flags []string) string {
+	matrix := map[string]bool{}
+	for _, job := range jobs {
+		matrix[job.id] = job.cells != nil
+	}
+	var b strings.Builder
+	b.WriteString("name: construct\n")
+	b.WriteString("on:\n  push:\n  pull_request:\n  workflow_dispatch:\n\n")
+	b.WriteString("jobs:\n")
+	for i, job := range jobs {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "  %s:\n", job.id)
+		artifact := "construct-" + job.id
+		cacheKey := "construct-" + job.id
+		run := shellQuoteWords(append(append([]string{"construct", "--no-deps"}, flags...), job.command))
+		if job.cells != nil {
+			b.WriteString("    name: ${{ matrix.target }}\n")
+		} else {
+			fmt.Fprintf(&b, "    name: %s\n", yamlString(job.command))
+		}
+		b.WriteString("    runs-on: ubuntu-latest\n")
+		if len(job.needs) > 0 {
+			fmt.Fprintf(&b, "    needs: [%s]\n", strings.Join(job.needs, ", "))
+		}
+		if job.cells != nil {
+			b.WriteString("    strategy:\n      fail-fast: false\n      matrix:\n        include:\n")
+			for _, cell := range job.cells {
+				fmt.Fprintf(&b, "          - target: %s\n            key: %s\n", yamlString(cell), ciSlug(cell))
+			}
+			artifact += ".${{ matrix.key }}"
+			cacheKey = "construct-${{ matrix.key }}"
+			run = shellQuoteWords(append([]string{"construct", "--no-deps"}, flags...)) + ` "$CONSTRUCT_TARGET"`
+		}
+		b.WriteString("    steps:\n")
+		b.WriteString("      - uses: actions/checkout@v4\n\n")
+		b.WriteString("      - name: Cache run state\n")
+		b.WriteString("        uses: actions/cache@v4\n")
+		b.WriteString("        with:\n")
+		b.WriteString("          path: .construct-cache\n")
+		fmt.Fprintf(&b, "          key: %s-${{ github.sha }}\n", cacheKey)
+		fmt.Fprintf(&b, "          restore-keys: %s-\n", cacheKey)
+		// A need's outputs and run state land in the workspace, so this
+		// job finds what its prerequisites built without rebuilding it.
+		for _, need := range job.needs {
+			pattern := "construct-" + need
+			if matrix[need] {
+				pattern += ".*"
+			}
+			fmt.Fprintf(&b, "\n      - name: Restore %s outputs\n", need)
+			b.WriteString("        uses: actions/download-artifact@v4\n")
+			b.WriteString("        with:\n")
+			fmt.Fprintf(&b, "          pattern: %s\n", yamlString(pattern))
+			b.WriteString("          merge-multiple: true\n")
+		}
+		b.WriteString("\n      - name: Install construct\n")
+		b.WriteString("        run: go install github.com/nicklvsa/construct@latest\n\n")
+		b.WriteString("      - name: Run construct\n")
+		if job.cells != nil {
+			b.WriteString("        env:\n          CONSTRUCT_TARGET: ${{ matrix.target }}\n")
+		}
+		fmt.Fprintf(&b, "        run: %s\n\n", yamlString(run))
+		b.WriteString("      - name: Upload outputs\n")
+		b.WriteString("        if: always()\n")
+		b.WriteString("        uses: actions/upload-artifact@v4\n")
+		b.WriteString("        with:\n")
+		fmt.Fprintf(&b, "          name: %s\n", artifact)
+		b.WriteString("          path: |\n            .construct-cache/outputs\n")
+		for _, out := range job.outputs {
+			fmt.Fprintf(&b, "            %s\n", out)
+		}
+		b.WriteString("          include-hidden-files: true\n")
+		b.WriteString("          if-no-files-found: ignore\n")
+	}
+	return b.String()
+}
+
+// gitlabPipeline relies on needs: downloading the needed jobs' artifacts,
+// which carry their declared outputs and .construct-cache/outputs, after the
+// job's own cache is restored.
+func gitlabPipeline(jobs []*ciJob, flags []string) string {
+	var b strings.Builder
+	b.WriteString("default:\n")
+	b.WriteString("  image: golang:latest\n")
+	b.WriteString("  before_script:\n")
+	b.WriteString("    - go install github.com/nicklvsa/construct@latest\n")
+	for _, job := range jobs {
+		fmt.Fprintf(&b, "\n%s:\n", job.id)
+		run := shellQuoteWords(append(append([]string{"construct", "--no-deps"}, flags...), job.command))
+		if job.cells != nil {
+			b.WriteString("  parallel:\n    matrix:\n      - CONSTRUCT_TARGET:\n")
+			for _, cell := range job.cells {
+				fmt.Fprintf(&b, "          - %s\n", yamlString(cell))
+			}
+			run = shellQuoteWords(append([]string{"construct", "--no-deps"}, flags...)) + ` "$CONSTRUCT_TARGET"`
+		}
+		if len(job.needs) > 0 {
+			fmt.Fprintf(&b, "  needs: [%s]\n", strings.Join(job.needs, ", "))
+		} else {
+			b.WriteString("  needs: []\n")
+		}
+		b.WriteString("  cache:\n")
+		b.WriteString("    key: construct-$CI_JOB_NAME_SLUG\n")
+		b.WriteString("    paths: [.construct-cache/]\n")
+		b.WriteString("  script:\n")
+		fmt.Fprintf(&b, "    - %s\n", yamlString(run))
+		b.WriteString("  artifacts:\n")
+		b.WriteString("    when: always\n")
+		b.WriteString("    paths:\n      - .construct-cache/outputs/\n")
+		for _, out := range job.outputs {
+			fmt.Fprintf(&b, "      - %s\n", yamlString(out))
+		}
+	}
+	return b.String()
+}
+
+// yamlString writes s as a plain scalar when YAML reads it back unchanged,
+// quoted otherwise.
+func yamlString(s string) string {
+	plain := s != "" && !strings.ContainsAny(s, ":#[]{},&*!|>'\"%@`\\\n\t") &&
+		strings.TrimSpace(s) == s && !strings.HasPrefix(s, "-") && !strings.HasPrefix(s, "?")
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~":
+		plain = false
+	}
+	if plain {
+		if s[0] >= '0' && s[0] <= '9' || s[0] == '.' || s[0] == '+' {
+			plain = false
+		}
+	}
+	if plain {
+		return s
+	}
+	if !strings.ContainsAny(s, "'\n\t") {
+		return "'" + s + "'"
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`).Replace(s) + `"`
+}
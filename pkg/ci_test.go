@@ -0,0 +1,165 @@
+package pkg
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const ciConstfile = `gen in tools produces gen.out {
+    $ echo gen
+}
+
+build matrix os in linux, darwin; arch in amd64 < gen {
+    $ echo &os &arch
+}
+
+lint {
+    $ echo lint
+}
+
+checks < build, lint { }
+
+image < checks {
+    $ echo image
+}
+`
+
+func generateCI(t *testing.T, src, provider string, targets ...string) (map[string]any, error) {
+	t.Helper()
+	p := NewParserFromContent("Constfile", src)
+	data, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := GenerateCI(data, provider, targets, []string{"mode=ci"})
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	if err := yaml.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("invalid YAML: %v\n%s", err, out)
+	}
+	return doc, nil
+}
+
+// ciStep returns the step of a GitHub job with the given name.
+func ciStep(t *testing.T, job any, name string) map[string]any {
+	t.Helper()
+	for _, s := range job.(map[string]any)["steps"].([]any) {
+		if step := s.(map[string]any); step["name"] == name {
+			return step
+		}
+	}
+	t.Fatalf("no %q step in %v", name, job)
+	return nil
+}
+
+func TestGenerateCIGitHubJobsFollowTheGraph(t *testing.T) {
+	doc, err := generateCI(t, ciConstfile, "github", "image")
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs := doc["jobs"].(map[string]any)
+	if _, ok := jobs["checks"]; ok || len(jobs) != 4 {
+		t.Fatalf("jobs = %v, want gen, build, lint, image (checks has no body)", jobs)
+	}
+	image := jobs["image"].(map[string]any)
+	if got := image["needs"]; !reflect.DeepEqual(got, []any{"build", "lint"}) {
+		t.Errorf("image needs = %v, want the jobs behind checks", got)
+	}
+	if run := ciStep(t, image, "Run construct")["run"]; run != "construct --no-deps -e mode=ci image" {
+		t.Errorf("run = %q, want image alone", run)
+	}
+
+	build := jobs["build"].(map[string]any)
+	include := build["strategy"].(map[string]any)["matrix"].(map[string]any)["include"].([]any)
+	want := []any{
+		map[string]any{"target": "build[linux,amd64]", "key": "build-linux-amd64"},
+		map[string]any{"target": "build[darwin,amd64]", "key": "build-darwin-amd64"},
+	}
+	if !reflect.DeepEqual(include, want) {
+		t.Errorf("matrix include = %v, want %v", include, want)
+	}
+	if got := build["needs"]; !reflect.DeepEqual(got, []any{"gen"}) {
+		t.Errorf("build needs = %v, want [gen]", got)
+	}
+}
+
+func TestGenerateCIGitHubPassesArtifacts(t *testing.T) {
+	doc, err := generateCI(t, ciConstfile, "github", "image")
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs := doc["jobs"].(map[string]any)
+	for _, tc := range []struct{ job, need, pattern string }{
+		{"image", "build", "construct-build.*"},
+		{"image", "lint", "construct-lint"},
+		{"build", "gen", "construct-gen"},
+	} {
+		step := ciStep(t, jobs[tc.job], "Restore "+tc.need+" outputs")
+		with := step["with"].(map[string]any)
+		if step["uses"] != "actions/download-artifact@v4" || with["pattern"] != tc.pattern || with["merge-multiple"] != true {
+			t.Errorf("%s restoring %s = %v", tc.job, tc.need, step)
+		}
+	}
+	for _, tc := range []struct{ job, name, path string }{
+		{"gen", "construct-gen", ".construct-cache/outputs\ntools/gen.out\n"},
+		{"build", "construct-build.${{ matrix.key }}", ".construct-cache/outputs\n"},
+		{"image", "construct-image", ".construct-cache/outputs\n"},
+	} {
+		step := ciStep(t, jobs[tc.job], "Upload outputs")
+		with := step["with"].(map[string]any)
+		if step["if"] != "always()" || with["name"] != tc.name || with["path"] != tc.path || with["include-hidden-files"] != true {
+			t.Errorf("%s upload = %v", tc.job, step)
+		}
+	}
+	for job, key := range map[string]string{"gen": "construct-gen-", "build": "construct-${{ matrix.key }}-", "image": "construct-image-"} {
+		with := ciStep(t, jobs[job], "Cache run state")["with"].(map[string]any)
+		if with["path"] != ".construct-cache" || with["restore-keys"] != key || with["key"] != key+"${{ github.sha }}" {
+			t.Errorf("%s cache = %v, want keyed %s", job, with, key)
+		}
+	}
+}
+
+func TestGenerateCIGitLab(t *testing.T) {
+	doc, err := generateCI(t, ciConstfile, "gitlab", "image", "build[linux,*]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := doc["image-job"]; !ok {
+		t.Fatalf("reserved job name not renamed: %v", doc)
+	}
+	if got := doc["image-job"].(map[string]any)["needs"]; !reflect.DeepEqual(got, []any{"build", "lint"}) {
+		t.Errorf("image needs = %v", got)
+	}
+	cell := doc["build-linux-amd64"].(map[string]any)
+	if got := cell["needs"]; !reflect.DeepEqual(got, []any{"gen"}) || cell["parallel"] != nil {
+		t.Errorf("selected cell job = %v, want a plain job needing gen", cell)
+	}
+	gen := doc["gen"].(map[string]any)
+	if got := gen["script"]; !reflect.DeepEqual(got, []any{"construct --no-deps -e mode=ci gen"}) {
+		t.Errorf("gen script = %v, want gen alone", got)
+	}
+	want := map[string]any{"when": "always", "paths": []any{".construct-cache/outputs/", "tools/gen.out"}}
+	if got := gen["artifacts"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("gen artifacts = %v, want %v", got, want)
+	}
+	want = map[string]any{"key": "construct-$CI_JOB_NAME_SLUG", "paths": []any{".construct-cache/"}}
+	if got := gen["cache"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("gen cache = %v, want %v", got, want)
+	}
+	matrix := doc["build"].(map[string]any)["parallel"].(map[string]any)["matrix"].([]any)
+	if got := matrix[0].(map[string]any)["CONSTRUCT_TARGET"]; !reflect.DeepEqual(got, []any{"build[linux,amd64]", "build[darwin,amd64]"}) {
+		t.Errorf("parallel matrix = %v", got)
+	}
+}
+
+func TestGenerateCIRejectsServices(t *testing.T) {
+	_, err := generateCI(t, "service api {\n    $ ./api\n}\n\ne2e < api {\n    $ echo e2e\n}\n", "github", "e2e")
+	if err == nil || !strings.Contains(err.Error(), "api (line 1): services run until stopped") {
+		t.Fatalf("err = %v, want the service reported", err)
+	}
+}
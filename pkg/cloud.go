@@ -1,7 +1,9 @@
 package pkg
 
 import (
+	"crypto/ed25519"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -12,6 +14,7 @@ import (
 type CloudEntry struct {
 	Name      string
 	BodyStmts int
+	Signature string // trusted, unsigned, invalid, or untrusted
 }
 
 func (e *Executor) resolveCloudFile() string {
@@ -56,9 +59,18 @@ func (e *Executor) CloudList() ([]CloudEntry, error) {
 	if err != nil {
 		return nil, err
 	}
+	tk, err := LoadTrustedKeys(filepath.Join(e.baseDir, TrustedKeysFile))
+	if err != nil {
+		return nil, err
+	}
 	var out []CloudEntry
 	for name, c := range defs {
-		out = append(out, CloudEntry{Name: name, BodyStmts: cloudStmtCount(c)})
+		sig := "trusted"
+		var ve *CloudVerifyError
+		if err := VerifyCloudDef(name, c, tk); errors.As(err, &ve) {
+			sig = ve.Status
+		}
+		out = append(out, CloudEntry{Name: name, BodyStmts: cloudStmtCount(c), Signature: sig})
 	}
 	slices.SortFunc(out, func(a, b CloudEntry) int { return strings.Compare(a.Name, b.Name) })
 	return out, nil
@@ -99,7 +111,10 @@ func (e *Executor) CloudPull(names []string, outFile string) (int, error) {
 	return len(selected), nil
 }
 
-func (e *Executor) CloudPush(names []string, file string) (int, error) {
+// CloudPush writes the named commands, or every cloud-accessible one, into
+// the cloud file. With a key, each definition is signed as it will be read
+// back.
+func (e *Executor) CloudPush(names []string, file string, key ed25519.PrivateKey) (int, error) {
 	var cmds []*Command
 	if len(names) > 0 {
 		for _, n := range names {
@@ -127,7 +142,16 @@ func (e *Executor) CloudPush(names []string, file string) (int, error) {
 		return 0, err
 	}
 	for _, c := range cmds {
-		defs[c.Name] = *c
+		def := *c
+		if key != nil {
+			if def, err = roundTripCommand(def); err != nil {
+				return 0, err
+			}
+			if err := SignCloudDef(c.Name, &def, key); err != nil {
+				return 0, err
+			}
+		}
+		defs[c.Name] = def
 	}
 	data, err := json.MarshalIndent(defs, "", "  ")
 	if err != nil {
@@ -138,3 +162,13 @@ func (e *Executor) CloudPush(names []string, file string) (int, error) {
 	}
 	return len(cmds), nil
 }
+
+func roundTripCommand(c Command) (Command, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return Command{}, err
+	}
+	var out Command
+	err = json.Unmarshal(data, &out)
+	return out, err
+}
@@ -0,0 +1,212 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CloudRun is a dispatched construct run in GitHub Actions' vocabulary:
+// Status is queued, in_progress, or completed, and a completed run's
+// Conclusion is success, failure, cancelled, or skipped. Other backends map
+// their states onto these.
+type CloudRun struct {
+	ID         int64     `json:"id"`
+	Status     string    `json:"status"`
+	Conclusion string    `json:"conclusion"`
+	HTMLURL    string    `json:"html_url"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type CloudJob struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion"`
+}
+
+// CloudArtifact is a zip archive a run uploaded: a GitHub Actions artifact,
+// or a GitLab job's artifacts.
+type CloudArtifact struct {
+	ID      int64  `json:"id"`
+	Name    string `json:"name"`
+	Size    int64  `json:"size"`
+	Expired bool   `json:"expired,omitempty"`
+}
+
+// artifactHTTP has no overall timeout: archives can take far longer to
+// download than an API call.
+var artifactHTTP = &http.Client{}
+
+// CloudBackend is a CI service that runs construct for `construct cloud`.
+// Dispatch inputs are "targets" and "args", the space-separated construct
+// targets and extra CLI arguments. Dispatch returns the run when the service
+// reports it at once; otherwise its ID is 0 and LatestRun finds it later.
+type CloudBackend interface {
+	Name() string
+	Dispatch(ctx context.Context, ref string, inputs map[string]string) (CloudRun, error)
+	LatestRun(ctx context.Context, since time.Time) (CloudRun, error)
+	Run(ctx context.Context, runID int64) (CloudRun, error)
+	Jobs(ctx context.Context, runID int64) ([]CloudJob, error)
+	JobLogs(ctx context.Context, jobID int64) ([]byte, error)
+	Cancel(ctx context.Context, runID int64) error
+	Artifacts(ctx context.Context, runID int64) ([]CloudArtifact, error)
+	DownloadArtifact(ctx context.Context, artifactID int64, w io.Writer) error
+	// LiveLogs reports whether JobLogs returns a running job's output so far.
+	LiveLogs() bool
+}
+
+// ParseGitRemote splits a git remote URL (https, ssh, or scp-style) into its
+// host and repository path, without the .git suffix.
+func ParseGitRemote(remote string) (host, path string, err error) {
+	r := strings.TrimSuffix(strings.TrimSpace(remote), ".git")
+	switch {
+	case strings.HasPrefix(r, "https://") || strings.HasPrefix(r, "http://") || strings.HasPrefix(r, "ssh://"):
+		u, err := url.Parse(r)
+		if err != nil {
+			return "", "", fmt.Errorf("unrecognized git remote %q: %w", remote, err)
+		}
+		host, path = u.Hostname(), strings.Trim(u.Path, "/")
+	case strings.Contains(r, "@") && strings.Contains(r, ":"):
+		userHost, p, _ := strings.Cut(r, ":")
+		host, path = userHost[strings.LastIndex(userHost, "@")+1:], strings.Trim(p, "/")
+	}
+	if host == "" || !strings.Contains(path, "/") {
+		return "", "", fmt.Errorf("unrecognized git remote %q", remote)
+	}
+	return host, path, nil
+}
+
+// BackendForHost names the cloud backend serving a git host: github for
+// github.com, gitlab for gitlab.com and hosts named like a GitLab instance,
+// "" when it cannot tell.
+func BackendForHost(host string) string {
+	host = strings.ToLower(host)
+	switch {
+	case host == "github.com":
+		return "github"
+	case host == "gitlab.com" || strings.HasPrefix(host, "gitlab.") || strings.Contains(host, ".gitlab."):
+		return "gitlab"
+	}
+	return ""
+}
+
+type ghBackend struct {
+	client   *GHClient
+	workflow string
+}
+
+// NewGitHubBackend runs construct through workflow_dispatch on workflow, a
+// file under .github/workflows.
+func NewGitHubBackend(client *GHClient, workflow string) CloudBackend {
+	return &ghBackend{client: client, workflow: workflow}
+}
+
+func (b *ghBackend) Name() string   { return "github" }
+func (b *ghBackend) LiveLogs() bool { return false }
+
+func (b *ghBackend) Dispatch(ctx context.Context, ref string, inputs map[string]string) (CloudRun, error) {
+	return CloudRun{}, b.client.Dispatch(ctx, b.workflow, ref, inputs)
+}
+
+func (b *ghBackend) LatestRun(ctx context.Context, since time.Time) (CloudRun, error) {
+	r, err := b.client.LatestDispatchRun(ctx, b.workflow, since)
+	return CloudRun(r), err
+}
+
+func (b *ghBackend) Run(ctx context.Context, runID int64) (CloudRun, error) {
+	r, err := b.client.Run(ctx, runID)
+	return CloudRun(r), err
+}
+
+func (b *ghBackend) Jobs(ctx context.Context, runID int64) ([]CloudJob, error) {
+	jobs, err := b.client.Jobs(ctx, runID)
+	out := make([]CloudJob, len(jobs))
+	for i, j := range jobs {
+		out[i] = CloudJob(j)
+	}
+	return out, err
+}
+
+func (b *ghBackend) JobLogs(ctx context.Context, jobID int64) ([]byte, error) {
+	return b.client.JobLogs(ctx, jobID)
+}
+
+func (b *ghBackend) Cancel(ctx context.Context, runID int64) error {
+	return b.client.Cancel(ctx, runID)
+}
+
+func (b *ghBackend) Artifacts(ctx context.Context, runID int64) ([]CloudArtifact, error) {
+	arts, err := b.client.Artifacts(ctx, runID)
+	out := make([]CloudArtifact, len(arts))
+	for i, a := range arts {
+		out[i] = CloudArtifact(a)
+	}
+	return out, err
+}
+
+func (b *ghBackend) DownloadArtifact(ctx context.Context, artifactID int64, w io.Writer) error {
+	return b.client.DownloadArtifact(ctx, artifactID, w)
+}
+
+// DownloadCloudArtifacts unpacks each of the run's unexpired artifacts into
+// into/<artifact name> and returns those directories.
+func DownloadCloudArtifacts(ctx context.Context, b CloudBackend, runID int64, into string) ([]string, error) {
+	arts, err := b.Artifacts(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	var dirs []string
+	for _, a := range arts {
+		if a.Expired {
+			continue
+		}
+		dir, err := safeExtractPath(into, a.Name)
+		if err != nil || dir == filepath.Clean(into) {
+			return dirs, fmt.Errorf("artifact %q: unusable name", a.Name)
+		}
+		if err := downloadArtifactTo(ctx, b, a, dir); err != nil {
+			return dirs, err
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs, nil
+}
+
+func downloadArtifactTo(ctx context.Context, b CloudBackend, a CloudArtifact, dir string) error {
+	tmp, err := os.CreateTemp("", "construct-artifact-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	err = b.DownloadArtifact(ctx, a.ID, tmp)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("download artifact %q: %w", a.Name, err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := extractZip(tmp.Name(), dir); err != nil {
+		return fmt.Errorf("unpack artifact %q: %w", a.Name, err)
+	}
+	return nil
+}
+
+// GitRemote returns the host and repository path of the origin remote.
+func GitRemote() (host, path string, err error) {
+	out, err := exec.Command("git", "config", "--get", "remote.origin.url").Output()
+	if err != nil {
+		return "", "", fmt.Errorf("could not determine the git remote (git config --get remote.origin.url): %w", err)
+	}
+	return ParseGitRemote(string(out))
+}
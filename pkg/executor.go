@@ -24,6 +24,7 @@ type Executor struct {
 	concurrent      bool
 	keepGoing       bool
 	noCache         bool
+	noDeps          bool // run targets without their prerequisites
 	quiet           bool
 	explain         bool
 	debug           bool
@@ -31,6 +32,9 @@ type Executor struct {
 	runCtx          context.Context
 	cloudDefs       map[string]Command
 	cloudLoaded     bool
+	trustedKeys     *TrustedKeys
+	trustedKeysErr  error // a malformed trusted-keys file; no cloud definition runs
+	cloudWarned     map[string]bool
 	shellName       string
 	shellArgs       []string
 	env             []string
@@ -75,6 +79,12 @@ type OutputCollector interface {
 	OutputWriter(name string) io.Writer
 }
 
+// ProcessObserver is told the pid of each streamed shell process a command
+// starts (construct dev reports it in `dev status`).
+type ProcessObserver interface {
+	ProcessStarted(name string, pid int)
+}
+
 type FlameRow struct {
 	Label  string
 	Start  time.Time
@@ -90,6 +100,8 @@ type RunRecord struct {
 	End        time.Time `json:"end"`
 	Error      string    `json:"error,omitempty"`
 	Log        string    `json:"log,omitempty"` // bounded capture of the command's streamed output
+	Profile    string    `json:"profile,omitempty"`
+	Source     string    `json:"source,omitempty"` // set on records merged from elsewhere, e.g. "cloud run #42"
 }
 
 type commandRun struct {
@@ -140,13 +152,13 @@ func (e *Executor) RunRecords() map[string]RunRecord {
 
 func (e *Executor) debugf(format string, args ...any) {
 	if e.debug {
-		fmt.Printf("[DEBUG] "+format, args...)
+		fmt.Print(MaskSecrets(fmt.Sprintf("[DEBUG] "+format, args...)))
 	}
 }
 
 func (e *Executor) explainf(format string, args ...any) {
 	if e.explain {
-		fmt.Printf(format, args...)
+		fmt.Print(MaskSecrets(fmt.Sprintf(format, args...)))
 	}
 }
 
@@ -158,6 +170,8 @@ func (e *Executor) loadCloudDefs() error {
 		return nil
 	}
 
+	e.trustedKeys, e.trustedKeysErr = LoadTrustedKeys(filepath.Join(e.baseDir, TrustedKeysFile))
+
 	fileBytes, err := os.ReadFile(e.resolveCloudFile())
 	if err != nil {
 		e.cloudDefs = make(map[string]Command)
@@ -183,11 +197,58 @@ func (e *Executor) RegisterArgumentFlags(flagSet *pflag.FlagSet) {
 		cmd.argKey = cmd.Name
 		for _, arg := range cmd.Arguments {
 			flagName := fmt.Sprintf("%s:%s", cmd.Name, arg.Name)
+			if arg.IsVariadic {
+				flagSet.StringArray(flagName, nil, fmt.Sprintf("Argument %s... for command %s (repeatable)", arg.Name, cmd.Name))
+				continue
+			}
 			flagSet.String(flagName, arg.Default, fmt.Sprintf("Argument %s for command %s", arg.Name, cmd.Name))
 		}
 	}
 }
 
+// BindPassthrough appends the words after `--` to the variadic argument of
+// the last requested target (or the default command when none is named).
+// Call it after RegisterArgumentFlags and the flag parse.
+func (e *Executor) BindPassthrough(targets []string, words []string) error {
+	if len(words) == 0 {
+		return nil
+	}
+	var cmd *Command
+	for i := len(targets) - 1; i >= 0 && cmd == nil; i-- {
+		if targets[i] == "" || targets[i][0] == '-' {
+			continue
+		}
+		c, err := e.StructuredParse.Instance(targets[i])
+		if err != nil {
+			return err
+		}
+		cmd = c
+	}
+	if cmd == nil {
+		def, err := e.StructuredParse.GetDefaultCommand()
+		if err != nil {
+			return fmt.Errorf("arguments after -- need a target with a variadic argument (name...)")
+		}
+		cmd = def
+	}
+	var variadic *Argument
+	for _, arg := range cmd.Arguments {
+		if arg.IsVariadic {
+			variadic = arg
+		}
+	}
+	if variadic == nil {
+		return fmt.Errorf("command '%s' has no variadic argument (name...) to take the words after --", cmd.Name)
+	}
+	fs := e.argFlags()
+	for _, w := range words {
+		if err := fs.Set(cmd.flagScope()+":"+variadic.Name, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (e *Executor) SetDebug(debug bool) {
 	e.debug = debug
 }
@@ -227,16 +288,16 @@ func (e *Executor) SetStderrSink(w io.Writer) {
 
 func (e *Executor) outSink() io.Writer {
 	if e.stdoutSink != nil {
-		return e.stdoutSink
+		return maskedWriter{e.stdoutSink}
 	}
-	return os.Stdout
+	return maskedWriter{os.Stdout}
 }
 
 func (e *Executor) errSink() io.Writer {
 	if e.stderrSink != nil {
-		return e.stderrSink
+		return maskedWriter{e.stderrSink}
 	}
-	return os.Stderr
+	return maskedWriter{os.Stderr}
 }
 
 func (e *Executor) errSinkFor(ctx *execContext) io.Writer {
@@ -275,6 +336,13 @@ func (e *Executor) SetNoCache(v bool) {
 	e.noCache = v
 }
 
+// SetNoDeps runs the requested targets without their prerequisites, which
+// are taken to have run already (a CI job whose needs restored their
+// outputs). A matrix target still runs its cells.
+func (e *Executor) SetNoDeps(v bool) {
+	e.noDeps = v
+}
+
 func (e *Executor) SetKeepGoing(v bool) {
 	e.keepGoing = v
 }
@@ -291,6 +359,11 @@ func (e *Executor) SetRunContext(ctx context.Context) {
 	e.runCtx = ctx
 }
 
+// Setenv sets an environment variable for every command e runs.
+func (e *Executor) Setenv(key, value string) {
+	e.env = setEnvVar(e.env, key, value)
+}
+
 func (e *Executor) SetYes(v bool) {
 	e.yes = v
 }
@@ -331,6 +404,19 @@ func (e *Executor) EvaluateCommand(command *Command) error {
 }
 
 func (e *Executor) RunServiceBody(command *Command) error {
+	if err := e.StructuredParse.checkSecretsUnlocked(); err != nil {
+		return err
+	}
+	body, err := e.bodyFor(command)
+	if err != nil {
+		return err
+	}
+	return e.runServiceStmts(command, body, nil)
+}
+
+// runServiceStmts runs body with command's env and container; runCtx
+// overrides the executor's run context when set.
+func (e *Executor) runServiceStmts(command *Command, body []BodyStatement, runCtx context.Context) error {
 	resolveValue := func(s, scope string) string {
 		s = resolveVarRefs(s, func(name string) (string, bool) {
 			return e.StructuredParse.LookupVariable(name, scope)
@@ -342,6 +428,10 @@ func (e *Executor) RunServiceBody(command *Command) error {
 		srcFile:     command.SourceFile,
 		forcePrefix: true,
 		container:   e.resolveContainer(resolveValue(command.Container, command.Name)),
+		runCtx:      runCtx,
+	}
+	if command.IsService {
+		ctx.stop = StopSpecFor(command)
 	}
 	var ctxCancel context.CancelFunc
 	if command.Timeout != "" {
@@ -364,7 +454,7 @@ func (e *Executor) RunServiceBody(command *Command) error {
 			defer os.Remove(ctx.envFile)
 		}
 	}
-	return e.execBody(ctx, e.bodyFor(command))
+	return e.execBody(ctx, body)
 }
 
 func (e *Executor) evaluate(command *Command, prereqDir string, isPrereq bool) error {
@@ -398,6 +488,23 @@ func (e *Executor) executeCommand(command *Command, prereqDir string, isPrereq b
 		command.PrereqOutput = []string{}
 	}
 	e.mu.Unlock()
+	if command.BaseName != "" {
+		e.bindInstanceArgs(command)
+	}
+	// A matrix group only schedules its cells; they own the body and caching,
+	// so cells run as targets rather than prerequisites.
+	group := command.Matrix != nil && command.BaseName == ""
+
+	if command.Guard != "" && !group && !e.conditionHolds(command.Guard, command.Name) {
+		e.explainf("(%s skipped: if %s is false)\n", command.Name, command.Guard)
+		if !e.explain && !e.silentStatus && !isPrereq {
+			fmt.Printf("(%s skipped)\n", command.Name)
+		}
+		rec := RunRecord{Status: "skipped", DurationMs: time.Since(start).Milliseconds(), End: time.Now()}
+		e.recordRun(command.Name, rec)
+		e.notifyFinish(command.Name, rec)
+		return nil
+	}
 
 	resolveValue := func(s, scope string) string {
 		s = resolveVarRefs(s, func(name string) (string, bool) {
@@ -407,11 +514,11 @@ func (e *Executor) executeCommand(command *Command, prereqDir string, isPrereq b
 	}
 
 	var depFiles []string
-	if len(command.FileDeps) > 0 && !isPrereq {
+	if len(command.FileDeps) > 0 && !isPrereq && !group {
 		depFiles = expandFileDeps(command.FileDeps, e.workDirFor(command, resolveValue, workDir))
 	}
 
-	if len(command.FileDeps) > 0 && !isPrereq && len(command.Produces) == 0 && !e.noCache {
+	if len(command.FileDeps) > 0 && !isPrereq && !group && len(command.Produces) == 0 && !e.noCache {
 		if skip, reason := e.shouldSkip(command, depFiles); skip {
 			e.explainf("(%s cached: %s)\n", command.Name, reason)
 			if !e.explain && !e.silentStatus {
@@ -425,7 +532,7 @@ func (e *Executor) executeCommand(command *Command, prereqDir string, isPrereq b
 			fmt.Printf("(%s running: %s)\n", command.Name, reason)
 		}
 	}
-	if len(command.Produces) > 0 && !isPrereq && !e.noCache {
+	if len(command.Produces) > 0 && !isPrereq && !group && !e.noCache {
 		if skip, reason := e.shouldSkipProduced(command, resolveValue, workDir, depFiles); skip {
 			e.explainf("(%s up to date: %s)\n", command.Name, reason)
 			if !e.explain && !e.silentStatus {
@@ -477,18 +584,27 @@ func (e *Executor) executeCommand(command *Command, prereqDir string, isPrereq b
 		}
 	}
 
-	var prereqCmds []*Command
+	var prereqCmds, skippedCmds []*Command
 	var prereqDirs []string
 	for _, prereqName := range command.Prereqs {
 		prereqName = strings.TrimSpace(prereqName)
 		if prereqName == "" {
 			continue
 		}
+		if cond := command.PrereqConds[prereqName]; cond != "" && !e.conditionHolds(cond, command.Name) {
+			e.explainf("(%s: prerequisite %s pruned: if %s is false)\n", command.Name, prereqName, cond)
+			continue
+		}
 
 		preCmd, err := e.StructuredParse.GetCommand(prereqName)
 		if err != nil {
 			return err
 		}
+		if e.noDeps && !isPrereq && !group {
+			e.explainf("(%s: prerequisite %s skipped: --no-deps)\n", command.Name, prereqName)
+			skippedCmds = append(skippedCmds, preCmd)
+			continue
+		}
 
 		prereqDirs = append(prereqDirs, command.PrereqDirs[prereqName])
 		prereqCmds = append(prereqCmds, preCmd)
@@ -506,7 +622,7 @@ func (e *Executor) executeCommand(command *Command, prereqDir string, isPrereq b
 			wg.Add(1)
 			go func(i int, pc *Command, dir string) {
 				defer wg.Done()
-				errs[i] = e.evaluate(pc, dir, true)
+				errs[i] = e.evaluate(pc, dir, !group)
 				results[i] = pc
 			}(i, preCmd, prereqDirs[i])
 		}
@@ -519,15 +635,25 @@ func (e *Executor) executeCommand(command *Command, prereqDir string, isPrereq b
 		command.PrereqCmds = append(command.PrereqCmds, results...)
 	} else {
 		for i, preCmd := range prereqCmds {
-			if err := e.evaluate(preCmd, prereqDirs[i], true); err != nil {
+			if err := e.evaluate(preCmd, prereqDirs[i], !group); err != nil {
 				return err
 			}
 			command.PrereqCmds = append(command.PrereqCmds, preCmd)
 		}
 	}
 
+	if len(skippedCmds) > 0 {
+		e.restoreNamedOutputs(skippedCmds)
+		command.PrereqCmds = append(command.PrereqCmds, skippedCmds...)
+	}
 	e.seedPrereqOutputs(command)
-	body := e.bodyFor(command)
+	body, err := e.bodyFor(command)
+	if err != nil {
+		return err
+	}
+	if group {
+		body = nil
+	}
 
 	e.notifyStart(command.Name)
 
@@ -553,7 +679,7 @@ func (e *Executor) executeCommand(command *Command, prereqDir string, isPrereq b
 		return bodyErr
 	}
 
-	if len(command.Produces) > 0 && !isPrereq {
+	if len(command.Produces) > 0 && !isPrereq && !group {
 		produced := expandFileDeps(command.Produces, e.workDirFor(command, resolveValue, workDir))
 		for _, artifact := range produced {
 			if _, err := os.Stat(artifact); err != nil {
@@ -564,7 +690,7 @@ func (e *Executor) executeCommand(command *Command, prereqDir string, isPrereq b
 		e.invalidateHashes(produced)
 	}
 
-	if len(command.FileDeps) > 0 && !isPrereq && len(command.Produces) == 0 && !e.noCache {
+	if len(command.FileDeps) > 0 && !isPrereq && !group && len(command.Produces) == 0 && !e.noCache {
 		e.updateCache(command, resolveValue, workDir)
 	}
 
@@ -572,12 +698,47 @@ func (e *Executor) executeCommand(command *Command, prereqDir string, isPrereq b
 		fmt.Printf("(%s completed in %s)\n", command.Name, time.Since(start).Round(time.Millisecond))
 	}
 
+	e.saveNamedOutputs(command)
 	rec := RunRecord{Status: "ok", DurationMs: time.Since(start).Milliseconds(), End: time.Now()}
 	e.recordRun(command.Name, rec)
 	e.notifyFinish(command.Name, rec)
 	return nil
 }
 
+// conditionHolds evaluates a header condition (a command guard or a
+// conditional prerequisite) with scope's variables, state, and the environment.
+func (e *Executor) conditionHolds(cond, scope string) bool {
+	resolved := resolveVarRefs(cond, func(name string) (string, bool) {
+		v, ok := LookupVariableIndexed(e.StructuredParse, name, scope)
+		if !ok {
+			return "", false
+		}
+		return v.String(), true
+	})
+	resolved = ResolveEnvRefs(resolveStateRefsWith(resolved, e.stateLookup))
+	e.debugf("Evaluating header condition: %s\n", resolved)
+	return evaluateConditionWithBase(resolved, e.baseDir)
+}
+
+// bindInstanceArgs gives a parameterized instance or matrix cell its base
+// command's scope plus its bound values as &name variables, the way invoke
+// passes args.
+func (e *Executor) bindInstanceArgs(command *Command) {
+	for _, v := range e.StructuredParse.SnapshotScope(command.BaseName) {
+		if v.IsList {
+			e.StructuredParse.SetVariableList(v.Name, command.Name, v.List)
+		} else {
+			e.StructuredParse.SetVariable(v.Name, command.Name, v.Value)
+		}
+	}
+	for name, val := range command.BoundArgs {
+		if slices.ContainsFunc(command.Arguments, func(a *Argument) bool { return a.Name == name && a.IsVariadic }) {
+			continue // resolved as words by variadicWords
+		}
+		e.StructuredParse.SetVariable(name, command.Name, val)
+	}
+}
+
 func ghErrorAnnotation(err error) {
 	file, line := "", 0
 	switch e := err.(type) {
@@ -598,6 +759,9 @@ func ghErrorAnnotation(err error) {
 }
 
 func (e *Executor) Execute(commands []string) error {
+	if err := e.StructuredParse.checkSecretsUnlocked(); err != nil {
+		return err
+	}
 	e.runs = make(map[string]*commandRun)
 	e.loadState()
 
@@ -619,7 +783,7 @@ func (e *Executor) Execute(commands []string) error {
 		if cmdName == "" || cmdName[0] == '-' {
 			continue
 		}
-		targets = append(targets, cmdName)
+		targets = append(targets, e.StructuredParse.MatrixTargets(cmdName)...)
 	}
 
 	if len(targets) == 0 {
@@ -636,6 +800,9 @@ func (e *Executor) Execute(commands []string) error {
 		}
 		neededScopes[name] = true
 		if cmd, err := e.StructuredParse.GetCommand(name); err == nil {
+			if cmd.BaseName != "" {
+				neededScopes[cmd.BaseName] = true
+			}
 			for _, prereq := range cmd.Prereqs {
 				addPrereqs(strings.TrimSpace(prereq))
 			}
@@ -729,13 +896,17 @@ func (e *Executor) execConcurrent(targets []string) error {
 }
 
 func (e *Executor) processCommand(name string) error {
-	command, err := e.StructuredParse.GetCommand(name)
+	command, err := e.StructuredParse.Instance(name)
 	if err != nil {
-		if def, cerr := e.getCloudDefinition(name); cerr == nil {
+		def, cerr := e.getCloudDefinition(name)
+		if cerr == nil {
 			e.debugf("Running cloud command %s (no local definition)\n", name)
 			command = &Command{Name: def.Name, Body: def.Body, Arguments: def.Arguments}
 			return e.EvaluateCommand(command)
 		}
+		if isCloudVerifyError(cerr) {
+			return cerr
+		}
 		return err
 	}
 	return e.EvaluateCommand(command)
@@ -746,9 +917,27 @@ func (e *Executor) getCloudDefinition(name string) (*Command, error) {
 		return nil, err
 	}
 
-	if c, ok := e.cloudDefs[name]; ok {
-		return &c, nil
+	c, ok := e.cloudDefs[name]
+	if !ok {
+		return nil, fmt.Errorf("%s command not found in cloud", name)
 	}
-
-	return nil, fmt.Errorf("%s command not found in cloud", name)
+	if e.trustedKeysErr != nil {
+		return nil, &CloudVerifyError{Name: name, Status: "invalid", Reason: fmt.Sprintf("cannot be verified: %v", e.trustedKeysErr)}
+	}
+	if err := VerifyCloudDef(name, c, e.trustedKeys); err != nil {
+		if e.trustedKeys.Policy != CloudPolicyWarn {
+			return nil, err
+		}
+		e.mu.Lock()
+		if e.cloudWarned == nil {
+			e.cloudWarned = make(map[string]bool)
+		}
+		warned := e.cloudWarned[name]
+		e.cloudWarned[name] = true
+		e.mu.Unlock()
+		if !warned {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		}
+	}
+	return &c, nil
 }
@@ -932,6 +932,70 @@ func TestEvaluateCommandUnsetArgEmpty(t *testing.T) {
 	}
 }
 
+func TestVariadicArgumentPassthrough(t *testing.T) {
+	data := &ParsedData{
+		Commands: []*Command{
+			{
+				Name:      "test",
+				Arguments: []*Argument{{Name: "pkgs", IsOptional: true, IsVariadic: true, Default: "./..."}},
+				Body:      []BodyStatement{{Type: "shell", Shell: "printf '[%s]' &pkgs", OutputName: "out"}},
+			},
+			{Name: "use", Prereqs: []string{"test"}, Body: shellBody("$ true")},
+		},
+	}
+	data.buildIndexMaps()
+
+	flagSet := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	executor := NewExecutor(data, false, false)
+	executor.RegisterArgumentFlags(flagSet)
+	if err := flagSet.Parse(nil); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if err := executor.BindPassthrough([]string{"test"}, []string{"-run", "TestFoo|Bar", "it's"}); err != nil {
+		t.Fatalf("BindPassthrough: %v", err)
+	}
+	if err := executor.Execute([]string{"use"}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	test, _ := data.GetCommand("test")
+	want := "[-run][TestFoo|Bar][it's]"
+	if len(test.PrereqOutput) != 1 || test.PrereqOutput[0] != want {
+		t.Errorf("prereq output = %#v, want [%q]", test.PrereqOutput, want)
+	}
+
+	if err := executor.BindPassthrough([]string{"use"}, []string{"x"}); err == nil {
+		t.Error("expected an error binding -- words to a command without a variadic argument")
+	}
+}
+
+func TestVariadicArgumentDefault(t *testing.T) {
+	data := &ParsedData{
+		Commands: []*Command{
+			{Name: "test", Arguments: []*Argument{{Name: "pkgs", IsOptional: true, IsVariadic: true, Default: "./a ./b"}}},
+		},
+	}
+	data.buildIndexMaps()
+	flagSet := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	executor := NewExecutor(data, false, false)
+	executor.RegisterArgumentFlags(flagSet)
+	cmd := data.Commands[0]
+	ctx := &execContext{target: cmd, env: &[]string{}}
+	if got := executor.resolveShellLine(ctx, "go test &pkgs"); got != "go test ./a ./b" {
+		t.Errorf("default words = %q", got)
+	}
+	before := executor.cacheKey(cmd)
+	if err := flagSet.Parse([]string{"--test:pkgs=./c", "--test:pkgs=./d"}); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got := executor.resolveShellLine(ctx, "go test &pkgs"); got != "go test ./c ./d" {
+		t.Errorf("flag words = %q", got)
+	}
+	if executor.cacheKey(cmd) == before {
+		t.Error("cache key should change with the variadic words")
+	}
+}
+
 func TestBodyFor(t *testing.T) {
 	dir := t.TempDir()
 	cloudFile := filepath.Join(dir, "cloud.json")
@@ -956,7 +1020,7 @@ func TestBodyFor(t *testing.T) {
 	t.Run("non-cloud command unchanged", func(t *testing.T) {
 		cmd := &Command{Name: "local", Body: shellBody("echo local")}
 		before := append([]BodyStatement(nil), cmd.Body...)
-		body := executor.bodyFor(cmd)
+		body, _ := executor.bodyFor(cmd)
 		if len(body) != len(before) {
 			t.Errorf("body changed: %#v vs %#v", body, before)
 		}
@@ -967,7 +1031,10 @@ func TestBodyFor(t *testing.T) {
 
 	t.Run("cloud body combined without mutating cmd", func(t *testing.T) {
 		cmd := &Command{Name: "fetch", CloudAccessible: true, Body: shellBody("echo local")}
-		body := executor.bodyFor(cmd)
+		body, err := executor.bodyFor(cmd)
+		if err != nil {
+			t.Fatal(err)
+		}
 		if len(cmd.Body) != 1 {
 			t.Errorf("cmd.Body mutated: %d stmts, want 1", len(cmd.Body))
 		}
@@ -978,7 +1045,7 @@ func TestBodyFor(t *testing.T) {
 
 	t.Run("cloud command with no definition unchanged", func(t *testing.T) {
 		cmd := &Command{Name: "missing", CloudAccessible: true, Body: shellBody("echo local")}
-		if body := executor.bodyFor(cmd); len(body) != 1 {
+		if body, _ := executor.bodyFor(cmd); len(body) != 1 {
 			t.Errorf("body = %d stmts, want 1", len(body))
 		}
 	})
@@ -1362,6 +1429,29 @@ func TestContainerArgs(t *testing.T) {
 	if !strings.HasPrefix(display, "docker run alpine:latest") {
 		t.Errorf("display = %q", display)
 	}
+	if strings.Contains(joined, " -p ") {
+		t.Errorf("a non-service published a port: %s", joined)
+	}
+
+	// Only `port HOST:CONTAINER` publishes; a plain port is just a readiness check.
+	for _, tc := range []struct {
+		svc  *Command
+		want string
+	}{
+		{&Command{Name: "db", IsService: true, Port: "5432"}, ""},
+		{&Command{Name: "api", IsService: true, Port: "8080", Publish: "8080:80"}, "-p 8080:80"},
+	} {
+		sCtx := &execContext{target: tc.svc, env: &env, container: "docker alpine:latest"}
+		argv, _, cleanup, err := e.shellArgsFor(sCtx, "serve")
+		if err != nil {
+			t.Fatal(err)
+		}
+		cleanup()
+		joined := strings.Join(argv, " ")
+		if got := strings.Contains(joined, " -p "); got != (tc.want != "") || tc.want != "" && !strings.Contains(joined, tc.want) {
+			t.Errorf("%s argv = %s, want publish %q", tc.svc.Name, joined, tc.want)
+		}
+	}
 }
 
 func captureStderr(t *testing.T, fn func()) string {
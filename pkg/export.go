@@ -0,0 +1,1101 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExportShell renders targets (the default command when none are given) as
+// a standalone POSIX shell script: the prerequisite closure runs in
+// execution order with each body inlined in a subshell. Variables and
+// arguments resolve now; environment refs, loop variables, lazy variables,
+// and prerequisite outputs become shell expansions. Locks, `parallel for`,
+// and cache skips become comments; anything else without a shell equivalent
+// (state, containers, services, &last) is an error.
+func (e *Executor) ExportShell(targets []string) (string, error) {
+	data := e.StructuredParse
+	if err := data.checkSecretsUnlocked(); err != nil {
+		return "", err
+	}
+	var names []string
+	for _, t := range targets {
+		names = append(names, data.MatrixTargets(t)...)
+	}
+	if len(names) == 0 {
+		def, err := data.GetDefaultCommand()
+		if err != nil || def == nil {
+			return "", errors.New("no commands requested and no default ('_') command defined")
+		}
+		names = []string{def.Name}
+	}
+
+	x := &shellExport{e: e, runtime: map[string]string{}, emitted: map[string]int{}, invoking: map[string]bool{}, used: map[string]bool{}}
+	var roots []*Command
+	for _, name := range names {
+		cmd, err := data.Instance(name)
+		if err != nil {
+			return "", err
+		}
+		roots = append(roots, cmd)
+	}
+	x.lazyVars(roots)
+	for _, cmd := range roots {
+		x.command(cmd, "", false)
+	}
+	if len(x.problems) > 0 {
+		return "", fmt.Errorf("cannot export to sh:\n  %s", strings.Join(x.problems, "\n  "))
+	}
+
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	fmt.Fprintf(&b, "# Generated by `construct export sh` for %s. Run it from the Constfile's directory.\n", strings.Join(names, ", "))
+	b.WriteString("set -e\n")
+	// Secrets never appear in the script: it reads them from the
+	// environment and stops before running anything when one is unset.
+	for _, env := range x.secrets {
+		fmt.Fprintf(&b, ": \"${%s:?}\"\n", env)
+	}
+	if x.used["root"] {
+		b.WriteString("__root=$(pwd)\n")
+	}
+	if x.used["out"] {
+		b.WriteString("__out=$(mktemp -d)\n")
+		b.WriteString("trap 'rm -rf \"$__out\"' EXIT\n")
+	}
+	for _, h := range shellHelpers {
+		if x.used[h.name] {
+			b.WriteString("\n")
+			b.WriteString(h.body)
+		}
+	}
+	b.WriteString("\n")
+	b.WriteString(x.lazy.String())
+	b.WriteString(x.out.String())
+	return b.String(), nil
+}
+
+// shellHelpers are emitted once each, only when a statement needs them.
+var shellHelpers = []struct{ name, body string }{
+	{"os", `__construct_os() {
+	case "$(uname -s)" in
+	Linux) echo linux ;;
+	Darwin) echo darwin ;;
+	FreeBSD) echo freebsd ;;
+	MINGW* | MSYS* | CYGWIN*) echo windows ;;
+	*) uname -s | tr '[:upper:]' '[:lower:]' ;;
+	esac
+}
+`},
+	{"arch", `__construct_arch() {
+	case "$(uname -m)" in
+	x86_64 | amd64) echo amd64 ;;
+	aarch64 | arm64) echo arm64 ;;
+	i386 | i686) echo 386 ;;
+	arm*) echo arm ;;
+	*) uname -m ;;
+	esac
+}
+`},
+	// Like construct's own comparison: numeric when both sides are
+	// integers, byte-wise string order otherwise.
+	{"cmp", `__construct_cmp() {
+	for __construct_v in "$1" "$3"; do
+		case "$__construct_v" in
+		'' | - | *[!0-9-]* | ?*-*) LC_ALL=C expr "x$1" "$2" "x$3" >/dev/null; return ;;
+		esac
+	done
+	case "$2" in
+	'>') [ "$1" -gt "$3" ] ;;
+	'<') [ "$1" -lt "$3" ] ;;
+	'>=') [ "$1" -ge "$3" ] ;;
+	'<=') [ "$1" -le "$3" ] ;;
+	esac
+}
+`},
+	{"download", `__construct_download() {
+	mkdir -p "$(dirname "$2")" || return
+	if command -v curl >/dev/null 2>&1; then
+		curl -fsSL -o "$2" "$1"
+	else
+		wget -q -O "$2" "$1"
+	fi
+}
+`},
+	{"extract", `__construct_extract() {
+	mkdir -p "$2" || return
+	case "$1" in
+	*.zip) unzip -oq "$1" -d "$2" ;;
+	*.tar.gz | *.tgz) gzip -dc "$1" | tar -xf - -C "$2" ;;
+	*.tar.bz2) bzip2 -dc "$1" | tar -xf - -C "$2" ;;
+	*.tar) tar -xf "$1" -C "$2" ;;
+	*) echo "extract: unsupported archive $1" >&2; return 1 ;;
+	esac
+}
+`},
+}
+
+const (
+	exportedUnder = iota + 1 // emitted inside a runtime condition; may not have run
+	exportedDone
+)
+
+type shellExport struct {
+	e        *Executor
+	out      strings.Builder
+	lazy     strings.Builder
+	depth    int
+	runtime  map[string]string // "scope.name" -> the shell expansion holding it
+	emitted  map[string]int    // command -> exportedUnder or exportedDone
+	invoking map[string]bool
+	used     map[string]bool // helpers and preamble variables the script needs
+	cond     int             // runtime conditions around the current output
+	secrets  []string        // environment variables standing in for secrets
+	problems []string
+}
+
+type exportCtx struct {
+	target   *Command
+	isPrereq bool
+	dir      string // the shell expression cd'd to, "" for the Constfile directory
+	indexed  bool   // dependents read this prerequisite's outputs as &name.N
+	onFails  []string
+}
+
+func (x *shellExport) line(format string, args ...any) {
+	x.out.WriteString(strings.Repeat("\t", x.depth))
+	fmt.Fprintf(&x.out, format, args...)
+	x.out.WriteString("\n")
+}
+
+func (x *shellExport) problem(ctx *exportCtx, line int, format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if line > 0 {
+		msg = fmt.Sprintf("%s (line %d): %s", ctx.target.Name, line, msg)
+	} else {
+		msg = ctx.target.Name + ": " + msg
+	}
+	if !slices.Contains(x.problems, msg) {
+		x.problems = append(x.problems, msg)
+	}
+}
+
+// lazyVars assigns the lazy variables the targets can reach at the top of
+// the script, as construct evaluates them before running anything.
+func (x *shellExport) lazyVars(roots []*Command) {
+	data := x.e.StructuredParse
+	needed := map[string]bool{"global": true}
+	var walk func(name string)
+	walk = func(name string) {
+		if needed[name] {
+			return
+		}
+		needed[name] = true
+		if cmd, err := data.GetCommand(name); err == nil {
+			if cmd.BaseName != "" {
+				needed[cmd.BaseName] = true
+			}
+			for _, p := range cmd.Prereqs {
+				walk(strings.TrimSpace(p))
+			}
+		}
+	}
+	for _, cmd := range roots {
+		walk(cmd.Name)
+	}
+	for _, cmd := range data.Commands {
+		if cmd.LazyEval == nil || !needed[cmd.LazyEval.Scope] {
+			continue
+		}
+		lazy := *cmd
+		if scopeCmd, err := data.GetCommand(cmd.LazyEval.Scope); err == nil {
+			lazy.Arguments = append(slices.Clone(cmd.Arguments), scopeCmd.Arguments...)
+		}
+		ctx := &exportCtx{target: &lazy}
+		name := cmd.LazyEval.VarName
+		if cmd.LazyEval.Scope != "global" {
+			name = cmd.LazyEval.Scope + "_" + name
+		}
+		sv := shellIdent(name)
+		for _, stmt := range cmd.Body {
+			if script := x.shellLine(ctx, stmt.Shell, stmt.SourceLine); script != "" {
+				fmt.Fprintf(&x.lazy, "%s=$(%s)\n", sv, script)
+			}
+		}
+		x.runtime[cmd.LazyEval.Scope+"."+cmd.LazyEval.VarName] = "${" + sv + "}"
+	}
+	if x.lazy.Len() > 0 {
+		x.lazy.WriteString("\n")
+	}
+}
+
+// command emits cmd after its prerequisites, once. A command first reached
+// under a runtime condition is re-emitted behind a ran-marker where it is
+// needed unconditionally.
+func (x *shellExport) command(cmd *Command, prereqDir string, isPrereq bool) {
+	state := x.emitted[cmd.Name]
+	if state == exportedDone {
+		return
+	}
+	marker := "__ran_" + shellIdent(cmd.Name)
+	if state == exportedUnder {
+		x.line(`if [ -z "${%s:-}" ]; then`, marker)
+		x.depth++
+		defer func() {
+			x.depth--
+			x.line("fi")
+		}()
+	}
+	if x.cond > 0 {
+		x.emitted[cmd.Name] = exportedUnder
+	} else {
+		x.emitted[cmd.Name] = exportedDone
+	}
+
+	ctx := &exportCtx{target: cmd, isPrereq: isPrereq}
+	if cmd.BaseName != "" {
+		x.e.bindInstanceArgs(cmd)
+	}
+	if cmd.IsService {
+		x.problem(ctx, cmd.SourceLine, "services run under construct dev and are not exported")
+		return
+	}
+	if cmd.Container != "" {
+		x.problem(ctx, cmd.SourceLine, "container commands are not exported")
+		return
+	}
+
+	group := cmd.Matrix != nil && cmd.BaseName == ""
+	if cmd.Guard != "" && !group {
+		c := x.condition(ctx, x.resolveValue(ctx, cmd.Guard))
+		switch {
+		case c.known && !c.value:
+			x.line("# %s skipped: if %s is false", cmd.Name, cmd.Guard)
+			return
+		case !c.known:
+			x.line("if %s; then", c.sh)
+			x.depth++
+			x.cond++
+			defer func() {
+				x.cond--
+				x.depth--
+				x.line("fi")
+			}()
+		}
+	}
+
+	for _, name := range cmd.Prereqs {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		pre, err := x.e.StructuredParse.GetCommand(name)
+		if err != nil {
+			x.problem(ctx, cmd.SourceLine, "%v", err)
+			continue
+		}
+		cond := cmd.PrereqConds[name]
+		if cond == "" {
+			x.command(pre, cmd.PrereqDirs[name], !group)
+			continue
+		}
+		c := x.condition(ctx, x.resolveValue(ctx, cond))
+		if c.known {
+			if c.value {
+				x.command(pre, cmd.PrereqDirs[name], !group)
+			}
+			continue
+		}
+		x.line("if %s; then", c.sh)
+		x.depth++
+		x.cond++
+		x.command(pre, cmd.PrereqDirs[name], !group)
+		x.cond--
+		x.depth--
+		x.line("fi")
+	}
+	if group || len(cmd.Body) == 0 {
+		return
+	}
+
+	x.line("# %s", cmd.Name)
+	if len(cmd.FileDeps) > 0 || len(cmd.Produces) > 0 {
+		x.line("# construct skips %s while its files are up to date; this script always runs it", cmd.Name)
+	}
+	if cmd.Timeout != "" {
+		x.line("# timeout<%s> is not enforced", cmd.Timeout)
+	}
+	if cmd.CloudAccessible {
+		x.line("# cloud additions to %s are not exported", cmd.Name)
+	}
+	x.line("(")
+	x.depth++
+	workDir := cmd.WorkDir
+	if prereqDir != "" {
+		workDir = prereqDir
+	}
+	if workDir != "" {
+		ctx.dir = x.dirExpr(x.resolveValue(ctx, workDir))
+		x.line("cd %s", ctx.dir)
+	}
+	if isPrereq && x.e.StructuredParse.OutputsIndexReferenced(cmd.Name) {
+		ctx.indexed = true
+		x.line("__n=0")
+	}
+	start := x.out.Len()
+	x.body(ctx, cmd.Body)
+	if x.out.Len() == start {
+		x.line(":")
+	}
+	x.depth--
+	x.line(")")
+	if state == exportedUnder || x.cond > 0 {
+		x.line("%s=1", marker)
+	}
+	x.line("")
+}
+
+func (x *shellExport) body(ctx *exportCtx, body []BodyStatement) {
+	scope := ctx.target.Name
+	for _, stmt := range body {
+		switch stmt.Type {
+		case StmtEnv:
+			for _, pair := range stmt.Env {
+				key, value, _ := strings.Cut(pair, "=")
+				key = strings.TrimSpace(key)
+				if shellIdent(key) != key {
+					x.problem(ctx, stmt.SourceLine, "env %s is not a shell variable name", key)
+					continue
+				}
+				v := x.resolveValue(ctx, value)
+				x.line("export %s=%s", key, shellDQ(v))
+				if isStatic(v) {
+					x.e.StructuredParse.SetVariable(key, scope, v)
+					delete(x.runtime, scope+"."+key)
+				} else {
+					x.runtime[scope+"."+key] = "${" + key + "}"
+				}
+			}
+
+		case StmtIf:
+			c := x.condition(ctx, x.resolveValue(ctx, stmt.Cond))
+			if c.known {
+				if c.value {
+					x.body(ctx, stmt.ThenBody)
+				} else {
+					x.body(ctx, stmt.ElseBody)
+				}
+				continue
+			}
+			x.cond++
+			x.line("if %s; then", c.sh)
+			x.nested(ctx, stmt.ThenBody)
+			if len(stmt.ElseBody) > 0 {
+				x.line("else")
+				x.nested(ctx, stmt.ElseBody)
+			}
+			x.line("fi")
+			x.cond--
+
+		case StmtSwitch:
+			x.switchStmt(ctx, stmt)
+
+		case StmtInDir:
+			dir := x.dirExpr(x.resolveValue(ctx, stmt.Shell))
+			x.line("mkdir -p %s", dir)
+			x.line("cd %s", dir)
+			sub := *ctx
+			sub.dir = dir
+			x.body(&sub, stmt.ThenBody)
+			back := ctx.dir
+			if back == "" {
+				x.used["root"] = true
+				back = `"$__root"`
+			}
+			x.line("cd %s", back)
+
+		case StmtLock:
+			x.line("# lock %s: not held; nothing else runs alongside this script", stmt.Shell)
+			x.body(ctx, stmt.ThenBody)
+
+		case StmtState:
+			x.problem(ctx, stmt.SourceLine, "state %s is kept by construct and has no shell equivalent", stmt.Shell)
+
+		case StmtConfirm:
+			x.line("printf '%%s [y/N]: ' %s", shellDQ(stmt.Message))
+			x.line("read -r __answer || true")
+			x.line(`case "$__answer" in`)
+			x.line("[yY] | [yY][eE][sS]) ;;")
+			x.line("*) echo %s >&2; exit 1 ;;", shellDQ("aborted by user: "+stmt.Message))
+			x.line("esac")
+
+		case StmtPrompt:
+			x.line("printf '%%s [press Enter to continue]: ' %s", shellDQ(stmt.Message))
+			x.line("read -r __answer || true")
+
+		case StmtInput:
+			sv := shellIdent(stmt.Shell)
+			if stmt.Message != "" {
+				x.line("printf '%%s ' %s", shellDQ(stmt.Message))
+			}
+			x.line("read -r %s || true", sv)
+			x.runtime[scope+"."+stmt.Shell] = "${" + sv + "}"
+
+		case StmtBuiltin:
+			x.builtin(ctx, stmt)
+
+		case StmtInvoke:
+			x.invoke(ctx, stmt)
+
+		case StmtFor:
+			x.forStmt(ctx, stmt)
+
+		case StmtContinue:
+			x.line("continue")
+		case StmtBreak:
+			x.line("break")
+
+		case StmtFail:
+			x.line("echo %s >&2", shellDQ(stmt.Message))
+			x.line("exit 1")
+
+		case StmtRequireEnv:
+			msg := "required environment variable " + stmt.Shell + " is not set"
+			if stmt.Message != "" {
+				msg += ": " + stmt.Message
+			}
+			x.line(`: "${%s?%s}"`, stmt.Shell, escapeShellValue(msg))
+
+		case StmtOnFail:
+			fn := fmt.Sprintf("__onfail_%s_%d", shellIdent(scope), len(ctx.onFails))
+			x.line("%s() {", fn)
+			x.nested(ctx, stmt.OnFailBody)
+			x.line("}")
+			ctx.onFails = append(ctx.onFails, fn)
+			x.line(`trap '__st=$?; if [ "$__st" -ne 0 ]; then %s; fi; exit "$__st"' EXIT`, strings.Join(ctx.onFails, "; "))
+
+		case StmtPort, StmtReady, StmtHealth, StmtStop, StmtOnStop:
+
+		default:
+			x.shellStmt(ctx, stmt)
+		}
+	}
+}
+
+func (x *shellExport) nested(ctx *exportCtx, body []BodyStatement) {
+	x.depth++
+	x.body(ctx, body)
+	x.depth--
+}
+
+// shellStmt emits a $ line. A prerequisite's outputs cross its subshell as
+// files in $__out, which dependents read where they reference them.
+func (x *shellExport) shellStmt(ctx *exportCtx, stmt BodyStatement) {
+	raw := stmt.Shell
+	tolerant := false
+	if body := shellLineBody(raw); strings.HasPrefix(body, "!") {
+		tolerant = true
+		raw = strings.TrimSpace(body[1:])
+	}
+	script := x.shellLine(ctx, raw, stmt.SourceLine)
+	if script == "" {
+		return
+	}
+	if stmt.Timeout != "" {
+		x.line("# timeout<%s> is not enforced", stmt.Timeout)
+	}
+	if ctx.isPrereq && (ctx.indexed || stmt.OutputName != "") {
+		x.used["out"] = true
+		capture := "__o=$(" + script + ")"
+		if tolerant && stmt.Retry == 0 {
+			capture += " || true"
+		}
+		x.retried(stmt, capture, tolerant)
+		file := shellIdent(ctx.target.Name)
+		if ctx.indexed {
+			x.line(`printf '%%s\n' "$__o" >"$__out/%s.$__n"`, file)
+			x.line("__n=$((__n + 1))")
+		}
+		if stmt.OutputName != "" {
+			x.line(`printf '%%s\n' "$__o" >"$__out/%s.%s"`, file, stmt.OutputName)
+		}
+		return
+	}
+	if needsShellIsolation(script) || strings.Contains(script, ";") {
+		script = "( " + script + " )"
+	}
+	if tolerant && stmt.Retry == 0 {
+		script += " || true"
+	}
+	x.retried(stmt, script, tolerant)
+}
+
+// retried emits script, in an until loop when the statement has retry<N>.
+func (x *shellExport) retried(stmt BodyStatement, script string, tolerant bool) {
+	if stmt.Retry == 0 {
+		for _, l := range strings.Split(script, "\n") {
+			x.line("%s", l)
+		}
+		return
+	}
+	giveUp := "exit 1"
+	if tolerant {
+		giveUp = "break"
+	}
+	x.line("__try=0")
+	x.line("until %s; do", script)
+	x.depth++
+	x.line("__try=$((__try + 1))")
+	x.line(`if [ "$__try" -gt %d ]; then %s; fi`, stmt.Retry, giveUp)
+	if d, err := time.ParseDuration(stmt.Modifier); err == nil && d > 0 {
+		x.line("sleep %d", int(math.Ceil(d.Seconds())))
+	}
+	x.depth--
+	x.line("done")
+}
+
+func (x *shellExport) switchStmt(ctx *exportCtx, stmt BodyStatement) {
+	expr := strings.Trim(x.resolveValue(ctx, stmt.SwitchExpr), `"`)
+	values := make([][]string, len(stmt.Cases))
+	static := isStatic(expr)
+	for i, c := range stmt.Cases {
+		for _, v := range c.Values {
+			rv := x.resolveValue(ctx, v)
+			static = static && isStatic(rv)
+			values[i] = append(values[i], rv)
+		}
+	}
+	if static {
+		for i, c := range stmt.Cases {
+			if !c.IsDefault && slices.Contains(values[i], expr) {
+				x.body(ctx, c.Body)
+				return
+			}
+		}
+		for _, c := range stmt.Cases {
+			if c.IsDefault {
+				x.body(ctx, c.Body)
+				return
+			}
+		}
+		if stmt.Modifier == "strict" {
+			x.line("echo %s >&2", shellDQ(fmt.Sprintf("strict switch: no case matched %q", expr)))
+			x.line("exit 1")
+		}
+		return
+	}
+
+	x.cond++
+	x.line("case %s in", shellDQ(expr))
+	hasDefault := false
+	for i, c := range stmt.Cases {
+		if c.IsDefault {
+			hasDefault = true
+			continue
+		}
+		pats := make([]string, len(values[i]))
+		for j, v := range values[i] {
+			pats[j] = shellDQ(v)
+		}
+		x.line("%s)", strings.Join(pats, " | "))
+		x.nested(ctx, c.Body)
+		x.line("\t;;")
+	}
+	for _, c := range stmt.Cases {
+		if c.IsDefault {
+			x.line("*)")
+			x.nested(ctx, c.Body)
+			x.line("\t;;")
+		}
+	}
+	if !hasDefault && stmt.Modifier == "strict" {
+		x.line("*)")
+		x.line("\techo %s >&2", shellDQ("strict switch: no case matched \""+expr+"\""))
+		x.line("\texit 1")
+		x.line("\t;;")
+	}
+	x.line("esac")
+	x.cond--
+}
+
+func (x *shellExport) forStmt(ctx *exportCtx, stmt BodyStatement) {
+	scope := ctx.target.Name
+	items := x.resolveValue(ctx, stmt.LoopItems)
+	for _, n := range wildcardRefNames(items) {
+		x.problem(ctx, stmt.SourceLine, "&%s.* (indexed prerequisite outputs) is not exported; name the outputs", n)
+	}
+	if strings.TrimSpace(items) == "" {
+		return
+	}
+	var words string
+	basenames := false
+	switch {
+	case !isStatic(items):
+		words = strings.ReplaceAll(strings.ReplaceAll(items, ",", " "), "\x00", "")
+	case strings.ContainsAny(items, "*?"):
+		// Globs expand on the target machine; construct loops over base names.
+		var pats []string
+		for p := range strings.SplitSeq(items, ",") {
+			p = strings.TrimSpace(p)
+			basenames = basenames || strings.Contains(p, "/")
+			pats = append(pats, p)
+		}
+		words = strings.Join(pats, " ")
+	default:
+		var expanded []string
+		if v, ok, err := evalValueExpr(items, exportEvalContext{x.e.StructuredParse, scope}); ok && err == nil && v.IsList {
+			expanded = v.L
+		} else if rng, ok := expandRange(items); ok {
+			expanded = rng
+		} else {
+			for item := range strings.SplitSeq(items, ",") {
+				expanded = append(expanded, strings.TrimSpace(item))
+			}
+		}
+		words = shellQuoteWords(expanded)
+	}
+
+	if stmt.Parallel {
+		x.line("# parallel for: iterations run one at a time")
+	}
+	sv := shellIdent(stmt.LoopVar)
+	x.runtime[scope+"."+stmt.LoopVar] = "${" + sv + "}"
+	idx := ""
+	if stmt.LoopIndex != "" {
+		idx = shellIdent(stmt.LoopIndex)
+		x.runtime[scope+"."+stmt.LoopIndex] = "${" + idx + "}"
+		x.line("%s=-1", idx)
+	}
+	x.cond++
+	x.line("for %s in %s; do", sv, words)
+	x.depth++
+	if idx != "" {
+		x.line("%s=$((%s + 1))", idx, idx)
+	}
+	if basenames {
+		x.line(`%s=$(basename "$%s")`, sv, sv)
+	}
+	x.depth--
+	x.nested(ctx, stmt.LoopBody)
+	x.line("done")
+	x.cond--
+}
+
+func (x *shellExport) builtin(ctx *exportCtx, stmt BodyStatement) {
+	var args []string
+	for _, a := range splitArgs(stmt.BuiltinArgs) {
+		args = append(args, shellDQ(x.resolveValue(ctx, a)))
+	}
+	paths := func() []string {
+		var out []string
+		for i, a := range splitArgs(stmt.BuiltinArgs) {
+			if !strings.HasPrefix(a, "-") {
+				out = append(out, args[i])
+			}
+		}
+		return out
+	}
+	var script string
+	switch stmt.Shell {
+	case "cp":
+		if len(args) < 2 {
+			x.problem(ctx, stmt.SourceLine, "cp requires a source and a destination")
+			return
+		}
+		script = "cp -R " + args[0] + " " + args[1]
+	case "rm":
+		if stmt.Modifier == "kill" {
+			x.line("# rm<kill>: processes holding the files are not stopped")
+		}
+		script = "rm -rf " + strings.Join(paths(), " ")
+	case "mkdir":
+		script = "mkdir -p " + strings.Join(paths(), " ")
+	case "touch":
+		script = "touch " + strings.Join(args, " ")
+	case "download", "extract":
+		if len(args) < 2 {
+			x.problem(ctx, stmt.SourceLine, "%s requires two arguments", stmt.Shell)
+			return
+		}
+		x.used[stmt.Shell] = true
+		script = "__construct_" + stmt.Shell + " " + args[0] + " " + args[1]
+	default:
+		x.problem(ctx, stmt.SourceLine, "unknown builtin %q", stmt.Shell)
+		return
+	}
+	if stmt.Tolerant {
+		script += " || true"
+	}
+	x.line("%s", script)
+}
+
+// invoke inlines the invoked body in the caller's context, as construct
+// runs it, with the invoke's arguments bound as the caller's variables.
+func (x *shellExport) invoke(ctx *exportCtx, stmt BodyStatement) {
+	name := strings.TrimSpace(stmt.Shell)
+	invoked, err := x.e.StructuredParse.GetCommand(name)
+	if err != nil {
+		x.problem(ctx, stmt.SourceLine, "invoke %s: %v (cloud definitions are not exported)", name, err)
+		return
+	}
+	if x.invoking[invoked.Name] {
+		x.problem(ctx, stmt.SourceLine, "circular invoke of command '%s'", invoked.Name)
+		return
+	}
+	x.invoking[invoked.Name] = true
+	defer delete(x.invoking, invoked.Name)
+
+	scope := ctx.target.Name
+	passed := map[string]bool{}
+	bind := func(key, val string) {
+		x.e.StructuredParse.SetVariable(key, scope, val)
+		delete(x.runtime, scope+"."+key)
+	}
+	for _, pair := range stmt.InvokeArgs {
+		key, val, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		bind(key, strings.Trim(strings.TrimSpace(val), `"`))
+		passed[key] = true
+	}
+	for _, arg := range invoked.Arguments {
+		if !passed[arg.Name] {
+			bind(arg.Name, strings.Trim(arg.Default, `"`))
+		}
+	}
+
+	x.line("# invoke %s", invoked.Name)
+	if stmt.OutputName == "" {
+		x.body(ctx, invoked.Body)
+		return
+	}
+	sv := shellIdent(stmt.OutputName)
+	x.line("%s=$(", sv)
+	x.nested(ctx, invoked.Body)
+	x.line(")")
+	x.runtime[scope+"."+stmt.OutputName] = "${" + sv + "}"
+}
+
+// shellLine resolves a $ line the way resolveShellLine does, with runtime
+// values left as shell expansions.
+func (x *shellExport) shellLine(ctx *exportCtx, line string, sourceLine int) string {
+	cmd := ctx.target
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return ""
+	}
+	if line[0] == '$' {
+		line = strings.TrimSpace(line[1:])
+	}
+	line = x.resolveVars(ctx, line, true, sourceLine)
+	for _, arg := range cmd.Arguments {
+		if findArgRef(line, arg.Name) < 0 {
+			continue
+		}
+		if arg.IsVariadic {
+			line = replaceArgRef(line, arg.Name, shellQuoteWords(x.e.variadicWords(cmd, arg)))
+			continue
+		}
+		v, err := x.e.argFlags().GetString(cmd.flagScope() + ":" + arg.Name)
+		if err != nil {
+			v = arg.Default
+		}
+		line = replaceArgRef(line, arg.Name, escapeShellValue(v))
+	}
+	return strings.ReplaceAll(x.resolveEnv(ctx, line, sourceLine), "\x00", "")
+}
+
+// resolveValue resolves a body value (a condition, directory, loop items, env
+// value, or builtin argument). Runtime values come back wrapped in \x00 so
+// shellDQ can quote around them.
+func (x *shellExport) resolveValue(ctx *exportCtx, s string) string {
+	return x.resolveEnv(ctx, x.resolveVars(ctx, s, false, 0), 0)
+}
+
+func (x *shellExport) resolveVars(ctx *exportCtx, s string, line bool, sourceLine int) string {
+	data := x.e.StructuredParse
+	scope := ctx.target.Name
+	return resolveVarRefs(s, func(name string) (string, bool) {
+		if strings.HasPrefix(name, "last.") {
+			x.problem(ctx, sourceLine, "&%s has no equivalent in the exported script", name)
+			return "", false
+		}
+		if ref, ok := x.runtime[scope+"."+name]; ok {
+			return "\x00" + ref + "\x00", true
+		}
+		local := false
+		if v, err := data.GetVariable(name, scope); err == nil && v.Scope == scope {
+			local = scope != "global"
+		}
+		if ref, ok := x.runtime["global."+name]; ok && !local {
+			return "\x00" + ref + "\x00", true
+		}
+		base, _, indexed := strings.Cut(name, ".")
+		if v, err := data.GetVariable(base, scope); err == nil && v.Secret {
+			if indexed {
+				x.problem(ctx, sourceLine, "secret &%s cannot be indexed in the exported script", name)
+				return "", false
+			}
+			env := strings.ToUpper(base)
+			if !slices.Contains(x.secrets, env) {
+				x.secrets = append(x.secrets, env)
+			}
+			return "\x00${" + env + ":?}\x00", true
+		}
+		v, ok := LookupVariableIndexed(data, name, scope)
+		if !ok {
+			if file, ok := x.outputFile(ctx, name); ok {
+				return "\x00$(cat \"$__out/" + file + "\" 2>/dev/null)\x00", true
+			}
+			return "", false
+		}
+		if line {
+			return escapeShellValue(v.Joined()), true
+		}
+		return v.String(), true
+	})
+}
+
+// outputFile names the $__out file holding a prerequisite output ref such
+// as gen.version, gen.0, or alias.0.
+func (x *shellExport) outputFile(ctx *exportCtx, name string) (string, bool) {
+	dot := strings.LastIndexByte(name, '.')
+	if dot <= 0 {
+		return "", false
+	}
+	prereq := name[:dot]
+	for p, alias := range ctx.target.PrereqAliases {
+		if alias == prereq {
+			prereq = p
+		}
+	}
+	if x.emitted[prereq] == 0 {
+		return "", false
+	}
+	return shellIdent(prereq) + name[dot:], true
+}
+
+func (x *shellExport) resolveEnv(ctx *exportCtx, s string, sourceLine int) string {
+	s = resolveStateRefsWith(s, func(name string) (string, bool) {
+		x.problem(ctx, sourceLine, "state(%q) is kept by construct and has no shell equivalent", name)
+		return "", false
+	})
+	if strings.IndexByte(s, '@') < 0 {
+		return s
+	}
+	return scanRefs(s, '@', isPlainRune, nil, func(token string) (string, bool) {
+		name, def, hasDefault := splitEnvRefToken(token)
+		if hasDefault {
+			return "\x00${" + name + ":-" + def + "}\x00", true
+		}
+		return "\x00${" + name + "}\x00", true
+	}, false)
+}
+
+// dirExpr quotes a work directory, relative to the Constfile directory.
+func (x *shellExport) dirExpr(dir string) string {
+	if strings.HasPrefix(dir, "/") {
+		return shellDQ(dir)
+	}
+	x.used["root"] = true
+	return shellDQ("\x00$__root\x00/" + dir)
+}
+
+type shellCond struct {
+	sh       string
+	known    bool // the condition folded at export time
+	value    bool
+	compound bool
+}
+
+func (c shellCond) group() string {
+	if c.compound {
+		return "{ " + c.sh + "; }"
+	}
+	return c.sh
+}
+
+var exportBuiltinCondRe = regexp.MustCompile(`^(exists|missing|glob|require|os|arch)\s*\(`)
+
+// condition translates a resolved condition the way evaluateConditionWithBase
+// reads it. Parts with no runtime values fold to a constant; builtins like
+// exists() and os() always test the machine running the script.
+func (x *shellExport) condition(ctx *exportCtx, cond string) shellCond {
+	cond = strings.TrimSpace(cond)
+	if strings.HasPrefix(cond, "(") && matchingOuterParens(cond) {
+		return x.condition(ctx, cond[1:len(cond)-1])
+	}
+	if idx := findTopLevelOp(cond, "||"); idx >= 0 {
+		l, r := x.condition(ctx, cond[:idx]), x.condition(ctx, cond[idx+2:])
+		switch {
+		case l.known && l.value, r.known && r.value:
+			return shellCond{known: true, value: true}
+		case l.known:
+			return r
+		case r.known:
+			return l
+		}
+		return shellCond{sh: l.group() + " || " + r.group(), compound: true}
+	}
+	if idx := findTopLevelOp(cond, "&&"); idx >= 0 {
+		l, r := x.condition(ctx, cond[:idx]), x.condition(ctx, cond[idx+2:])
+		switch {
+		case l.known && !l.value, r.known && !r.value:
+			return shellCond{known: true}
+		case l.known:
+			return r
+		case r.known:
+			return l
+		}
+		return shellCond{sh: l.group() + " && " + r.group(), compound: true}
+	}
+	if rest, ok := strings.CutPrefix(cond, "!"); ok {
+		rest = strings.TrimSpace(rest)
+		if rest == "" {
+			return shellCond{known: true}
+		}
+		c := x.condition(ctx, rest)
+		if c.known {
+			return shellCond{known: true, value: !c.value}
+		}
+		return shellCond{sh: "! " + c.group()}
+	}
+
+	if exportBuiltinCondRe.MatchString(cond) && strings.HasSuffix(cond, ")") {
+		open := strings.IndexByte(cond, '(')
+		name := strings.TrimSpace(cond[:open])
+		arg := strings.Trim(strings.TrimSpace(cond[open+1:len(cond)-1]), `"`)
+		if arg != "" {
+			return shellCond{sh: x.builtinCondition(name, arg)}
+		}
+	}
+	if isStatic(cond) {
+		return shellCond{known: true, value: evaluateConditionWithBase(cond, "")}
+	}
+
+	operands := func(op string) (string, string, bool) {
+		idx := findTopLevelOp(cond, op)
+		if idx <= 0 {
+			return "", "", false
+		}
+		return strings.Trim(strings.TrimSpace(cond[:idx]), `"`), strings.Trim(strings.TrimSpace(cond[idx+len(op):]), `"`), true
+	}
+	patterns := map[string]string{" contains ": `*%s*`, " starts_with ": `%s*`, " ends_with ": `*%s`}
+	for _, op := range []string{" contains ", " starts_with ", " ends_with "} {
+		if l, r, ok := operands(op); ok {
+			return shellCond{sh: fmt.Sprintf("case %s in "+patterns[op]+") true ;; *) false ;; esac", shellDQ(l), shellDQ(r))}
+		}
+	}
+	if l, r, ok := operands(" matches "); ok {
+		return shellCond{sh: fmt.Sprintf("printf '%%s\\n' %s | grep -Eq %s", shellDQ(l), shellDQ(r))}
+	}
+	if l, r, ok := operands(" in "); ok {
+		var items []string
+		for item := range strings.SplitSeq(r, ",") {
+			items = append(items, shellDQ(strings.TrimSpace(item)))
+		}
+		return shellCond{sh: fmt.Sprintf("case %s in %s) true ;; *) false ;; esac", shellDQ(l), strings.Join(items, " | "))}
+	}
+	for _, op := range conditionOps {
+		l, r, ok := operands(op)
+		if !ok {
+			continue
+		}
+		switch {
+		case op == "==" || op == "!=":
+			return shellCond{sh: fmt.Sprintf("[ %s %s %s ]", shellDQ(l), map[string]string{"==": "=", "!=": "!="}[op], shellDQ(r))}
+		case isNonIntegerLiteral(l) || isNonIntegerLiteral(r):
+			return shellCond{sh: fmt.Sprintf("LC_ALL=C expr %s '%s' %s >/dev/null", shellDQ("x"+l), op, shellDQ("x"+r))}
+		default:
+			x.used["cmp"] = true
+			return shellCond{sh: fmt.Sprintf("__construct_cmp %s '%s' %s", shellDQ(l), op, shellDQ(r))}
+		}
+	}
+	return shellCond{known: true}
+}
+
+func (x *shellExport) builtinCondition(name, arg string) string {
+	switch name {
+	case "exists":
+		return "[ -e " + shellDQ(arg) + " ]"
+	case "missing":
+		return "[ ! -e " + shellDQ(arg) + " ]"
+	case "glob":
+		return "ls -d " + strings.ReplaceAll(arg, "\x00", "") + " >/dev/null 2>&1"
+	case "require":
+		return "command -v " + shellDQ(arg) + " >/dev/null 2>&1"
+	case "os":
+		x.used["os"] = true
+		if arg == "macos" {
+			arg = "darwin"
+		}
+		return `[ "$(__construct_os)" = ` + shellDQ(arg) + " ]"
+	}
+	x.used["arch"] = true
+	return `[ "$(__construct_arch)" = ` + shellDQ(arg) + " ]"
+}
+
+// shellDQ double-quotes a resolved value, leaving its \x00-marked shell
+// expansions live.
+func shellDQ(s string) string {
+	parts := strings.Split(s, "\x00")
+	var b strings.Builder
+	b.WriteByte('"')
+	for i, p := range parts {
+		if i%2 == 1 {
+			b.WriteString(p)
+		} else {
+			b.WriteString(escapeShellValue(p))
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func isStatic(s string) bool {
+	return !strings.Contains(s, "\x00")
+}
+
+// isNonIntegerLiteral reports whether s, known at export time, makes a
+// comparison a string comparison whatever the other side holds.
+func isNonIntegerLiteral(s string) bool {
+	_, err := strconv.Atoi(s)
+	return isStatic(s) && err != nil
+}
+
+// shellIdent turns a construct name into a shell variable name.
+func shellIdent(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		if !isWordByte(c) {
+			b[i] = '_'
+		}
+	}
+	if len(b) == 0 || (b[0] >= '0' && b[0] <= '9') {
+		return "_" + string(b)
+	}
+	return string(b)
+}
+
+// exportEvalContext evaluates static list expressions in loop items.
+type exportEvalContext struct {
+	data  *ParsedData
+	scope string
+}
+
+func (c exportEvalContext) LookupVar(name string) (Value, bool) {
+	return LookupVariableIndexed(c.data, name, c.scope)
+}
+
+func (c exportEvalContext) LookupEnv(string) (string, bool) { return "", false }
+
+func (c exportEvalContext) LookupState(string) (string, bool) { return "", false }
+
+func (c exportEvalContext) BaseDir() string { return "." }
@@ -0,0 +1,214 @@
+package pkg
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func exportShell(t *testing.T, src string, targets ...string) (string, error) {
+	t.Helper()
+	p := NewParserFromContent("Constfile", src)
+	data, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewExecutor(data, false, false).ExportShell(targets)
+}
+
+func TestExportShellRunsClosureInOrder(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	script, err := exportShell(t, `var name = world
+var rev = $ echo abc
+
+gen {
+    $ echo "1.2.3" as version
+}
+
+build (opt mode=release) in out < gen {
+    env { V=&gen.version }
+    $ echo "build &mode &name $V &rev"
+    if &name == "world" {
+        $ echo folded
+    } else {
+        $ echo dropped
+    }
+    if exists("../Constfile") {
+        $ echo runtime-if
+    }
+    for t in a, b {
+        $ echo "item &t"
+    }
+    switch @EXPORT_MODE:-fast {
+        case "fast" { $ echo fast }
+        default { $ echo slow }
+    }
+    mkdir dist/bin
+    ! $ false
+}
+
+_ < build, gen { }
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"rev=$(echo abc)\n",
+		`__o=$(echo "1.2.3")`,
+		`export V="$(cat "$__out/gen.version" 2>/dev/null)"`,
+		`cd "$__root/out"`,
+		"\tfor t in a b; do\n",
+		`case "${EXPORT_MODE:-fast}" in`,
+		"\tfalse || true\n",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("missing %q:\n%s", want, script)
+		}
+	}
+	if strings.Contains(script, "dropped") || strings.Count(script, "# gen\n") != 1 {
+		t.Errorf("folded branch or duplicate prerequisite emitted:\n%s", script)
+	}
+
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "out"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "Constfile"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("sh", "-c", script)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("script failed: %v\n%s", err, out)
+	}
+	want := "build release world 1.2.3 abc\nfolded\nruntime-if\nitem a\nitem b\nfast\n"
+	if string(out) != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out", "dist", "bin")); err != nil {
+		t.Errorf("mkdir did not run in the command's directory: %v", err)
+	}
+}
+
+func TestExportShellReportsUntranslatable(t *testing.T) {
+	script, err := exportShell(t, `deploy {
+    lock db {
+        $ echo locked
+    }
+    parallel for f in a, b {
+        $ echo &f
+    }
+}
+`, "deploy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# lock db: not held", "# parallel for: iterations run one at a time"} {
+		if !strings.Contains(script, want) {
+			t.Errorf("missing %q:\n%s", want, script)
+		}
+	}
+
+	_, err = exportShell(t, `service api {
+    $ ./api
+}
+
+deploy < api {
+    state released = 1
+    $ echo &last.exit
+}
+`, "deploy")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"api (line 1): services run", "deploy (line 6): state released", "deploy (line 7): &last.exit"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error missing %q:\n%v", want, err)
+		}
+	}
+}
+
+func TestExportShellKeepsSecretsOut(t *testing.T) {
+	script, err := exportShell(t, `secret token = hunter2-export
+
+deploy {
+    $ echo "X: &token"
+}
+`, "deploy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(script, "hunter2-export") || !strings.Contains(script, `: "${TOKEN:?}"`) || !strings.Contains(script, `echo "X: ${TOKEN:?}"`) {
+		t.Errorf("secret not read from the environment:\n%s", script)
+	}
+	if runtime.GOOS != "windows" {
+		cmd := exec.Command("sh", "-c", script)
+		cmd.Env = append(os.Environ(), "TOKEN=from-env")
+		if out, err := cmd.CombinedOutput(); err != nil || string(out) != "X: from-env\n" {
+			t.Errorf("script with TOKEN set = %q, %v", out, err)
+		}
+		cmd = exec.Command("sh", "-c", script)
+		cmd.Env = append(os.Environ(), "TOKEN=")
+		if out, err := cmd.CombinedOutput(); err == nil || strings.Contains(string(out), "X:") {
+			t.Errorf("script without TOKEN ran: %q", out)
+		}
+	}
+
+	data, err := NewParserFromContent("Constfile", "deploy {\n    $ echo hi\n}\n").Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.lockedSecrets = []string{"api"}
+	if _, err := NewExecutor(data, false, false).ExportShell([]string{"deploy"}); !errors.Is(err, ErrSecretsLocked) {
+		t.Errorf("locked store: err = %v, want ErrSecretsLocked", err)
+	}
+}
+
+func TestExportShellComparisons(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	script, err := exportShell(t, `check {
+    if @L > b {
+        $ echo str-gt
+    }
+    if @L > 5 {
+        $ echo gt-5
+    }
+    if @L <= @R {
+        $ echo le-r
+    }
+}
+`, "check")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(script, `-gt "b"`) {
+		t.Errorf("string operand compared numerically:\n%s", script)
+	}
+	for _, tc := range []struct{ l, r string }{{"c", "a"}, {"10", "9"}, {"a", "b"}, {"-3", "-3"}, {"9", "10"}} {
+		var want []string
+		if compareValues(tc.l, "b", ">") {
+			want = append(want, "str-gt")
+		}
+		if compareValues(tc.l, "5", ">") {
+			want = append(want, "gt-5")
+		}
+		if compareValues(tc.l, tc.r, "<=") {
+			want = append(want, "le-r")
+		}
+		cmd := exec.Command("sh", "-c", script)
+		cmd.Env = append(os.Environ(), "L="+tc.l, "R="+tc.r)
+		out, err := cmd.CombinedOutput()
+		if got := strings.Fields(string(out)); err != nil || strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Errorf("L=%s R=%s: ran %q (%v), want %q", tc.l, tc.r, out, err, want)
+		}
+	}
+}
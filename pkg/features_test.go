@@ -2035,6 +2035,49 @@ func TestKeepGoingRunsOtherTargets(t *testing.T) {
 	}
 }
 
+func TestNoDepsSkipsPrerequisites(t *testing.T) {
+	data, err := NewParserFromContent("Constfile", "gen {\n    $ echo gen-ran\n}\n\ntest matrix os in linux, darwin < gen {\n    $ echo test-&os\n}\n\nimage < test {\n    $ echo image-ran\n}\n").Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := func(target string) string {
+		e := NewExecutor(data, false, false)
+		e.SetBaseDir(t.TempDir())
+		e.SetNoDeps(true)
+		return captureStdoutFor(t, func() error { return e.Execute([]string{target}) })
+	}
+	if out := run("image"); !strings.Contains(out, "image-ran") || strings.Contains(out, "test-") || strings.Contains(out, "gen-ran") {
+		t.Errorf("image with no deps ran %q, want image alone", out)
+	}
+	// A matrix target still runs its cells, but not what they depend on.
+	if out := run("test"); !strings.Contains(out, "test-linux") || !strings.Contains(out, "test-darwin") || strings.Contains(out, "gen-ran") {
+		t.Errorf("test with no deps ran %q, want both cells without gen", out)
+	}
+}
+
+func TestNoDepsReadsRecordedOutputs(t *testing.T) {
+	data, err := NewParserFromContent("Constfile", "gen {\n    $ echo gen-ran >&2\n    $ echo 1.2.3 as version\n}\n\nbuild < gen {\n    $ echo v=&gen.version\n}\n").Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	e := NewExecutor(data, false, false)
+	e.SetBaseDir(dir)
+	e.SetRecordRuns(true)
+	captureStdoutFor(t, func() error { return e.Execute([]string{"gen"}) })
+	saved, err := os.ReadFile(filepath.Join(dir, CacheDirName(), "outputs", "gen.json"))
+	if err != nil || !strings.Contains(string(saved), `"version": "1.2.3"`) {
+		t.Fatalf("saved outputs = %s, %v", saved, err)
+	}
+
+	e = NewExecutor(data, false, false)
+	e.SetBaseDir(dir)
+	e.SetNoDeps(true)
+	if out := captureStdoutFor(t, func() error { return e.Execute([]string{"build"}) }); out != "v=1.2.3\n" {
+		t.Errorf("build with no deps printed %q, want gen's recorded version", out)
+	}
+}
+
 func TestNoCacheReruns(t *testing.T) {
 	dir := t.TempDir()
 	dep := filepath.Join(dir, "data.txt")
@@ -2203,3 +2246,433 @@ func TestOnChangeHeaderGrammar(t *testing.T) {
 		}
 	}
 }
+
+func TestParameterizedPrereqs(t *testing.T) {
+	in := `build (os, arch=amd64) {
+    $ echo "&os/&arch"
+}
+
+release < build(os=linux), build(arch=arm64, os=windows) as win, build(os=linux) {
+    $ echo "&build(os=linux).0 &win.0"
+}`
+	data, err := NewParserFromContent("t.constfile", in).Parse()
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	release, _ := data.GetCommand("release")
+	if got := strings.Join(release.Prereqs, " "); got != "build(os=linux) build(arch=arm64,os=windows) build(os=linux)" {
+		t.Fatalf("prereqs = %q", got)
+	}
+	if release.PrereqAliases["build(arch=arm64,os=windows)"] != "win" {
+		t.Errorf("aliases = %v", release.PrereqAliases)
+	}
+	if len(data.Commands) != 2 {
+		t.Errorf("instances leaked into Commands: %d commands", len(data.Commands))
+	}
+
+	e := NewExecutor(data, true, false)
+	if err := e.Execute([]string{"release"}); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	linux, _ := data.GetCommand("build(os=linux)")
+	win, _ := data.GetCommand("build(arch=arm64,os=windows)")
+	if len(linux.PrereqOutput) != 1 || linux.PrereqOutput[0] != "linux/amd64" {
+		t.Errorf("linux output = %q", linux.PrereqOutput)
+	}
+	if len(win.PrereqOutput) != 1 || win.PrereqOutput[0] != "windows/arm64" {
+		t.Errorf("windows output = %q", win.PrereqOutput)
+	}
+	if e.cacheKey(linux) == e.cacheKey(win) {
+		t.Error("instances share a cache key")
+	}
+	if got, _ := data.LookupVariable("win.0", "release"); got != "windows/arm64" {
+		t.Errorf("&win.0 = %q", got)
+	}
+}
+
+func TestParameterizedPrereqErrors(t *testing.T) {
+	cases := map[string]string{
+		"unknown argument": "build (os) {\n    $ true\n}\nr < build(cpu=x) {\n    $ true\n}",
+		"cycle":            "build (os) < r {\n    $ true\n}\nr < build(os=x) {\n    $ true\n}",
+		"missing base":     "r < bld(os=x) {\n    $ true\n}",
+	}
+	for name, in := range cases {
+		if _, err := NewParserFromContent("t.constfile", in).Parse(); err == nil {
+			t.Errorf("%s: expected a parse error", name)
+		}
+	}
+}
+
+func TestHeaderMatrixExpansion(t *testing.T) {
+	in := `build matrix os in linux, windows; arch in amd64, arm64; exclude os=windows arch=arm64; include os=darwin arch=arm64 < prep {
+    $ echo "&os/&arch"
+}
+
+prep {
+    $ echo prep
+}
+
+ship < build[linux,arm64] as b {
+    $ echo "&b.0"
+}`
+	data, err := NewParserFromContent("t.constfile", in).Parse()
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	build, _ := data.GetCommand("build")
+	want := "build[linux,amd64] build[linux,arm64] build[windows,amd64] build[darwin,arm64]"
+	if got := strings.Join(build.Prereqs, " "); got != want {
+		t.Fatalf("cells = %q, want %q", got, want)
+	}
+	cell, err := data.GetCommand("build[darwin,arm64]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cell.BaseName != "build" || cell.BoundArgs["os"] != "darwin" || strings.Join(cell.Prereqs, ",") != "prep" {
+		t.Errorf("cell = base %q bound %v prereqs %v", cell.BaseName, cell.BoundArgs, cell.Prereqs)
+	}
+	if got := strings.Join(data.MatrixTargets("build[linux,*]"), " "); got != "build[linux,amd64] build[linux,arm64]" {
+		t.Errorf("MatrixTargets = %q", got)
+	}
+	if !strings.Contains(EmitHeader(build), "matrix os in linux, windows; arch in amd64, arm64; exclude os=windows arch=arm64; include os=darwin arch=arm64 < prep {") {
+		t.Errorf("EmitHeader = %q", EmitHeader(build))
+	}
+
+	e := NewExecutor(data, false, false)
+	if err := e.Execute([]string{"ship", "build[windows,*]"}); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if got, _ := data.LookupVariable("b.0", "ship"); got != "linux/arm64" {
+		t.Errorf("&b.0 = %q", got)
+	}
+}
+
+func TestHeaderMatrixErrors(t *testing.T) {
+	cases := map[string]string{
+		"unknown rule axis": "build matrix os in a, b; exclude cpu=x {\n    $ true\n}",
+		"partial include":   "build matrix os in a; arch in x; include os=c {\n    $ true\n}",
+		"everything gone":   "build matrix os in a; exclude os=a {\n    $ true\n}",
+		"missing cell":      "build matrix os in a {\n    $ true\n}\nship < build[b] {\n    $ true\n}",
+	}
+	for name, in := range cases {
+		if _, err := NewParserFromContent("t.constfile", in).Parse(); err == nil {
+			t.Errorf("%s: expected a parse error", name)
+		}
+	}
+}
+
+func TestConditionalPrereqsAndGuards(t *testing.T) {
+	t.Setenv("CONSTRUCT_TEST_CI", "false")
+	in := `lint {
+    $ echo linted
+}
+
+gen {
+    $ echo generated
+}
+
+build < lint if "@CONSTRUCT_TEST_CI" == "true", gen {
+    $ echo "&gen.0"
+}
+
+windows_only if os("plan9") {
+    $ echo nope
+}`
+	data, err := NewParserFromContent("t.constfile", in).Parse()
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	build, _ := data.GetCommand("build")
+	if got := strings.Join(build.Prereqs, " "); got != "lint gen" {
+		t.Fatalf("prereqs = %q", got)
+	}
+	if build.PrereqConds["lint"] != `"@CONSTRUCT_TEST_CI" == "true"` {
+		t.Errorf("conds = %v", build.PrereqConds)
+	}
+	guarded, _ := data.GetCommand("windows_only")
+	if guarded.Guard != `os("plan9")` {
+		t.Errorf("guard = %q", guarded.Guard)
+	}
+	if got := EmitHeader(build); got != `build < lint if "@CONSTRUCT_TEST_CI" == "true", gen {` {
+		t.Errorf("EmitHeader = %q", got)
+	}
+	if got := EmitHeader(guarded); got != `windows_only if os("plan9") {` {
+		t.Errorf("EmitHeader = %q", got)
+	}
+
+	e := NewExecutor(data, true, false)
+	e.SetBaseDir(t.TempDir())
+	e.SetRecordRuns(true)
+	if err := e.Execute([]string{"build", "windows_only"}); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	lint, _ := data.GetCommand("lint")
+	gen, _ := data.GetCommand("gen")
+	if len(lint.PrereqOutput) != 0 {
+		t.Errorf("pruned prerequisite ran: %q", lint.PrereqOutput)
+	}
+	if len(gen.PrereqOutput) != 1 || gen.PrereqOutput[0] != "generated" {
+		t.Errorf("gen output = %q", gen.PrereqOutput)
+	}
+	if got := e.RunRecords()["windows_only"].Status; got != "skipped" {
+		t.Errorf("guarded command status = %q", got)
+	}
+}
+
+func TestOrderOnlyPrereqs(t *testing.T) {
+	in := `mkdirs {
+    $ echo dirs
+}
+
+gen {
+    $ echo gen
+}
+
+build < gen, main.go | mkdirs {
+    $ echo build
+}
+
+setup < | mkdirs if "a" == "a" || "b" == "c" {
+    $ echo setup
+}`
+	data, err := NewParserFromContent("t.constfile", in).Parse()
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	build, _ := data.GetCommand("build")
+	if got := strings.Join(build.Prereqs, " "); got != "gen mkdirs" {
+		t.Fatalf("prereqs = %q", got)
+	}
+	if !build.PrereqOrderOnly["mkdirs"] || build.PrereqOrderOnly["gen"] {
+		t.Errorf("order-only = %v", build.PrereqOrderOnly)
+	}
+	if got := strings.Join(build.FileDeps, " "); got != "main.go" {
+		t.Errorf("file deps = %q", got)
+	}
+	if got := EmitHeader(build); got != "build < gen, main.go | mkdirs {" {
+		t.Errorf("EmitHeader = %q", got)
+	}
+	setup, _ := data.GetCommand("setup")
+	if setup.PrereqConds["mkdirs"] != `"a" == "a" || "b" == "c"` || !setup.PrereqOrderOnly["mkdirs"] {
+		t.Errorf("setup conds = %v, order-only = %v", setup.PrereqConds, setup.PrereqOrderOnly)
+	}
+	if got := EmitHeader(setup); got != `setup < | mkdirs if "a" == "a" || "b" == "c" {` {
+		t.Errorf("EmitHeader = %q", got)
+	}
+
+	e := NewExecutor(data, true, false)
+	e.SetBaseDir(t.TempDir())
+	if err := e.Execute([]string{"build"}); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	mkdirs, _ := data.GetCommand("mkdirs")
+	if len(mkdirs.PrereqOutput) != 1 || mkdirs.PrereqOutput[0] != "dirs" {
+		t.Errorf("order-only prerequisite did not run first: %q", mkdirs.PrereqOutput)
+	}
+
+	if _, err := NewParserFromContent("t.constfile", "b < | out/ {\n    $ true\n}").Parse(); err == nil {
+		t.Error("expected an order-only file prerequisite to be rejected")
+	}
+}
+
+func TestParameterizedImports(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "tool.constfile"), []byte(`var flags = -v
+var cmdline = go build &flags
+`), 0644)
+	os.WriteFile(filepath.Join(dir, "lib.constfile"), []byte(`import "tool.constfile" as tool with (flags = "-race")
+var goversion = 1.22
+var out = dist
+var image = golang:&goversion
+`), 0644)
+	os.WriteFile(filepath.Join(dir, "Constfile"), []byte(`var dist = build/out
+import "lib.constfile" as lib with (goversion = "1.26", out = &dist, tool.flags = "-trimpath", typo = x)
+`), 0644)
+	p, err := NewParser(filepath.Join(dir, "Constfile"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"lib.goversion":    "1.26",
+		"lib.out":          "build/out",
+		"lib.image":        "golang:1.26", // derived globals see the override
+		"lib.tool.cmdline": "go build -trimpath",
+	}
+	for name, v := range want {
+		if got, _ := data.LookupVariable(name, "global"); got != v {
+			t.Errorf("&%s = %q, want %q", name, got, v)
+		}
+	}
+
+	var unknown []string
+	for _, is := range Lint(nil, data, dir) {
+		if strings.Contains(is.Message, "to override") {
+			unknown = append(unknown, is.Message)
+			if is.Severity != LintError {
+				t.Errorf("severity = %d for %q", is.Severity, is.Message)
+			}
+		}
+		if strings.Contains(is.Message, `"dist" is never referenced`) {
+			t.Errorf("a global used only in with (...) was reported unused")
+		}
+	}
+	if len(unknown) != 1 || !strings.Contains(unknown[0], `"typo"`) {
+		t.Errorf("unknown-override lint = %q", unknown)
+	}
+
+	for _, tc := range []struct{ src, want string }{
+		{"import \"lib.constfile\" as lib with (out = &missing)\n", "&missing is not a global defined before the import"},
+		{"import \"lib.constfile\" as lib with (out = &later)\nvar later = x\n", "&later is not a global defined before the import"},
+	} {
+		os.WriteFile(filepath.Join(dir, "Constfile"), []byte("var dist = build/out\n"+tc.src), 0644)
+		p, err := NewParser(filepath.Join(dir, "Constfile"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = p.Parse()
+		var pe *ParseError
+		if !errors.As(err, &pe) || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%q: err = %v, want a parse error containing %q", tc.src, err, tc.want)
+			continue
+		}
+		if pe.Line != 2 || !strings.HasSuffix(pe.File, "Constfile") {
+			t.Errorf("%q: error at %s:%d, want Constfile:2", tc.src, pe.File, pe.Line)
+		}
+	}
+}
+
+func TestPrivateCommandsAndVars(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "lib.constfile"), []byte(`private var flags = -trimpath
+var cmdline = go build &flags
+private setup {
+    echo setup
+}
+private unused {
+    echo nobody calls this
+}
+build < setup {
+    invoke setup
+    echo &flags
+}
+`), 0644)
+	parse := func(main string) (*ParsedData, error) {
+		os.WriteFile(filepath.Join(dir, "Constfile"), []byte(main), 0644)
+		p, err := NewParser(filepath.Join(dir, "Constfile"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p.Parse()
+	}
+
+	data, err := parse("import \"lib.constfile\" as lib\n_ < lib.build {\n    echo &lib.cmdline\n}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd, _ := data.GetCommand("lib.setup"); cmd == nil || !cmd.Private {
+		t.Errorf("lib.setup = %+v, want a private command", cmd)
+	}
+	if got, _ := data.LookupVariable("lib.cmdline", "global"); got != "go build -trimpath" {
+		t.Errorf("&lib.cmdline = %q", got)
+	}
+	var unused []string
+	for _, is := range Lint(nil, data, dir) {
+		if strings.Contains(is.Message, "never referenced") && strings.Contains(is.Message, "lib.") {
+			unused = append(unused, is.Message)
+		}
+	}
+	if len(unused) != 1 || !strings.Contains(unused[0], `private command "lib.unused"`) {
+		t.Errorf("unreferenced lint = %q", unused)
+	}
+
+	for _, tc := range []struct{ name, main, want string }{
+		{"prereq", "import \"lib.constfile\" as lib\n_ < lib.setup {\n}\n", `command "lib.setup" is private to lib.constfile`},
+		{"invoke", "import \"lib.constfile\" as lib\n_ {\n    invoke lib.setup\n}\n", `command "lib.setup" is private`},
+		{"ref", "import \"lib.constfile\" as lib\n_ {\n    echo &lib.flags\n}\n", `variable "lib.flags" is private`},
+		{"global", "import \"lib.constfile\" as lib\nvar mine = &lib.flags\n", `variable "lib.flags" is private`},
+		{"flat", "import \"lib.constfile\"\n_ < setup {\n}\n", `command "setup" is private`},
+		{"with", "import \"lib.constfile\" as lib with (flags = -race)\n", `variable "flags" is private`},
+	} {
+		if _, err := parse(tc.main); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: err = %v, want %q", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	src := `var region = us-west-2
+var replicas = 1
+var image = app:&region
+
+profile prod {
+    var region = us-east-1 // comment
+    var replicas = 3
+    var typo = x
+}
+profile dev { var region = local }
+
+deploy {
+    echo &image &replicas
+}
+`
+	parse := func(profile string) (*ParsedData, error) {
+		p := NewParserFromContent("Constfile", src)
+		p.Profile = profile
+		return p.Parse()
+	}
+
+	for profile, want := range map[string][2]string{
+		"":     {"app:us-west-2", "1"},
+		"prod": {"app:us-east-1", "3"},
+		"dev":  {"app:local", "1"},
+	} {
+		data, err := parse(profile)
+		if err != nil {
+			t.Fatalf("%q: %v", profile, err)
+		}
+		image, _ := data.LookupVariable("image", "global")
+		replicas, _ := data.LookupVariable("replicas", "global")
+		if image != want[0] || replicas != want[1] {
+			t.Errorf("profile %q: image=%q replicas=%q, want %v", profile, image, replicas, want)
+		}
+		if data.Profile != profile || !slices.Equal(data.ProfileNames(), []string{"dev", "prod"}) {
+			t.Errorf("profile %q: Profile=%q names=%v", profile, data.Profile, data.ProfileNames())
+		}
+	}
+
+	if _, err := parse("stage"); err == nil || !strings.Contains(err.Error(), "available: dev, prod") {
+		t.Errorf("unknown profile err = %v", err)
+	}
+
+	data, _ := parse("")
+	var warnings []string
+	for _, is := range Lint(nil, data, "") {
+		if strings.Contains(is.Message, "profile") {
+			warnings = append(warnings, fmt.Sprintf("%d:%s", is.Line+1, is.Message))
+		}
+	}
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], `8:profile prod sets "typo"`) {
+		t.Errorf("profile lint = %q", warnings)
+	}
+
+	prod, _ := parse("prod")
+	e := NewExecutor(prod, false, false)
+	e.SetBaseDir(t.TempDir())
+	e.SetQuiet(true)
+	e.SetRecordRuns(true)
+	cmd, _ := prod.GetCommand("deploy")
+	if key := e.cacheKey(cmd); !strings.Contains(key, "profile=prod") {
+		t.Errorf("cache key %q does not include the profile", key)
+	}
+	if err := e.Execute([]string{"deploy"}); err != nil {
+		t.Fatal(err)
+	}
+	hist := LoadRunHistory(filepath.Join(e.baseDir, CacheDirName()))
+	if recs := hist["deploy"]; len(recs) != 1 || recs[0].Profile != "prod" {
+		t.Errorf("run records = %+v", recs)
+	}
+}
@@ -32,6 +32,13 @@ type GHJob struct {
 	Conclusion string `json:"conclusion"`
 }
 
+type GHArtifact struct {
+	ID      int64  `json:"id"`
+	Name    string `json:"name"`
+	Size    int64  `json:"size_in_bytes"`
+	Expired bool   `json:"expired"`
+}
+
 type GHClient struct {
 	baseURL string
 	token   string
@@ -183,6 +190,38 @@ func (c *GHClient) JobLogs(ctx context.Context, jobID int64) ([]byte, error) {
 	return io.ReadAll(io.LimitReader(resp.Body, maxLogBytes))
 }
 
+func (c *GHClient) Artifacts(ctx context.Context, runID int64) ([]GHArtifact, error) {
+	var res struct {
+		Artifacts []GHArtifact `json:"artifacts"`
+	}
+	_, err := c.do(ctx, "GET", fmt.Sprintf("/repos/%s/actions/runs/%d/artifacts?per_page=100", c.repo, runID), nil, &res)
+	return res.Artifacts, err
+}
+
+// DownloadArtifact writes the artifact's zip archive to w. The API answers
+// with a redirect to blob storage, which net/http follows without the token.
+func (c *GHClient) DownloadArtifact(ctx context.Context, artifactID int64, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/repos/%s/actions/artifacts/%d/zip", c.baseURL, c.repo, artifactID), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := artifactHTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("artifact %d: %s", artifactID, resp.Status)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
 func (c *GHClient) Cancel(ctx context.Context, runID int64) error {
 	_, err := c.do(ctx, "POST", fmt.Sprintf("/repos/%s/actions/runs/%d/cancel", c.repo, runID), nil, nil)
 	return err
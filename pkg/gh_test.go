@@ -1,11 +1,15 @@
 package pkg
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -98,6 +102,23 @@ func mockGithub(t *testing.T) (*GHClient, *httptest.Server) {
 	mux.HandleFunc("GET /repos/o/r/actions/jobs/{id}/logs", func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprint(w, "group: building\nsecret value hunter2\nline two\n")
 	})
+	mux.HandleFunc("GET /repos/o/r/actions/runs/{id}/artifacts", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"artifacts": []GHArtifact{
+			{ID: 9, Name: "construct-cache", Size: 120},
+			{ID: 10, Name: "old", Expired: true},
+		}})
+	})
+	// Like GitHub, hand the archive off to blob storage on another host.
+	blob := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("token forwarded to blob storage")
+		}
+		w.Write(zipArchive(t, map[string]string{"run-state.json": "{}"}))
+	}))
+	t.Cleanup(blob.Close)
+	mux.HandleFunc("GET /repos/o/r/actions/artifacts/{id}/zip", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, strings.Replace(blob.URL, "127.0.0.1", "localhost", 1)+"/"+r.PathValue("id"), http.StatusFound)
+	})
 
 	client := NewGHClient("o/r", "test-token", srv.URL)
 	return client, srv
@@ -160,6 +181,40 @@ func TestGHClientJobLogsAndCancel(t *testing.T) {
 	}
 }
 
+func TestGHBackendDownloadsArtifacts(t *testing.T) {
+	client, srv := mockGithub(t)
+	defer srv.Close()
+	into := t.TempDir()
+	dirs, err := DownloadCloudArtifacts(context.Background(), NewGitHubBackend(client, "construct.yml"), 42, into)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(into, "construct-cache"); len(dirs) != 1 || dirs[0] != want {
+		t.Fatalf("dirs = %v, want [%s] (the expired artifact skipped)", dirs, want)
+	}
+	if _, err := os.Stat(filepath.Join(dirs[0], "run-state.json")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// zipArchive builds a zip of name -> content.
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
 func TestRunConclusionToExit(t *testing.T) {
 	for c, want := range map[string]int{"success": 0, "skipped": 0, "neutral": 0, "failure": 1, "cancelled": 1, "timed_out": 1, "": 1} {
 		if got := RunConclusionToExit(c); got != want {
@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -23,9 +24,11 @@ type gitSource struct {
 }
 
 type ImportLockEntry struct {
+	Kind string `json:"kind,omitempty"` // "url" for archive imports; empty for git
 	URL  string `json:"url"`
 	Ref  string `json:"ref,omitempty"`
 	Rev  string `json:"rev"`
+	Hash string `json:"hash,omitempty"` // sha256 of the imported Constfile and its local imports
 	Dir  string `json:"dir,omitempty"`
 	Path string `json:"path,omitempty"`
 }
@@ -34,6 +37,15 @@ type ImportLock struct {
 	Imports map[string]ImportLockEntry `json:"imports"`
 }
 
+// frozenLockfile makes remote imports read-only: nothing outside
+// .construct.lock is fetched and the lock is never rewritten.
+var frozenLockfile bool
+
+// SetFrozenLockfile turns on --frozen-lockfile for every parse in the process.
+func SetFrozenLockfile(v bool) {
+	frozenLockfile = v
+}
+
 func parseGitSpec(spec string) (gitSource, error) {
 	src := gitSource{spec: strings.TrimSpace(spec)}
 	s := strings.Trim(src.spec, `"`)
@@ -153,6 +165,13 @@ func runGit(dir string, args ...string) (string, error) {
 }
 
 func ensureGitImport(spec, baseDir string) (string, string, error) {
+	return gitImportFile(spec, baseDir, true)
+}
+
+// gitImportFile resolves a git import to a Constfile on disk: the vendored
+// copy when preferVendor is set and one exists, else the cached checkout,
+// fetching it when needed.
+func gitImportFile(spec, baseDir string, preferVendor bool) (string, string, error) {
 	src, err := parseGitSpec(spec)
 	if err != nil {
 		return "", "", err
@@ -161,11 +180,21 @@ func ensureGitImport(spec, baseDir string) (string, string, error) {
 	lockPath := importLockPath(root)
 	lock := loadImportLock(lockPath)
 	entry, locked := lock.Imports[src.spec]
+	if frozenLockfile && !locked {
+		return "", "", fmt.Errorf("import %q is not pinned in %s (--frozen-lockfile)", src.spec, filepath.Base(lockPath))
+	}
+	if locked && preferVendor {
+		if file, ok, err := vendoredImport(root, src.spec, entry); ok || err != nil {
+			return file, src.repo, err
+		}
+	}
 
 	dir := filepath.Join(root, CacheDirName(), "imports", shortHash(src.spec))
+	file := filepath.Join(dir, src.subPath, "Constfile")
+	fetched := false
 	if !locked || !dirExists(filepath.Join(dir, ".git")) {
 		ref := src.ref
-		if locked && ref == "" {
+		if locked {
 			ref = entry.Rev
 		}
 		if err := gitClone(src.url, ref, dir); err != nil {
@@ -175,20 +204,124 @@ func ensureGitImport(spec, baseDir string) (string, string, error) {
 		if rev == "" {
 			return "", "", fmt.Errorf("import %q: could not read the fetched revision", src.spec)
 		}
-		lock.Imports[src.spec] = ImportLockEntry{URL: src.url, Ref: src.ref, Rev: rev, Dir: dir}
-		if err := saveImportLock(lockPath, lock); err != nil {
-			return "", "", fmt.Errorf("import %q: could not save %s: %w", src.spec, filepath.Base(lockPath), err)
+		if !locked {
+			entry = ImportLockEntry{URL: src.url, Ref: src.ref, Rev: rev}
 		}
+		entry.Dir = dir
+		fetched = true
 		fmt.Fprintf(os.Stderr, "(import: fetched %s @ %s)\n", src.repo, shortRev(rev))
 	}
 
-	file := filepath.Join(dir, src.subPath, "Constfile")
 	if _, err := os.Stat(file); err != nil {
 		return "", "", fmt.Errorf("import %q: no Constfile found at %s", src.spec, filepath.Join(src.repo, src.subPath, "Constfile"))
 	}
+	if err := pinImport(lock, lockPath, src.spec, entry, file, fetched); err != nil {
+		return "", "", err
+	}
 	return file, src.repo, nil
 }
 
+// pinImport checks a remote import's Constfile closure against the hash in
+// its lock entry, recording the hash when the entry has none yet, and saves
+// the lock when the entry is new or changed (never under --frozen-lockfile).
+func pinImport(lock *ImportLock, lockPath, key string, entry ImportLockEntry, file string, fetched bool) error {
+	hash, err := importClosureHash(file)
+	if err != nil {
+		return fmt.Errorf("import %q: %w", key, err)
+	}
+	root := filepath.Dir(lockPath)
+	switch {
+	case entry.Hash == "" && frozenLockfile:
+		return fmt.Errorf("import %q has no content hash in %s (--frozen-lockfile); run a build without it to record one", key, filepath.Base(lockPath))
+	case entry.Hash == "":
+		entry.Hash = hash // first fetch, or a lock written before hashes
+	case entry.Hash != hash:
+		return fmt.Errorf("import %q: %s does not match %s (got %s, locked %s); run `construct import verify`, or delete the checkout to refetch",
+			key, relOrAbs(root, entry.Dir), filepath.Base(lockPath), shortHashValue(hash), shortHashValue(entry.Hash))
+	}
+	if !frozenLockfile && (fetched || lock.Imports[key] != entry) {
+		lock.Imports[key] = entry
+		if err := saveImportLock(lockPath, lock); err != nil {
+			return fmt.Errorf("import %q: could not save %s: %w", key, filepath.Base(lockPath), err)
+		}
+	}
+	return nil
+}
+
+// importClosureHash hashes a remote Constfile together with every local file
+// it imports, so an edit anywhere in the recipe changes the hash. Paths are
+// hashed relative to the Constfile, keeping the hash stable across machines.
+func importClosureHash(file string) (string, error) {
+	closure, err := importClosure(file)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, rel := range slices.Sorted(maps.Keys(closure)) {
+		data := closure[rel]
+		fmt.Fprintf(h, "%s\x00%d\x00", rel, len(data))
+		h.Write(data)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// importClosure reads a Constfile and the local files it imports, keyed by
+// slash path relative to the Constfile's directory.
+func importClosure(file string) (map[string][]byte, error) {
+	base := filepath.Dir(file)
+	closure := map[string][]byte{}
+	queue := []string{filepath.Clean(file)}
+	for len(queue) > 0 {
+		f := queue[0]
+		queue = queue[1:]
+		rel, err := filepath.Rel(base, f)
+		if err != nil {
+			rel = f
+		}
+		rel = filepath.ToSlash(rel)
+		if _, seen := closure[rel]; seen {
+			continue
+		}
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		closure[rel] = data
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if !strings.HasPrefix(line, "import ") {
+				continue
+			}
+			spec, err := parseImportSpec(line)
+			if err != nil || spec.isGit || spec.isURL {
+				continue // remote imports are pinned by their own lock entry
+			}
+			path := spec.path
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(filepath.Dir(f), path)
+			}
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				queue = append(queue, filepath.Clean(path))
+			}
+		}
+	}
+	return closure, nil
+}
+
+func shortHashValue(hash string) string {
+	if len(hash) > len("sha256:")+12 {
+		return hash[:len("sha256:")+12]
+	}
+	return hash
+}
+
+func relOrAbs(root, path string) string {
+	if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
 func gitClone(url, ref, dst string) error {
 	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
 		return err
@@ -232,6 +365,9 @@ func gitRev(dir string) string {
 }
 
 func UpdateGitImports(baseDir string, specs []string) (int, error) {
+	if frozenLockfile {
+		return 0, fmt.Errorf("import update rewrites the lock file; it cannot run with --frozen-lockfile")
+	}
 	root := importRootDir(baseDir)
 	lockPath := importLockPath(root)
 	lock := loadImportLock(lockPath)
@@ -251,6 +387,10 @@ func UpdateGitImports(baseDir string, specs []string) (int, error) {
 			continue
 		}
 		entry := lock.Imports[k]
+		if entry.Kind == "url" {
+			fmt.Printf("%s: pinned by checksum; change the URL to update\n", k)
+			continue
+		}
 		if isCommitSHA(entry.Ref) {
 			fmt.Printf("%s: pinned to a commit; nothing to update\n", k)
 			continue
@@ -275,9 +415,15 @@ func UpdateGitImports(baseDir string, specs []string) (int, error) {
 			return updated, fmt.Errorf("import %q: %w", k, err)
 		}
 		rev := gitRev(entry.Dir)
-		if rev != "" && rev != entry.Rev {
+		hash := entry.Hash
+		if src, err := parseGitSpec(k); err == nil {
+			if h, err := importClosureHash(filepath.Join(entry.Dir, src.subPath, "Constfile")); err == nil {
+				hash = h
+			}
+		}
+		if rev != "" && (rev != entry.Rev || hash != entry.Hash) {
 			fmt.Printf("%s: %s -> %s\n", k, shortRev(entry.Rev), shortRev(rev))
-			entry.Rev = rev
+			entry.Rev, entry.Hash = rev, hash
 			lock.Imports[k] = entry
 			updated++
 		} else if rev != "" {
@@ -293,6 +439,82 @@ func UpdateGitImports(baseDir string, specs []string) (int, error) {
 	return updated, nil
 }
 
+// VerifyImports checks every pinned import against .construct.lock: a
+// vendored copy by content hash, otherwise the cached copy's commit (or
+// archive digest) and content hash, fetching a missing copy at its pinned
+// version first. It returns the number of imports that failed.
+func VerifyImports(baseDir string) (int, error) {
+	root := importRootDir(baseDir)
+	lockPath := importLockPath(root)
+	lock := loadImportLock(lockPath)
+	if len(lock.Imports) == 0 {
+		return 0, fmt.Errorf("no remote imports recorded in %s", filepath.Base(lockPath))
+	}
+
+	failed := 0
+	for _, k := range slices.Sorted(maps.Keys(lock.Imports)) {
+		entry := lock.Imports[k]
+		if file := filepath.Join(vendorDir(root, k), "Constfile"); dirExists(filepath.Dir(file)) {
+			// A vendored import is what builds use, and checking it must
+			// work where the remote cannot be reached.
+			if !verifyImportHash(k+" (vendored)", entry, file) {
+				failed++
+			}
+			continue
+		}
+		if entry.Kind == "url" {
+			if !verifyURLImport(root, k, entry) {
+				failed++
+			}
+			continue
+		}
+		src, err := parseGitSpec(k)
+		if err != nil {
+			fmt.Printf("%s: %v\n", k, err)
+			failed++
+			continue
+		}
+		dir := entry.Dir
+		if dir == "" {
+			dir = filepath.Join(root, CacheDirName(), "imports", shortHash(k))
+		}
+		if !dirExists(filepath.Join(dir, ".git")) {
+			if err := gitClone(entry.URL, entry.Rev, dir); err != nil {
+				fmt.Printf("%s: fetch failed: %v\n", k, err)
+				failed++
+				continue
+			}
+		}
+		if rev := gitRev(dir); rev != entry.Rev {
+			fmt.Printf("%s: checkout is at %s, lock pins %s\n", k, shortRev(rev), shortRev(entry.Rev))
+			failed++
+			continue
+		}
+		if !verifyImportHash(k, entry, filepath.Join(dir, src.subPath, "Constfile")) {
+			failed++
+		}
+	}
+	return failed, nil
+}
+
+// verifyImportHash prints one `import verify` line for a fetched import and
+// reports whether its content matches the lock.
+func verifyImportHash(key string, entry ImportLockEntry, file string) bool {
+	hash, err := importClosureHash(file)
+	switch {
+	case err != nil:
+		fmt.Printf("%s: %v\n", key, err)
+	case entry.Hash == "":
+		fmt.Printf("%s: no content hash recorded (run a build to record %s)\n", key, shortHashValue(hash))
+	case hash != entry.Hash:
+		fmt.Printf("%s: content %s does not match locked %s\n", key, shortHashValue(hash), shortHashValue(entry.Hash))
+	default:
+		fmt.Printf("%s: ok (%s)\n", key, shortRev(strings.TrimPrefix(entry.Rev, "sha256:")))
+		return true
+	}
+	return false
+}
+
 func gitDefaultBranch(url string) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
 	defer cancel()
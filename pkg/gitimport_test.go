@@ -26,6 +26,9 @@ func TestParseImportSpec(t *testing.T) {
 		{line: `import git "github.com/acme/recipes"`, path: "github.com/acme/recipes", isGit: true},
 		{line: `import git "github.com/acme/recipes@v1.2.0" as r if require("git")`, path: "github.com/acme/recipes@v1.2.0", ns: "r", cond: `require("git")`, isGit: true},
 		{line: `import "strange if path.constfile"`, path: "strange if path.constfile"},
+		{line: `import "lib.constfile" as lib with (go = "1.26", out = &dist) if exists("lib.constfile")`, path: "lib.constfile", ns: "lib", cond: `exists("lib.constfile")`},
+		{line: `import "lib.constfile" with (`, wantErr: true},
+		{line: `import "lib.constfile" with (= 1)`, wantErr: true},
 		{line: `import "x.constfile" on`, wantErr: true},
 		{line: `import "x.constfile" if`, wantErr: true},
 	}
@@ -226,3 +229,69 @@ func TestServiceParsing(t *testing.T) {
 		t.Errorf("port list should stay shell, got %+v", cmd.Body)
 	}
 }
+
+func TestGitImportLockHash(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	remote := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = remote
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	run("init", "-q")
+	os.WriteFile(filepath.Join(remote, "Constfile"), []byte("import \"lib.constfile\"\n\nremote-cmd {\n  $ echo hi\n}\n"), 0644)
+	os.WriteFile(filepath.Join(remote, "lib.constfile"), []byte("lib-cmd {\n  $ echo lib\n}\n"), 0644)
+	run("add", "-A")
+	run("config", "user.email", "t@t")
+	run("config", "user.name", "t")
+	run("commit", "-qm", "v1")
+
+	root := t.TempDir()
+	spec := "file://" + filepath.ToSlash(remote)
+	os.WriteFile(filepath.Join(root, "Constfile"), []byte("import git \""+spec+"\" as r\n"), 0644)
+	parse := func() error {
+		p, err := NewParser(filepath.Join(root, "Constfile"))
+		if err != nil {
+			return err
+		}
+		_, err = p.Parse()
+		return err
+	}
+	if err := parse(); err != nil {
+		t.Fatal(err)
+	}
+	entry := loadImportLock(importLockPath(root)).Imports[spec]
+	if !strings.HasPrefix(entry.Hash, "sha256:") {
+		t.Fatalf("lock entry has no content hash: %+v", entry)
+	}
+	if failed, err := VerifyImports(root); err != nil || failed != 0 {
+		t.Fatalf("verify of a clean checkout: failed=%d err=%v", failed, err)
+	}
+
+	// Editing a file the recipe imports must fail the next parse.
+	os.WriteFile(filepath.Join(entry.Dir, "lib.constfile"), []byte("lib-cmd {\n  $ rm -rf /\n}\n"), 0644)
+	if err := parse(); err == nil || !strings.Contains(err.Error(), "does not match .construct.lock") {
+		t.Fatalf("tampered checkout: err = %v", err)
+	}
+	if failed, _ := VerifyImports(root); failed != 1 {
+		t.Errorf("verify of a tampered checkout: failed = %d", failed)
+	}
+
+	// A missing checkout is refetched at the pinned commit under --frozen-lockfile,
+	// but an import that is not pinned is refused.
+	os.RemoveAll(entry.Dir)
+	SetFrozenLockfile(true)
+	defer SetFrozenLockfile(false)
+	if err := parse(); err != nil {
+		t.Fatalf("frozen refetch of a pinned import: %v", err)
+	}
+	os.WriteFile(filepath.Join(root, "Constfile"), []byte("import git \""+spec+"\" as r\nimport git \""+spec+"@master\" as m\n"), 0644)
+	if err := parse(); err == nil || !strings.Contains(err.Error(), "not pinned") {
+		t.Errorf("frozen lockfile accepted an unpinned import: %v", err)
+	}
+}
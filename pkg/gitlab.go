@@ -0,0 +1,235 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+type glPipeline struct {
+	ID        int64     `json:"id"`
+	Status    string    `json:"status"`
+	WebURL    string    `json:"web_url"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type glJob struct {
+	ID            int64  `json:"id"`
+	Name          string `json:"name"`
+	Status        string `json:"status"`
+	ArtifactsFile *struct {
+		Size int64 `json:"size"`
+	} `json:"artifacts_file"`
+}
+
+// GLClient runs construct as GitLab CI pipelines. Submitted targets and
+// arguments reach the pipeline as the CONSTRUCT_TARGETS and CONSTRUCT_ARGS
+// variables.
+type GLClient struct {
+	baseURL string
+	token   string
+	project string // group/project, URL-escaped into API paths
+	http    *http.Client
+}
+
+// NewGLClient talks to the API at apiBase, https://<host>/api/v4 by default.
+func NewGLClient(project, token, apiBase, host string) *GLClient {
+	if apiBase == "" {
+		if host == "" {
+			host = "gitlab.com"
+		}
+		apiBase = "https://" + host + "/api/v4"
+	}
+	return &GLClient{
+		baseURL: strings.TrimSuffix(apiBase, "/"),
+		token:   token,
+		project: project,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func GLToken() string {
+	if v := os.Getenv("CONSTRUCT_GITLAB_TOKEN"); v != "" {
+		return v
+	}
+	return os.Getenv("GITLAB_TOKEN")
+}
+
+func (c *GLClient) Name() string   { return "gitlab" }
+func (c *GLClient) LiveLogs() bool { return true }
+
+func (c *GLClient) projectPath() string {
+	return "/projects/" + url.PathEscape(c.project)
+}
+
+func (c *GLClient) request(ctx context.Context, method, path string, body any) (*http.Response, error) {
+	var rd io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		rd = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, rd)
+	if err != nil {
+		return nil, err
+	}
+	if c.token != "" {
+		req.Header.Set("PRIVATE-TOKEN", c.token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("gitlab api %s %s: %s (%s)", method, path, strings.TrimSpace(string(msg)), resp.Status)
+	}
+	return resp, nil
+}
+
+func (c *GLClient) do(ctx context.Context, method, path string, body any, out any) error {
+	resp, err := c.request(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("decode gitlab response %s %s (status %d): %w", method, path, resp.StatusCode, err)
+		}
+	}
+	return nil
+}
+
+// Dispatch creates a pipeline on ref; each input becomes a CONSTRUCT_<NAME>
+// variable.
+func (c *GLClient) Dispatch(ctx context.Context, ref string, inputs map[string]string) (CloudRun, error) {
+	vars := make([]map[string]string, 0, len(inputs))
+	for k, v := range inputs {
+		vars = append(vars, map[string]string{"key": "CONSTRUCT_" + strings.ToUpper(k), "value": v})
+	}
+	var p glPipeline
+	if err := c.do(ctx, "POST", c.projectPath()+"/pipeline", map[string]any{"ref": ref, "variables": vars}, &p); err != nil {
+		return CloudRun{}, err
+	}
+	return p.cloudRun(), nil
+}
+
+// LatestRun is unused for GitLab, whose Dispatch reports the pipeline.
+func (c *GLClient) LatestRun(ctx context.Context, since time.Time) (CloudRun, error) {
+	return CloudRun{}, errors.New("gitlab pipelines are reported when created")
+}
+
+func (c *GLClient) Run(ctx context.Context, runID int64) (CloudRun, error) {
+	var p glPipeline
+	err := c.do(ctx, "GET", fmt.Sprintf("%s/pipelines/%d", c.projectPath(), runID), nil, &p)
+	return p.cloudRun(), err
+}
+
+func (c *GLClient) jobs(ctx context.Context, runID int64) ([]glJob, error) {
+	var jobs []glJob
+	err := c.do(ctx, "GET", fmt.Sprintf("%s/pipelines/%d/jobs?per_page=100", c.projectPath(), runID), nil, &jobs)
+	return jobs, err
+}
+
+func (c *GLClient) Jobs(ctx context.Context, runID int64) ([]CloudJob, error) {
+	jobs, err := c.jobs(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]CloudJob, len(jobs))
+	for i, j := range jobs {
+		status, conclusion := glStatus(j.Status)
+		out[i] = CloudJob{ID: j.ID, Name: j.Name, Status: status, Conclusion: conclusion}
+	}
+	return out, nil
+}
+
+// JobLogs returns the job's trace, which grows while the job runs.
+func (c *GLClient) JobLogs(ctx context.Context, jobID int64) ([]byte, error) {
+	resp, err := c.request(ctx, "GET", fmt.Sprintf("%s/jobs/%d/trace", c.projectPath(), jobID), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(io.LimitReader(resp.Body, maxLogBytes))
+}
+
+// Artifacts lists the pipeline's jobs that kept an artifacts archive; each
+// artifact is named after, and identified by, its job.
+func (c *GLClient) Artifacts(ctx context.Context, runID int64) ([]CloudArtifact, error) {
+	jobs, err := c.jobs(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	var out []CloudArtifact
+	for _, j := range jobs {
+		if j.ArtifactsFile != nil {
+			out = append(out, CloudArtifact{ID: j.ID, Name: j.Name, Size: j.ArtifactsFile.Size})
+		}
+	}
+	return out, nil
+}
+
+func (c *GLClient) DownloadArtifact(ctx context.Context, artifactID int64, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s%s/jobs/%d/artifacts", c.baseURL, c.projectPath(), artifactID), nil)
+	if err != nil {
+		return err
+	}
+	if c.token != "" {
+		req.Header.Set("PRIVATE-TOKEN", c.token)
+	}
+	resp, err := artifactHTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("artifacts of job %d: %s", artifactID, resp.Status)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+func (c *GLClient) Cancel(ctx context.Context, runID int64) error {
+	return c.do(ctx, "POST", fmt.Sprintf("%s/pipelines/%d/cancel", c.projectPath(), runID), nil, nil)
+}
+
+func (p glPipeline) cloudRun() CloudRun {
+	status, conclusion := glStatus(p.Status)
+	return CloudRun{ID: p.ID, Status: status, Conclusion: conclusion, HTMLURL: p.WebURL, CreatedAt: p.CreatedAt}
+}
+
+// glStatus maps a GitLab pipeline or job status onto CloudRun's. A pipeline
+// stopped at a manual job is complete as far as construct can follow it.
+func glStatus(s string) (status, conclusion string) {
+	switch s {
+	case "success":
+		return "completed", "success"
+	case "failed":
+		return "completed", "failure"
+	case "canceled":
+		return "completed", "cancelled"
+	case "skipped":
+		return "completed", "skipped"
+	case "manual":
+		return "completed", "action_required"
+	case "running", "canceling":
+		return "in_progress", ""
+	}
+	return "queued", ""
+}
@@ -0,0 +1,188 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseGitRemote(t *testing.T) {
+	cases := map[string][2]string{
+		"https://gitlab.com/group/sub/proj.git":         {"gitlab.com", "group/sub/proj"},
+		"git@gitlab.example.com:group/proj.git":         {"gitlab.example.com", "group/proj"},
+		"ssh://git@gitlab.com/group/proj.git":           {"gitlab.com", "group/proj"},
+		"https://github.com/owner/repo":                 {"github.com", "owner/repo"},
+		"https://user:pw@git.corp.example/team/app.git": {"git.corp.example", "team/app"},
+	}
+	for in, want := range cases {
+		host, path, err := ParseGitRemote(in)
+		if err != nil {
+			t.Errorf("%q: %v", in, err)
+			continue
+		}
+		if host != want[0] || path != want[1] {
+			t.Errorf("%q = %q %q, want %q %q", in, host, path, want[0], want[1])
+		}
+	}
+	for _, bad := range []string{"", "not-a-remote", "https://gitlab.com/solo"} {
+		if _, _, err := ParseGitRemote(bad); err == nil {
+			t.Errorf("%q should fail", bad)
+		}
+	}
+}
+
+func TestBackendForHost(t *testing.T) {
+	for host, want := range map[string]string{
+		"github.com": "github", "GitLab.com": "gitlab", "gitlab.example.com": "gitlab",
+		"ci.gitlab.corp.net": "gitlab", "git.example.com": "",
+	} {
+		if got := BackendForHost(host); got != want {
+			t.Errorf("%q = %q, want %q", host, got, want)
+		}
+	}
+}
+
+// mockGitlab spins up a fake GitLab API server for the group/proj project.
+// Job 7's trace grows by a line on every read until the pipeline finishes.
+func mockGitlab(t *testing.T) (*GLClient, *map[string]any) {
+	t.Helper()
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	var triggered map[string]any
+	status := "running"
+	reads := 0
+
+	const project = "/api/v4/projects/group%2Fproj"
+	mux.HandleFunc("POST "+project+"/pipeline", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "test-token" {
+			w.WriteHeader(401)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&triggered)
+		w.WriteHeader(201)
+		json.NewEncoder(w).Encode(map[string]any{"id": 42, "status": "created", "web_url": srv.URL + "/group/proj/-/pipelines/42"})
+	})
+	mux.HandleFunc("GET "+project+"/pipelines/{id}", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"id": 42, "status": status, "web_url": srv.URL + "/group/proj/-/pipelines/42"})
+	})
+	mux.HandleFunc("GET "+project+"/pipelines/{id}/jobs", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]any{
+			{"id": 7, "name": "construct", "status": status, "artifacts_file": map[string]any{"filename": "artifacts.zip", "size": 100}},
+			{"id": 8, "name": "lint", "status": status},
+		})
+	})
+	mux.HandleFunc("GET "+project+"/jobs/{id}/trace", func(w http.ResponseWriter, r *http.Request) {
+		reads++
+		fmt.Fprint(w, "remote: building...\n")
+		if reads > 1 {
+			fmt.Fprint(w, "remote: token hunter2 leaked\n")
+			status = "success"
+		}
+	})
+	mux.HandleFunc("GET "+project+"/jobs/{id}/artifacts", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != "7" || r.Header.Get("PRIVATE-TOKEN") != "test-token" {
+			w.WriteHeader(404)
+			return
+		}
+		w.Write(zipArchive(t, map[string]string{".construct-cache/run-state.json": "{}"}))
+	})
+	mux.HandleFunc("POST "+project+"/pipelines/{id}/cancel", func(w http.ResponseWriter, r *http.Request) {
+		status = "canceled"
+		json.NewEncoder(w).Encode(map[string]any{"id": 42, "status": status})
+	})
+	t.Cleanup(srv.Close)
+	return NewGLClient("group/proj", "test-token", srv.URL+"/api/v4", ""), &triggered
+}
+
+func TestGLClientDispatchSendsVariables(t *testing.T) {
+	client, triggered := mockGitlab(t)
+	run, err := client.Dispatch(context.Background(), "main", map[string]string{"targets": "build test", "args": "-e X=1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.ID != 42 || run.Status != "queued" || !strings.HasSuffix(run.HTMLURL, "/pipelines/42") {
+		t.Fatalf("run = %+v", run)
+	}
+	if (*triggered)["ref"] != "main" {
+		t.Errorf("ref = %v", (*triggered)["ref"])
+	}
+	vars := map[string]string{}
+	for _, v := range (*triggered)["variables"].([]any) {
+		kv := v.(map[string]any)
+		vars[kv["key"].(string)] = kv["value"].(string)
+	}
+	if vars["CONSTRUCT_TARGETS"] != "build test" || vars["CONSTRUCT_ARGS"] != "-e X=1" {
+		t.Errorf("variables = %v", vars)
+	}
+
+	bad := NewGLClient("group/proj", "wrong", client.baseURL, "")
+	if _, err := bad.Dispatch(context.Background(), "main", nil); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("err = %v, want the 401 reported", err)
+	}
+}
+
+func TestGLClientRunJobsTraceAndCancel(t *testing.T) {
+	client, _ := mockGitlab(t)
+	ctx := context.Background()
+	run, err := client.Run(ctx, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != "in_progress" || run.Conclusion != "" {
+		t.Fatalf("run = %+v", run)
+	}
+	jobs, err := client.Jobs(ctx, 42)
+	if err != nil || len(jobs) != 2 || jobs[0].Name != "construct" || jobs[0].Status != "in_progress" {
+		t.Fatalf("jobs = %+v, %v", jobs, err)
+	}
+	first, err := client.JobLogs(ctx, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := client.JobLogs(ctx, 7)
+	if !strings.HasPrefix(string(second), string(first)) || len(second) <= len(first) {
+		t.Errorf("trace did not grow: %q then %q", first, second)
+	}
+	if run, _ := client.Run(ctx, 42); run.Status != "completed" || RunConclusionToExit(run.Conclusion) != 0 {
+		t.Errorf("finished run = %+v", run)
+	}
+	if err := client.Cancel(ctx, 42); err != nil {
+		t.Fatal(err)
+	}
+	if run, _ := client.Run(ctx, 42); run.Conclusion != "cancelled" {
+		t.Errorf("cancelled run = %+v", run)
+	}
+}
+
+func TestGLClientArtifactsAreJobArchives(t *testing.T) {
+	client, _ := mockGitlab(t)
+	into := t.TempDir()
+	dirs, err := DownloadCloudArtifacts(context.Background(), client, 42, into)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) != 1 || filepath.Base(dirs[0]) != "construct" {
+		t.Fatalf("dirs = %v, want only the job that kept artifacts", dirs)
+	}
+	if _, err := os.Stat(filepath.Join(dirs[0], ".construct-cache", "run-state.json")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGLStatus(t *testing.T) {
+	for in, want := range map[string][2]string{
+		"created": {"queued", ""}, "pending": {"queued", ""}, "running": {"in_progress", ""},
+		"success": {"completed", "success"}, "failed": {"completed", "failure"},
+		"canceled": {"completed", "cancelled"}, "skipped": {"completed", "skipped"},
+	} {
+		if s, c := glStatus(in); s != want[0] || c != want[1] {
+			t.Errorf("%q = %q %q, want %q %q", in, s, c, want[0], want[1])
+		}
+	}
+}
@@ -14,6 +14,23 @@ type importSpec struct {
 	ns    string
 	cond  string
 	isGit bool
+	isURL bool        // import url "https://.../recipes.tar.gz#sha256=..."
+	with  [][2]string // with (goversion = "1.26"): global name, raw value
+}
+
+// importOverride is one `with (name = value)` entry. Entries are keyed by the
+// name inside the imported file, which may reach into its own namespaced
+// imports (b.goversion); name is the global as the declaring file sees it.
+type importOverride struct {
+	key   string // as written in with (...)
+	name  string
+	value Value
+	refs  []string // &names in the raw value, which count as uses
+	used  bool
+
+	importPath string // as written, for lint messages
+	file       string
+	line       int
 }
 
 func lastIndexOutsideQuotes(s, marker string) int {
@@ -40,6 +57,32 @@ func parseImportSpec(line string) (importSpec, error) {
 	if rest, ok := strings.CutPrefix(spec, "git "); ok {
 		out.isGit = true
 		spec = strings.TrimSpace(rest)
+	} else if rest, ok := strings.CutPrefix(spec, "url "); ok {
+		out.isURL = true
+		spec = strings.TrimSpace(rest)
+	}
+
+	if w := findTopLevelKeyword(spec, " with ("); w >= 0 {
+		open := w + len(" with ")
+		end := matchingParen(spec, open)
+		if end < 0 {
+			return importSpec{}, fmt.Errorf("import with: missing closing parenthesis")
+		}
+		for _, part := range splitTopLevel(spec[open+1:end], ',') {
+			if part == "" {
+				continue
+			}
+			name, value, ok := strings.Cut(part, "=")
+			name = strings.TrimSpace(name)
+			if !ok || name == "" || strings.ContainsAny(name, " \t\"&@$()") {
+				return importSpec{}, fmt.Errorf("import with: expected name = value, got %q", part)
+			}
+			out.with = append(out.with, [2]string{name, strings.TrimSpace(value)})
+		}
+		if len(out.with) == 0 {
+			return importSpec{}, fmt.Errorf("import with: no overrides given")
+		}
+		spec = spec[:w] + spec[end+1:]
 	}
 
 	var condRaw, condKind string
@@ -97,7 +140,7 @@ func parseImportSpec(line string) (importSpec, error) {
 	return out, nil
 }
 
-func (p *Parser) processImport(line string) error {
+func (p *Parser) processImport(line string, lineNum int) error {
 	spec, err := parseImportSpec(line)
 	if err != nil {
 		return err
@@ -111,8 +154,12 @@ func (p *Parser) processImport(line string) error {
 	}
 
 	path := spec.path
-	if spec.isGit {
-		resolved, defaultNS, err := ensureGitImport(spec.path, importBaseDir(p.InputFile))
+	if spec.isGit || spec.isURL {
+		ensure := ensureGitImport
+		if spec.isURL {
+			ensure = ensureURLImport
+		}
+		resolved, defaultNS, err := ensure(spec.path, importBaseDir(p.InputFile))
 		if err != nil {
 			return err
 		}
@@ -136,6 +183,9 @@ func (p *Parser) processImport(line string) error {
 	}
 
 	if p.imported[dedupKey] {
+		if len(spec.with) > 0 {
+			return fmt.Errorf("import %q is already imported; its with (...) overrides would not apply", spec.path)
+		}
 		return nil
 	}
 
@@ -152,13 +202,31 @@ func (p *Parser) processImport(line string) error {
 		return fmt.Errorf("failed to read import %q: %w", spec.path, err)
 	}
 
+	overrides, own, err := p.importOverridesFor(spec, ns, lineNum)
+	if err != nil {
+		return err
+	}
+
 	imported := NewParserFromContent(cleanPath, string(content))
 	imported.importStack = p.importStack
 	imported.imported = p.imported
 	imported.ImportReader = p.ImportReader
+	imported.overrides = overrides
+	imported.Profile = p.Profile
 	if err := imported.parseLines(); err != nil {
 		return err
 	}
+	p.Data.importOverrides = append(p.Data.importOverrides, own...)
+	return p.mergeImported(imported, ns, cleanPath, fmt.Sprintf("import %q", spec.path))
+}
+
+// mergeImported moves a parsed child file's commands, globals and source
+// files into p, under ns when one is given. what names the child in
+// duplicate-command errors.
+func (p *Parser) mergeImported(imported *Parser, ns, cleanPath, what string) error {
+	p.Data.importOverrides = append(p.Data.importOverrides, imported.Data.importOverrides...)
+	p.Data.Profiles = append(p.Data.Profiles, imported.Data.Profiles...)
+	p.Data.lockedSecrets = append(p.Data.lockedSecrets, imported.Data.lockedSecrets...)
 
 	if ns != "" {
 		renameImportNamespace(imported.Data, ns)
@@ -166,7 +234,7 @@ func (p *Parser) processImport(line string) error {
 
 	for _, cmd := range imported.Data.Commands {
 		if existing, err := p.Data.GetCommand(cmd.Name); err == nil && existing != nil {
-			return fmt.Errorf("duplicate command %q from import %q", cmd.Name, spec.path)
+			return fmt.Errorf("duplicate command %q from %s", cmd.Name, what)
 		}
 	}
 
@@ -188,6 +256,66 @@ func (p *Parser) processImport(line string) error {
 	return nil
 }
 
+// importOverridesFor builds the override set an imported file sees: the
+// import's own `with (...)` entries, evaluated here in the importer (a &ref
+// must name a global already defined), plus any
+// overrides this file received for names under the import's namespace
+// (lib.goversion reaches goversion inside `import ... as lib`). An override
+// from further out wins over the importer's own. own lists the entries
+// declared on this import line.
+func (p *Parser) importOverridesFor(spec importSpec, ns string, lineNum int) (map[string]*importOverride, []*importOverride, error) {
+	overrides := map[string]*importOverride{}
+	var own []*importOverride
+	for _, w := range spec.with {
+		raw := w[1]
+		if len(raw) >= 2 && raw[0] == '"' && raw[len(raw)-1] == '"' && !strings.Contains(raw[1:len(raw)-1], `"`) {
+			raw = raw[1 : len(raw)-1] // a quoted literal, as in build(os="linux")
+		}
+		for _, ref := range VarRefNames(w[1]) {
+			first, _, _ := strings.Cut(ref, ".")
+			if _, err := p.Data.GetVariable(ref, "global"); err == nil {
+				continue
+			}
+			if _, err := p.Data.GetVariable(first, "global"); err == nil || strings.Contains(ref, "(") {
+				continue
+			}
+			return nil, nil, fmt.Errorf("import %q: with %s: &%s is not a global defined before the import", spec.path, w[0], ref)
+		}
+		scope := "global"
+		val, isList, list, err := p.evalVarValue(raw, nil, &scope, lineNum)
+		if err != nil {
+			return nil, nil, fmt.Errorf("import %q: with %s: %w", spec.path, w[0], err)
+		}
+		v := Value{S: val}
+		if isList {
+			v = ListValue(list)
+		}
+		name := w[0]
+		if ns != "" {
+			name = ns + "." + name
+		}
+		ov := &importOverride{key: w[0], name: name, value: v, refs: VarRefNames(w[1]), importPath: spec.path, file: p.InputFile, line: lineNum}
+		overrides[w[0]] = ov
+		own = append(own, ov)
+	}
+	for name, ov := range p.overrides {
+		if ns != "" {
+			var ok bool
+			if name, ok = strings.CutPrefix(name, ns+"."); !ok {
+				continue
+			}
+		}
+		if shadowed := overrides[name]; shadowed != nil {
+			shadowed.used = true // the outer override takes its place
+		}
+		overrides[name] = ov
+	}
+	if len(overrides) == 0 {
+		return nil, nil, nil
+	}
+	return overrides, own, nil
+}
+
 func importBaseDir(inputFile string) string {
 	u, err := url.Parse(inputFile)
 	if err == nil && u.Scheme == "file" {
@@ -219,6 +347,17 @@ func renameImportNamespace(data *ParsedData, ns string) {
 			v.Scope = n
 		}
 	}
+
+	// Overrides declared inside the import keep naming the global they set
+	// once it moves under the namespace (b.goversion -> lib.b.goversion).
+	for _, ov := range data.importOverrides {
+		ov.name = ns + "." + ov.name
+		for i, ref := range ov.refs {
+			if n, ok := globalNew[ref]; ok {
+				ov.refs[i] = n
+			}
+		}
+	}
 }
 
 func importRenameMaps(data *ParsedData, ns string) (commandNew, globalNew map[string]string) {
@@ -248,6 +387,9 @@ func commandShadowSets(data *ParsedData) map[string]map[string]bool {
 		for _, arg := range c.Arguments {
 			shadow[arg.Name] = true
 		}
+		for _, alias := range c.PrereqAliases {
+			shadow[alias] = true
+		}
 		if c.LazyEval != nil && c.LazyEval.Scope != "global" {
 			// Lazy bodies resolve in their scope command's context.
 			if scopeCmd, err := data.GetCommand(c.LazyEval.Scope); err == nil {
@@ -269,22 +411,15 @@ func commandShadowSets(data *ParsedData) map[string]map[string]bool {
 
 func renameCommandRefs(c *Command, commandNew, globalNew map[string]string, shadow map[string]bool) {
 	for i, prereq := range c.Prereqs {
-		if n, ok := commandNew[strings.TrimSpace(prereq)]; ok {
+		if n, ok := renamePrereq(strings.TrimSpace(prereq), commandNew); ok {
 			c.Prereqs[i] = n
 		}
 	}
 
-	if len(c.PrereqDirs) > 0 {
-		newDirs := make(map[string]string, len(c.PrereqDirs))
-		for prereq, dir := range c.PrereqDirs {
-			if n, ok := commandNew[prereq]; ok {
-				newDirs[n] = dir
-			} else {
-				newDirs[prereq] = dir
-			}
-		}
-		c.PrereqDirs = newDirs
-	}
+	c.PrereqDirs = renamePrereqKeys(c.PrereqDirs, commandNew)
+	c.PrereqAliases = renamePrereqKeys(c.PrereqAliases, commandNew)
+	c.PrereqConds = renamePrereqKeys(c.PrereqConds, commandNew)
+	c.PrereqOrderOnly = renamePrereqKeys(c.PrereqOrderOnly, commandNew)
 
 	if c.LazyEval != nil {
 		if c.LazyEval.Scope == "global" {
@@ -313,6 +448,39 @@ func renameCommandRefs(c *Command, commandNew, globalNew map[string]string, shad
 		return "", false
 	}
 	renameBodyRefs(c.Body, rename)
+	c.Guard = renameVarRefs(c.Guard, rename)
+	for prereq, cond := range c.PrereqConds {
+		c.PrereqConds[prereq] = renameVarRefs(cond, rename)
+	}
+}
+
+// renamePrereq maps a prerequisite to its namespaced name, including the
+// base of a parameterized call such as build(os=linux).
+func renamePrereq(prereq string, commandNew map[string]string) (string, bool) {
+	if n, ok := commandNew[prereq]; ok {
+		return n, true
+	}
+	if open := strings.IndexByte(prereq, '('); open > 0 {
+		if n, ok := commandNew[prereq[:open]]; ok {
+			return n + prereq[open:], true
+		}
+	}
+	return "", false
+}
+
+func renamePrereqKeys[V any](m map[string]V, commandNew map[string]string) map[string]V {
+	if len(m) == 0 {
+		return m
+	}
+	out := make(map[string]V, len(m))
+	for prereq, v := range m {
+		if n, ok := renamePrereq(prereq, commandNew); ok {
+			out[n] = v
+		} else {
+			out[prereq] = v
+		}
+	}
+	return out
 }
 
 func collectLoopVars(stmts []BodyStatement, out map[string]bool) {
@@ -331,7 +499,7 @@ func collectLoopVars(stmts []BodyStatement, out map[string]bool) {
 			for _, c := range stmt.Cases {
 				collectLoopVars(c.Body, out)
 			}
-		case StmtInDir, StmtLock:
+		case StmtInDir, StmtLock, StmtOnStop:
 			collectLoopVars(stmt.ThenBody, out)
 		}
 	}
@@ -362,7 +530,7 @@ func renameBodyRefs(stmts []BodyStatement, rename func(string) (string, bool)) {
 				}
 				renameBodyRefs(stmts[i].Cases[j].Body, rename)
 			}
-		case StmtInDir, StmtLock:
+		case StmtInDir, StmtLock, StmtOnStop:
 			stmts[i].Shell = renameVarRefs(stmts[i].Shell, rename)
 			renameBodyRefs(stmts[i].ThenBody, rename)
 		case StmtBuiltin:
@@ -390,3 +558,28 @@ func renameVarRefs(s string, rename func(string) (string, bool)) string {
 	}
 	return scanRefs(s, '&', isVarIdentRune, isVarIdentRune, rename, false)
 }
+
+// matchingParen returns the index of the ')' closing the '(' at open, skipping
+// quoted text, or -1.
+func matchingParen(s string, open int) int {
+	depth := 0
+	inQuote := false
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			inQuote = !inQuote
+		case '(':
+			if !inQuote {
+				depth++
+			}
+		case ')':
+			if !inQuote {
+				depth--
+				if depth == 0 {
+					return i
+				}
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,374 @@
+package pkg
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type justRecipe struct {
+	name    string
+	params  []string // construct argument specs
+	exports []string // $params, exported to the environment
+	deps    []string
+	body    []string
+	doc     []string
+	flags   []string
+}
+
+var justAssignRe = regexp.MustCompile(`^(export\s+)?([A-Za-z_][A-Za-z0-9_-]*)\s*:=\s*(.*)$`)
+
+var justAliasRe = regexp.MustCompile(`^alias\s+([A-Za-z_][A-Za-z0-9_-]*)\s*:=\s*([A-Za-z_][A-Za-z0-9_-]*)$`)
+
+var justInterpRe = regexp.MustCompile(`\{\{\s*([^{}]*?)\s*\}\}`)
+
+var justIdentRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// ImportJustfile converts justfile recipes to commands: parameters become
+// arguments (`+args`/`*args` variadic, `$param` also exported), and
+// dependencies become prerequisites. The first recipe is the default, as
+// in just; one named `default` becomes `_` itself.
+func ImportJustfile(content string) (ImportResult, error) {
+	var (
+		recipes []*justRecipe
+		aliases [][2]string
+		vars    []string
+		known   = map[string]bool{}
+		flags   strings.Builder
+		res     ImportResult
+		doc     []string
+		attrs   []string
+		cur     *justRecipe
+	)
+	flag := func(desc, orig string) {
+		res.Flagged++
+		flags.WriteString("# construct-import: ")
+		flags.WriteString(desc)
+		flags.WriteString("\n# ")
+		flags.WriteString(orig)
+		flags.WriteString("\n\n")
+	}
+
+	for _, raw := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		line := strings.TrimSpace(raw)
+		if cur != nil && line != "" && (raw[0] == ' ' || raw[0] == '\t') {
+			cur.body = append(cur.body, line)
+			continue
+		}
+		if line == "" {
+			doc = nil
+			continue
+		}
+		cur = nil
+		if strings.HasPrefix(line, "#") {
+			doc = append(doc, strings.TrimSpace(strings.TrimLeft(line, "# ")))
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			attrs = append(attrs, line)
+			continue
+		}
+		if m := justAliasRe.FindStringSubmatch(line); m != nil {
+			aliases = append(aliases, [2]string{m[1], m[2]})
+			continue
+		}
+		if m := justAssignRe.FindStringSubmatch(line); m != nil {
+			value, ok := justValue(m[3])
+			if !ok {
+				flag("variable "+m[2]+" needs manual translation", line)
+				continue
+			}
+			known[m[2]] = true
+			vars = append(vars, "var "+m[2]+" = "+value)
+			res.Variables++
+			if m[1] != "" {
+				flag("exported variable "+m[2]+": add env { "+m[2]+"=&"+m[2]+" } to the commands that need it", line)
+			}
+			continue
+		}
+		if f := strings.Fields(line); f[0] == "set" || f[0] == "import" || f[0] == "mod" {
+			flag("skipped "+f[0]+" directive", line)
+			continue
+		}
+		r, err := parseJustHeader(line)
+		if err != nil {
+			flag(err.Error(), line)
+			continue
+		}
+		r.doc = doc
+		for _, a := range attrs {
+			if d, ok := strings.CutPrefix(a, "[doc("); ok {
+				r.doc = []string{strings.Trim(strings.TrimSuffix(d, ")]"), `"'`)}
+				continue
+			}
+			r.flags = append(r.flags, "skipped attribute "+a)
+		}
+		doc, attrs = nil, nil
+		recipes = append(recipes, r)
+		cur = r
+	}
+	if len(recipes) == 0 {
+		return ImportResult{}, fmt.Errorf("no justfile recipes found")
+	}
+
+	mapping := map[string]string{}
+	taken := map[string]bool{}
+	for i, r := range recipes {
+		if i == 0 && r.name == "default" {
+			mapping[r.name], taken["_"] = "_", true
+			continue
+		}
+		mapping[r.name] = uniqueName(commandName(r.name, true), taken)
+	}
+
+	var out strings.Builder
+	out.WriteString("# Imported from a justfile by `construct import`. Review construct-import comments.\n\n")
+	for _, v := range vars {
+		out.WriteString(v)
+		out.WriteString("\n")
+	}
+	if len(vars) > 0 {
+		out.WriteString("\n")
+	}
+	for _, r := range recipes {
+		for _, d := range r.doc {
+			out.WriteString("# ")
+			out.WriteString(d)
+			out.WriteString("\n")
+		}
+		out.WriteString(mapping[r.name])
+		if len(r.params) > 0 {
+			out.WriteString(" (")
+			out.WriteString(strings.Join(r.params, ", "))
+			out.WriteString(")")
+		}
+		var deps []string
+		for _, d := range r.deps {
+			if m, ok := mapping[d]; ok {
+				deps = append(deps, m)
+			} else {
+				r.flags = append(r.flags, "dependency "+d+" has no recipe")
+			}
+		}
+		if len(deps) > 0 {
+			out.WriteString(" < ")
+			out.WriteString(strings.Join(deps, ", "))
+		}
+		out.WriteString(" {\n")
+		for _, f := range r.flags {
+			res.Flagged++
+			out.WriteString("    # construct-import: ")
+			out.WriteString(f)
+			out.WriteString("\n")
+		}
+		if len(r.exports) > 0 {
+			out.WriteString("    env { ")
+			out.WriteString(strings.Join(r.exports, ", "))
+			out.WriteString(" }\n")
+		}
+		scope := map[string]bool{}
+		for k := range known {
+			scope[k] = true
+		}
+		for _, p := range r.params {
+			p = strings.TrimPrefix(p, "opt ")
+			p, _, _ = strings.Cut(p, "=")
+			scope[strings.TrimSuffix(p, "...")] = true
+		}
+		shebang := len(r.body) > 0 && strings.HasPrefix(r.body[0], "#!")
+		if shebang {
+			res.Flagged++
+			out.WriteString("    # construct-import: shebang recipe needs manual translation\n")
+		}
+		for _, line := range r.body {
+			if shebang {
+				out.WriteString("    # ")
+				out.WriteString(line)
+				out.WriteString("\n")
+				continue
+			}
+			if strings.HasPrefix(line, "#") {
+				out.WriteString("    ")
+				out.WriteString(line)
+				out.WriteString("\n")
+				continue
+			}
+			tolerant := false
+			for len(line) > 0 && (line[0] == '@' || line[0] == '-') {
+				tolerant = tolerant || line[0] == '-'
+				line = line[1:]
+			}
+			line = strings.TrimSpace(line)
+			converted, ok := justInterpolate(line, scope)
+			if !ok {
+				res.Flagged++
+				out.WriteString("    # construct-import: needs manual translation\n")
+				out.WriteString("    # $ ")
+				out.WriteString(line)
+				out.WriteString("\n")
+				continue
+			}
+			if tolerant {
+				out.WriteString("    ! $ ")
+			} else {
+				out.WriteString("    $ ")
+			}
+			out.WriteString(converted)
+			out.WriteString("\n")
+		}
+		out.WriteString("}\n\n")
+		res.Commands++
+	}
+	for _, a := range aliases {
+		if target, ok := mapping[a[1]]; ok && !taken[a[0]] {
+			taken[a[0]] = true
+			fmt.Fprintf(&out, "# alias for %s\n%s < %s { }\n\n", target, a[0], target)
+			res.Commands++
+		} else {
+			flag("alias "+a[0]+" does not name a recipe", "alias "+a[0]+" := "+a[1])
+		}
+	}
+	if def := mapping[recipes[0].name]; def != "_" {
+		fmt.Fprintf(&out, "# Default goal\n_ < %s { }\n", def)
+		res.Commands++
+	}
+	if flags.Len() > 0 {
+		out.WriteString("\n# ---- flagged during import ----\n")
+		out.WriteString(flags.String())
+	}
+	res.Constfile = FormatConstfile(out.String())
+	return parseChecked(res)
+}
+
+// parseJustHeader reads `name param='default' +rest: dep1 dep2`.
+func parseJustHeader(line string) (*justRecipe, error) {
+	words, ok := justWords(line)
+	if !ok || len(words) == 0 {
+		return nil, fmt.Errorf("unrecognized line")
+	}
+	name := strings.TrimPrefix(words[0], "@")
+	if !justIdentRe.MatchString(strings.TrimSuffix(name, ":")) {
+		return nil, fmt.Errorf("unrecognized line")
+	}
+	r := &justRecipe{}
+	colon := -1
+	for i, w := range words {
+		if strings.HasSuffix(w, ":") && !strings.HasSuffix(w, ":=") {
+			words[i] = strings.TrimSuffix(w, ":")
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return nil, fmt.Errorf("unrecognized line")
+	}
+	r.name = strings.TrimPrefix(words[0], "@")
+	for _, p := range words[1 : colon+1] {
+		if p == "" {
+			continue
+		}
+		variadic := strings.HasPrefix(p, "+") || strings.HasPrefix(p, "*")
+		p = strings.TrimLeft(p, "+*")
+		export := strings.HasPrefix(p, "$")
+		p = strings.TrimPrefix(p, "$")
+		name, def, hasDefault := strings.Cut(p, "=")
+		def = strings.Trim(def, `"'`)
+		if strings.ContainsAny(def, ",() ") {
+			r.flags = append(r.flags, "default of parameter "+name+" needs manual translation: "+def)
+			def = ""
+		}
+		if export {
+			r.exports = append(r.exports, name+"=&"+name)
+		}
+		spec := name
+		switch {
+		case variadic:
+			spec += "..."
+			if hasDefault && def != "" {
+				spec += "=" + def
+			}
+		case hasDefault:
+			spec = "opt " + spec
+			if def != "" {
+				spec += "=" + def
+			}
+		}
+		r.params = append(r.params, spec)
+	}
+	deps := words[colon+1:]
+	for i := 0; i < len(deps); i++ {
+		d := deps[i]
+		switch {
+		case d == "&&":
+			r.flags = append(r.flags, "subsequent dependencies (after &&) skipped: "+strings.Join(deps[i+1:], " "))
+			i = len(deps)
+		case strings.HasPrefix(d, "("):
+			r.flags = append(r.flags, "dependency with arguments skipped: "+d)
+		default:
+			r.deps = append(r.deps, d)
+		}
+	}
+	return r, nil
+}
+
+// justWords splits a recipe header on spaces outside quotes and
+// parentheses.
+func justWords(line string) ([]string, bool) {
+	var words []string
+	var cur strings.Builder
+	var quote rune
+	depth := 0
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case (r == ' ' || r == '\t') && depth == 0:
+			if cur.Len() > 0 {
+				words = append(words, cur.String())
+				cur.Reset()
+			}
+			continue
+		}
+		cur.WriteRune(r)
+	}
+	if cur.Len() > 0 {
+		words = append(words, cur.String())
+	}
+	return words, quote == 0 && depth == 0
+}
+
+// justValue converts a quoted literal or a variable reference; other
+// expressions (backticks, functions, concatenation) do not convert.
+func justValue(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] && !strings.ContainsRune(s[1:len(s)-1], rune(s[0])) {
+		return s[1 : len(s)-1], true
+	}
+	if justIdentRe.MatchString(s) {
+		return "&" + s, true
+	}
+	return "", false
+}
+
+// justInterpolate rewrites {{name}} to &name for variables and parameters
+// in scope.
+func justInterpolate(line string, scope map[string]bool) (string, bool) {
+	ok := true
+	line = justInterpRe.ReplaceAllStringFunc(line, func(m string) string {
+		name := justInterpRe.FindStringSubmatch(m)[1]
+		if !scope[name] {
+			ok = false
+			return m
+		}
+		return "&" + name
+	})
+	return line, ok
+}
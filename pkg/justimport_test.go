@@ -0,0 +1,60 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestImportJustfile(t *testing.T) {
+	res, err := ImportJustfile(`set dotenv-load
+version := "1.2.0"
+alias b := build
+
+# Build the project
+build target="debug": gen
+    cargo build --profile {{target}}
+    @echo built {{ version }}
+
+gen:
+    -rm -rf gen
+
+test $CI="1" +pkgs: build
+    go test {{pkgs}}
+    echo {{uppercase(version)}}
+
+py:
+    #!/usr/bin/env python3
+    print("hi")
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Commands != 6 || res.Variables != 1 || res.Flagged != 3 {
+		t.Errorf("commands=%d vars=%d flagged=%d, want 6, 1, 3:\n%s", res.Commands, res.Variables, res.Flagged, res.Constfile)
+	}
+	for _, want := range []string{
+		"var version = 1.2.0",
+		"# Build the project\nbuild (opt target=debug) < gen {\n    $ cargo build --profile &target\n    $ echo built &version\n}",
+		"gen {\n    ! $ rm -rf gen\n}",
+		"test (opt CI=1, pkgs...) < build {\n    env { CI=&CI }\n    $ go test &pkgs\n",
+		"# $ echo {{uppercase(version)}}",
+		"# construct-import: shebang recipe needs manual translation",
+		"b < build { }",
+		"_ < build { }",
+		"# construct-import: skipped set directive\n# set dotenv-load",
+	} {
+		if !strings.Contains(res.Constfile, want) {
+			t.Errorf("missing %q:\n%s", want, res.Constfile)
+		}
+	}
+}
+
+func TestImportJustfileDefaultRecipe(t *testing.T) {
+	res, err := ImportJustfile("default: lint\n\nlint:\n    golangci-lint run\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(res.Constfile, "_ < lint {") || strings.Contains(res.Constfile, "default") {
+		t.Errorf("default recipe not mapped to _:\n%s", res.Constfile)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -55,6 +56,93 @@ func Lint(lines []string, data *ParsedData, baseDir string) []LintIssue {
 	issues = append(issues, lintStatementKeywordCommands(data)...)
 	issues = append(issues, lintUnknownVarRefs(lines, data)...)
 	issues = append(issues, lintSwitchAndOutputs(data)...)
+	issues = append(issues, lintImportOverrides(data)...)
+	issues = append(issues, lintProfileVars(data)...)
+	issues = append(issues, lintPlaintextSecrets(lines)...)
+	issues = append(issues, lintDevControlServiceNames(data)...)
+	return issues
+}
+
+// DevControlVerbs are the words `construct dev` reads as control commands
+// rather than services to start.
+var DevControlVerbs = []string{"status", "restart", "stop", "logs", "attach", "down"}
+
+// lintDevControlServiceNames flags services named like a dev control verb:
+// `construct dev logs` would never start them.
+func lintDevControlServiceNames(data *ParsedData) []LintIssue {
+	var issues []LintIssue
+	for _, cmd := range data.Commands {
+		if !cmd.IsService || !slices.Contains(DevControlVerbs, cmd.Name) {
+			continue
+		}
+		issues = append(issues, LintIssue{
+			File: cmd.SourceFile,
+			Line: max(cmd.SourceLine-1, 0), Col: 0, EndCol: len(cmd.Name),
+			Severity: LintWarning,
+			Message:  fmt.Sprintf("service `%s` shares its name with `construct dev %s`, so it can't be started by name; rename it", cmd.Name, cmd.Name),
+		})
+	}
+	return issues
+}
+
+// lintPlaintextSecrets flags `secret name = value` written as a literal: the
+// value is masked in output but still sits in the Constfile.
+func lintPlaintextSecrets(lines []string) []LintIssue {
+	var issues []LintIssue
+	for i, line := range lines {
+		rest, ok := strings.CutPrefix(stripInlineComment(line), "secret ")
+		if !ok {
+			continue
+		}
+		name, value, ok := strings.Cut(rest, "=")
+		value = strings.TrimSpace(value)
+		if !ok || value == "" || strings.ContainsAny(value, "@&(") {
+			continue
+		}
+		issues = append(issues, LintIssue{
+			Line: i, Col: 0, EndCol: len("secret"),
+			Severity: LintWarning,
+			Message:  fmt.Sprintf("secret %q is written in plain text; read it from @ENV, file(...), or %s", strings.TrimSpace(name), SecretsFileName),
+		})
+	}
+	return issues
+}
+
+// lintProfileVars flags profile entries that name no global declared in the
+// profile's file: selecting the profile would not change anything.
+func lintProfileVars(data *ParsedData) []LintIssue {
+	var issues []LintIssue
+	for _, prof := range data.Profiles {
+		for _, name := range prof.order {
+			if prof.overrides[name] {
+				continue
+			}
+			issues = append(issues, LintIssue{
+				File: prof.SourceFile,
+				Line: max(prof.lines[name]-1, 0), Col: 0, EndCol: 0,
+				Severity: LintWarning,
+				Message:  fmt.Sprintf("profile %s sets %q, which does not override any declared global", prof.Name, name),
+			})
+		}
+	}
+	return issues
+}
+
+// lintImportOverrides flags `with (name = ...)` entries that no global in the
+// import (or its nested imports) picked up; they would silently do nothing.
+func lintImportOverrides(data *ParsedData) []LintIssue {
+	var issues []LintIssue
+	for _, ov := range data.importOverrides {
+		if ov.used {
+			continue
+		}
+		issues = append(issues, LintIssue{
+			File: ov.file,
+			Line: max(ov.line-1, 0), Col: 0, EndCol: len("import"),
+			Severity: LintError,
+			Message:  fmt.Sprintf("import %q does not declare a global %q to override", ov.importPath, ov.key),
+		})
+	}
 	return issues
 }
 
@@ -98,7 +186,7 @@ func knownRefNames(data *ParsedData) map[string]bool {
 				for _, c := range stmt.Cases {
 					walk(c.Body)
 				}
-			case StmtInDir, StmtLock:
+			case StmtInDir, StmtLock, StmtOnStop:
 				walk(stmt.ThenBody)
 			}
 		}
@@ -107,6 +195,14 @@ func knownRefNames(data *ParsedData) map[string]bool {
 		for _, arg := range cmd.Arguments {
 			known[arg.Name] = true
 		}
+		for _, alias := range cmd.PrereqAliases {
+			known[alias] = true
+		}
+		if cmd.Matrix != nil {
+			for _, axis := range cmd.Matrix.Axes {
+				known[axis.Name] = true
+			}
+		}
 		walk(cmd.Body)
 	}
 	return known
@@ -198,7 +294,7 @@ func lintSwitchAndOutputs(data *ParsedData) []LintIssue {
 				walk(file, stmt.LoopBody)
 			case StmtOnFail:
 				walk(file, stmt.OnFailBody)
-			case StmtInDir, StmtLock:
+			case StmtInDir, StmtLock, StmtOnStop:
 				walk(file, stmt.ThenBody)
 			}
 		}
@@ -217,7 +313,7 @@ var statementKeywordNames = map[string]bool{
 	"global": true, "var": true, "state": true, "lock": true,
 	"continue": true, "break": true, "manual": true, "produces": true,
 	"container": true, "onchange": true, "import": true,
-	"service": true, "port": true,
+	"service": true, "port": true, "ready": true, "health": true, "stop": true, "on_stop": true,
 }
 
 func lintStatementKeywordCommands(data *ParsedData) []LintIssue {
@@ -338,7 +434,7 @@ func lintLoopControl(data *ParsedData) []LintIssue {
 				for _, c := range stmt.Cases {
 					walk(file, c.Body, inLoop, parallel)
 				}
-			case StmtInDir, StmtLock:
+			case StmtInDir, StmtLock, StmtOnStop:
 				walk(file, stmt.ThenBody, inLoop, parallel)
 			}
 		}
@@ -415,6 +511,9 @@ func lintRefRules(lines []string, data *ParsedData) []LintIssue {
 			if err != nil || cmd == nil {
 				continue
 			}
+			if suffix == "port" && cmd.IsService && cmd.Port != "" {
+				continue // construct dev sets &service.port
+			}
 
 			shells := ShellStatements(cmd.Body)
 			shellCount := len(shells)
@@ -486,11 +585,13 @@ func lintDuplicatePrereqs(lines []string, data *ParsedData) []LintIssue {
 			continue
 		}
 		segment := line[lt+1 : lt+brace]
+		if bar := orderOnlyIndex(segment); bar >= 0 {
+			segment = segment[:bar] + "," + segment[bar+1:] // `a | a` repeats too
+		}
 
 		seen := map[string]bool{}
 		searchPos := 0
-		for part := range strings.SplitSeq(segment, ",") {
-			part = strings.TrimSpace(part)
+		for _, part := range splitTopLevel(segment, ',') {
 			if part == "" {
 				continue
 			}
@@ -499,18 +600,25 @@ func lintDuplicatePrereqs(lines []string, data *ParsedData) []LintIssue {
 				continue
 			}
 			name := part
-			if inIdx := strings.Index(name, " in "); inIdx >= 0 {
+			if ifIdx := findTopLevelKeyword(name, " if "); ifIdx >= 0 {
+				name = strings.TrimSpace(name[:ifIdx])
+			}
+			if asIdx := findTopLevelKeyword(name, " as "); asIdx >= 0 {
+				name = strings.TrimSpace(name[:asIdx])
+			}
+			if inIdx := findTopLevelKeyword(name, " in "); inIdx >= 0 {
 				name = strings.TrimSpace(name[:inIdx])
 			}
 			if name == "" || strings.ContainsAny(name, "/\\") {
 				searchPos += len(part) + 1
 				continue
 			}
-			if _, err := data.GetCommand(name); err != nil {
+			key := canonicalRefName(name) // build(b=2, a=1) repeats build(a=1,b=2)
+			if _, err := data.GetCommand(key); err != nil {
 				searchPos += len(part) + 1
 				continue
 			}
-			if seen[name] {
+			if seen[key] {
 				absCol := strings.Index(line[searchPos:], name)
 				if absCol >= 0 {
 					absCol += searchPos
@@ -526,7 +634,7 @@ func lintDuplicatePrereqs(lines []string, data *ParsedData) []LintIssue {
 					Message:  fmt.Sprintf("duplicate prerequisite `%s`", name),
 				})
 			}
-			seen[name] = true
+			seen[key] = true
 			if idx := strings.Index(line[searchPos:], name); idx >= 0 {
 				searchPos += idx + len(name)
 			} else {
@@ -575,6 +683,27 @@ func lintUnusedGlobals(data *ParsedData) []LintIssue {
 				used[n] = true
 			}
 		}
+		if cmd.Matrix != nil {
+			for _, axis := range cmd.Matrix.Axes {
+				for _, v := range axis.Values {
+					if name, ok := strings.CutPrefix(v, "&"); ok {
+						used[name] = true
+					}
+				}
+			}
+		}
+	}
+	for _, ov := range data.importOverrides {
+		for _, n := range ov.refs {
+			used[n] = true
+		}
+	}
+	for _, prof := range data.Profiles {
+		for _, raw := range prof.Vars {
+			for _, n := range VarRefNames(raw) {
+				used[n] = true
+			}
+		}
 	}
 	var issues []LintIssue
 	for _, v := range data.Variables {
@@ -608,17 +737,18 @@ func lintUnreferencedCommands(data *ParsedData) []LintIssue {
 				for _, c := range stmt.Cases {
 					collectInvokes(c.Body)
 				}
-			case StmtInDir, StmtLock:
+			case StmtInDir, StmtLock, StmtOnStop:
 				collectInvokes(stmt.ThenBody)
 			}
 		}
 	}
-	for _, cmd := range data.Commands {
+	for _, cmd := range append(slices.Clone(data.Commands), data.Instances()...) {
 		if cmd.IsDefault {
 			referenced[cmd.Name] = true
 		}
 		for _, p := range cmd.Prereqs {
 			referenced[p] = true
+			referenced[PrereqBase(p)] = true
 		}
 		collectInvokes(cmd.Body)
 	}
@@ -627,6 +757,16 @@ func lintUnreferencedCommands(data *ParsedData) []LintIssue {
 		if cmd.Name == "_" || cmd.Manual || IsLazyName(cmd.Name) || referenced[cmd.Name] {
 			continue
 		}
+		if cmd.Private {
+			// Nothing outside the file can reach it, so it is dead code.
+			issues = append(issues, LintIssue{
+				File: cmd.SourceFile,
+				Line: max(cmd.SourceLine-1, 0), Col: 0, EndCol: 0,
+				Severity: LintWarning,
+				Message:  fmt.Sprintf("private command %q is never referenced in %s", cmd.Name, filepath.Base(cmd.SourceFile)),
+			})
+			continue
+		}
 		issues = append(issues, LintIssue{
 			File: cmd.SourceFile,
 			Line: max(cmd.SourceLine-1, 0), Col: 0, EndCol: 0,
@@ -654,7 +794,7 @@ func ShellStatements(body []BodyStatement) []BodyStatement {
 			for _, c := range stmt.Cases {
 				out = append(out, ShellStatements(c.Body)...)
 			}
-		case StmtInDir, StmtLock:
+		case StmtInDir, StmtLock, StmtOnStop:
 			out = append(out, ShellStatements(stmt.ThenBody)...)
 		}
 	}
@@ -689,7 +829,7 @@ func InvokeCaptureHint(body []BodyStatement, name string) (invoked string, line
 					return inv, ln, f
 				}
 			}
-		case StmtInDir, StmtLock:
+		case StmtInDir, StmtLock, StmtOnStop:
 			if inv, ln, f := InvokeCaptureHint(stmt.ThenBody, name); f {
 				return inv, ln, f
 			}
@@ -738,7 +878,7 @@ func HasNamedOutput(body []BodyStatement, name string) bool {
 					return true
 				}
 			}
-		case StmtInDir, StmtLock:
+		case StmtInDir, StmtLock, StmtOnStop:
 			if HasNamedOutput(stmt.ThenBody, name) {
 				return true
 			}
@@ -33,6 +33,15 @@ func TestLintUnknownNamedOutput(t *testing.T) {
 	}
 }
 
+func TestLintServicePortRef(t *testing.T) {
+	issues := lintText(t, "service api {\n  port auto\n  $ serve &api.port\n}\nservice web < api {\n  $ web --api :&api.port\n}\n")
+	for _, is := range issues {
+		if is.Severity == LintError {
+			t.Errorf("unexpected error: %s", is.Message)
+		}
+	}
+}
+
 func TestLintIndexOutOfBounds(t *testing.T) {
 	issues := lintText(t, "gen {\n  $ echo one\n}\nuse {\n  $ x &gen.5\n}\n")
 	found := false
@@ -421,3 +430,16 @@ func TestLintImportedCommandAttribution(t *testing.T) {
 		t.Errorf("unreferenced imported command not flagged: %v", issues)
 	}
 }
+
+func TestLintDevControlServiceNames(t *testing.T) {
+	issues := lintText(t, "service logs {\n  $ tail -f app.log\n}\nstatus {\n  $ git status\n}\nservice api {\n  $ serve\n}\n")
+	var msgs []string
+	for _, is := range issues {
+		if strings.Contains(is.Message, "construct dev") {
+			msgs = append(msgs, is.Message)
+		}
+	}
+	if len(msgs) != 1 || !strings.Contains(msgs[0], "service `logs`") {
+		t.Errorf("dev control name warnings = %v", msgs)
+	}
+}
@@ -13,6 +13,15 @@ type ImportResult struct {
 	Flagged   int
 }
 
+// parseChecked confirms an importer's output parses, so a conversion bug
+// surfaces at import time rather than on the first run.
+func parseChecked(res ImportResult) (ImportResult, error) {
+	if _, err := NewParserFromContent("Constfile", res.Constfile).Parse(); err != nil {
+		return ImportResult{}, fmt.Errorf("generated Constfile does not parse: %w", err)
+	}
+	return res, nil
+}
+
 type importItem struct {
 	kind  string // "var", "rule", "flag", "import"
 	rule  *makeRule
@@ -419,23 +428,35 @@ func (imp *makeImporter) emitRule(out, flags *strings.Builder, rule *makeRule, m
 			prereqs = append(prereqs, p)
 		}
 	}
-	if len(rule.orderOnly) > 0 {
+	// Order-only prerequisites must name commands; one without a rule
+	// (usually a directory Make expects to exist) has nothing to run.
+	var orderOnly, skipped []string
+	for _, p := range rule.orderOnly {
+		if m, ok := mapping[p]; ok {
+			orderOnly = append(orderOnly, m)
+		} else {
+			skipped = append(skipped, p)
+		}
+	}
+	if len(skipped) > 0 {
 		imp.flagged++
-		flags.WriteString("# construct-import: order-only prerequisites skipped: ")
-		flags.WriteString(strings.Join(rule.orderOnly, ", "))
+		flags.WriteString("# construct-import: order-only prerequisites without a rule skipped: ")
+		flags.WriteString(strings.Join(skipped, ", "))
 		flags.WriteString("\n\n")
 	}
 
-	var header string
-	switch {
-	case fileLike && len(prereqs) > 0:
-		header = name + " produces " + rule.name + " < " + strings.Join(prereqs, ", ")
-	case fileLike:
-		header = name + " produces " + rule.name
-	case len(prereqs) > 0:
-		header = name + " < " + strings.Join(prereqs, ", ")
-	default:
-		header = name
+	header := name
+	if fileLike {
+		header += " produces " + rule.name
+	}
+	if len(prereqs) > 0 {
+		header += " < " + strings.Join(prereqs, ", ")
+	}
+	if len(orderOnly) > 0 {
+		if len(prereqs) == 0 {
+			header += " <"
+		}
+		header += " | " + strings.Join(orderOnly, ", ")
 	}
 
 	if len(rule.recipe) == 0 {
@@ -535,15 +556,7 @@ func (imp *makeImporter) convertText(s string, auto *makeAutoVars) (string, bool
 
 func commandName(target string, phony bool) string {
 	if !phony && strings.ContainsAny(target, "./") {
-		var b strings.Builder
-		for _, r := range target {
-			if r == '_' || r == '-' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
-				b.WriteRune(r)
-			} else {
-				b.WriteRune('-')
-			}
-		}
-		target = b.String()
+		target = safeCommandName(target)
 	}
 	if target == "" {
 		target = "target"
@@ -551,7 +564,25 @@ func commandName(target string, phony bool) string {
 	return target
 }
 
+// safeCommandName replaces every character a command name cannot hold
+// (`build:prod`, `docs.serve`) with a dash.
+func safeCommandName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if r == '_' || r == '-' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('-')
+		}
+	}
+	return b.String()
+}
+
 func uniqueName(base string, taken map[string]bool) string {
+	// Statement keywords (`env`, `stop`, `default`) cannot name a command.
+	if statementKeywordNames[base] {
+		base += "-cmd"
+	}
 	name := base
 	for i := 2; taken[name]; i++ {
 		name = fmt.Sprintf("%s-%d", base, i)
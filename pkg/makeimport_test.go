@@ -222,14 +222,29 @@ func TestImportDoubleColonAndOrderOnly(t *testing.T) {
 	if !strings.Contains(res.Constfile, "double-colon rule treated as a normal rule") {
 		t.Errorf(":: not flagged:\n%s", res.Constfile)
 	}
-	if !strings.Contains(res.Constfile, "order-only prerequisites skipped: order") {
-		t.Errorf("order-only not flagged:\n%s", res.Constfile)
+	if !strings.Contains(res.Constfile, "order-only prerequisites without a rule skipped: order") {
+		t.Errorf("order-only without a rule not flagged:\n%s", res.Constfile)
 	}
 	if !strings.Contains(res.Constfile, "build < main.c {") {
 		t.Errorf("normal prereq lost:\n%s", res.Constfile)
 	}
 }
 
+func TestImportOrderOnlyConverted(t *testing.T) {
+	res := importOK(t, `app: main.c | dirs
+	gcc -o out/app main.c
+
+dirs:
+	mkdir -p out
+`)
+	if !strings.Contains(res.Constfile, "app < main.c | dirs {") {
+		t.Errorf("order-only prereq not converted:\n%s", res.Constfile)
+	}
+	if strings.Contains(res.Constfile, "order-only") {
+		t.Errorf("converted order-only prereq still flagged:\n%s", res.Constfile)
+	}
+}
+
 func TestImportMultipleTargets(t *testing.T) {
 	res := importOK(t, `a.out b.out: src.c
 	cp src.c a.out
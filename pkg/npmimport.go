@@ -0,0 +1,106 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ImportPackageJSON converts package.json scripts to commands. npm's
+// pre<name> hook becomes a prerequisite of <name>, and <name> ends by
+// invoking post<name>, so running <name> runs both hooks as `npm run` does
+// while post<name> on its own runs only itself. Like npm, every script
+// finds node_modules/.bin on PATH.
+func ImportPackageJSON(content string) (ImportResult, error) {
+	var manifest struct {
+		Scripts json.RawMessage `json:"scripts"`
+	}
+	if err := json.Unmarshal([]byte(content), &manifest); err != nil {
+		return ImportResult{}, err
+	}
+	names, scripts, err := orderedStrings(manifest.Scripts)
+	if err != nil {
+		return ImportResult{}, fmt.Errorf("scripts: %w", err)
+	}
+	if len(names) == 0 {
+		return ImportResult{}, fmt.Errorf("no package.json scripts found")
+	}
+
+	mapping := map[string]string{}
+	taken := map[string]bool{}
+	for _, n := range names {
+		mapping[n] = uniqueName(commandName(safeCommandName(n), true), taken)
+	}
+
+	var out strings.Builder
+	out.WriteString("# Imported from package.json by `construct import`. Review construct-import comments.\n\n")
+	res := ImportResult{Commands: len(names)}
+	for _, n := range names {
+		var prereqs []string
+		if _, ok := scripts["pre"+n]; ok {
+			prereqs = append(prereqs, mapping["pre"+n])
+		}
+		if base, ok := strings.CutPrefix(n, "post"); ok && scripts[base] != "" {
+			fmt.Fprintf(&out, "# npm post hook: invoked at the end of %s\n", mapping[base])
+		}
+		out.WriteString(mapping[n])
+		if len(prereqs) > 0 {
+			out.WriteString(" < ")
+			out.WriteString(strings.Join(prereqs, ", "))
+		}
+		out.WriteString(" {\n    env { PATH=node_modules/.bin:@PATH }\n")
+		script := strings.TrimSpace(scripts[n])
+		// npm exports package.json fields as $npm_package_* and config
+		// as $npm_config_*; construct has neither.
+		if strings.Contains(script, "npm_package_") || strings.Contains(script, "npm_config_") || strings.Contains(script, "\n") {
+			res.Flagged++
+			out.WriteString("    # construct-import: needs manual translation\n")
+			for _, line := range strings.Split(script, "\n") {
+				out.WriteString("    # $ ")
+				out.WriteString(line)
+				out.WriteString("\n")
+			}
+		} else if script != "" {
+			out.WriteString("    $ ")
+			out.WriteString(script)
+			out.WriteString("\n")
+		}
+		if _, ok := scripts["post"+n]; ok {
+			out.WriteString("    invoke ")
+			out.WriteString(mapping["post"+n])
+			out.WriteString("\n")
+		}
+		out.WriteString("}\n\n")
+	}
+	res.Constfile = FormatConstfile(out.String())
+	return parseChecked(res)
+}
+
+// orderedStrings decodes a JSON object of strings, keeping its key order.
+func orderedStrings(raw json.RawMessage) ([]string, map[string]string, error) {
+	values := map[string]string{}
+	if len(raw) == 0 {
+		return nil, values, nil
+	}
+	dec := json.NewDecoder(strings.NewReader(string(raw)))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, nil, fmt.Errorf("expected an object")
+	}
+	var keys []string
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		key, _ := tok.(string)
+		var value string
+		if err := dec.Decode(&value); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", key, err)
+		}
+		if _, dup := values[key]; !dup {
+			keys = append(keys, key)
+		}
+		values[key] = value
+	}
+	return keys, values, nil
+}
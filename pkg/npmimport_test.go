@@ -0,0 +1,82 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImportPackageJSON(t *testing.T) {
+	res, err := ImportPackageJSON(`{
+  "name": "app",
+  "scripts": {
+    "prebuild": "rimraf dist",
+    "build": "tsc -p .",
+    "postbuild": "cp package.json dist/",
+    "build:watch": "tsc -w",
+    "stop": "pkill node",
+    "version": "echo $npm_package_version"
+  }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Commands != 6 || res.Flagged != 1 {
+		t.Errorf("commands=%d flagged=%d, want 6 and 1:\n%s", res.Commands, res.Flagged, res.Constfile)
+	}
+	for _, want := range []string{
+		"prebuild {\n    env { PATH=node_modules/.bin:@PATH }\n    $ rimraf dist\n}",
+		"build < prebuild {",
+		"    $ tsc -p .\n    invoke postbuild\n}",
+		"# npm post hook: invoked at the end of build\npostbuild {",
+		"build-watch {",
+		"stop-cmd {",
+		"    # construct-import: needs manual translation\n    # $ echo $npm_package_version",
+	} {
+		if !strings.Contains(res.Constfile, want) {
+			t.Errorf("missing %q:\n%s", want, res.Constfile)
+		}
+	}
+	// Scripts keep their package.json order.
+	if strings.Index(res.Constfile, "prebuild {") > strings.Index(res.Constfile, "build-watch {") {
+		t.Errorf("scripts reordered:\n%s", res.Constfile)
+	}
+
+	if _, err := ImportPackageJSON(`{"name": "lib"}`); err == nil {
+		t.Error("package.json without scripts imported without error")
+	}
+}
+
+func TestImportPackageJSONRunsHooks(t *testing.T) {
+	res, err := ImportPackageJSON(`{"scripts": {
+    "prebuild": "echo pre >> order.txt",
+    "build": "echo build >> order.txt",
+    "postbuild": "echo post >> order.txt"
+  }}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := NewParserFromContent("Constfile", res.Constfile).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	run := func(target string) string {
+		t.Helper()
+		os.Remove(filepath.Join(dir, "order.txt"))
+		e := NewExecutor(data, false, false)
+		e.SetBaseDir(dir)
+		if err := e.Execute([]string{target}); err != nil {
+			t.Fatalf("%s: %v\n%s", target, err, res.Constfile)
+		}
+		out, _ := os.ReadFile(filepath.Join(dir, "order.txt"))
+		return string(out)
+	}
+	if got := run("build"); got != "pre\nbuild\npost\n" {
+		t.Errorf("build ran %q, want pre, build, post", got)
+	}
+	if got := run("postbuild"); got != "post\n" {
+		t.Errorf("postbuild ran %q, want only itself", got)
+	}
+}
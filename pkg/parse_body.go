@@ -179,15 +179,58 @@ func (p *Parser) parseBodyStatements(raw []rawLine, scope string) ([]BodyStateme
 			continue
 		}
 
+		// `port 8080`, `port auto`, or `port 8080:80` (published from a container).
 		if strings.HasPrefix(line, "port ") {
 			rest := strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "port")), `"`)
-			if n, err := strconv.Atoi(rest); err == nil && n >= 1 && n <= 65535 {
+			host, target, mapped := strings.Cut(rest, ":")
+			if rest == PortAuto || validPort(host) && (!mapped || validPort(target)) {
 				stmts = append(stmts, BodyStatement{Type: StmtPort, Shell: rest, SourceLine: lineNum})
 				i++
 				continue
 			}
 		}
 
+		// `ready http "URL"` / `health<5s, 3> $ cmd`: service probes for construct dev.
+		if kw, _, _ := strings.Cut(firstWord(line), "<"); kw == StmtReady || kw == StmtHealth {
+			stmt, ok, err := parseProbe(kw, strings.TrimPrefix(line, kw))
+			if err != nil {
+				return nil, NewParseError(p.InputFile, lineNum, 1, err.Error(), line)
+			}
+			if ok {
+				stmt.SourceLine = lineNum
+				stmts = append(stmts, *stmt)
+				i++
+				continue
+			}
+		}
+
+		// `stop SIGINT after 30s`: how construct dev stops a service.
+		if firstWord(line) == StmtStop {
+			stmt, ok, err := parseStop(strings.TrimPrefix(line, StmtStop))
+			if err != nil {
+				return nil, NewParseError(p.InputFile, lineNum, 1, err.Error(), line)
+			}
+			if ok && !p.inService {
+				return nil, NewParseError(p.InputFile, lineNum, 1, "stop is only allowed in a service body (prefix with $ to run a program named stop)", line)
+			}
+			if ok {
+				stmt.SourceLine = lineNum
+				stmts = append(stmts, *stmt)
+				i++
+				continue
+			}
+		}
+
+		if strings.HasPrefix(line, "on_stop ") || line == "on_stop{" {
+			stmt, consumed, err := p.parseHandlerBlock(raw[i:], scope, StmtOnStop)
+			if err != nil {
+				return nil, err
+			}
+			stmts = append(stmts, stmt)
+			i += consumed
+			continue
+		}
+
 		// `retry<N> $ cmd` / `retry<N, 2s> $ cmd` rerun a statement up to N extra times.
 		if strings.HasPrefix(line, "retry ") || strings.HasPrefix(line, "retry\t") {
 			return nil, NewParseError(p.InputFile, lineNum, 1, "the positional retry form was removed — use retry<3> $ cmd, or retry<3, 2s> to back off between attempts (prefix with $ to run a shell command)", line)
@@ -216,7 +259,7 @@ func (p *Parser) parseBodyStatements(raw []rawLine, scope string) ([]BodyStateme
 		}
 
 		if strings.HasPrefix(line, "onfail ") || line == "onfail{" {
-			stmt, consumed, err := p.parseOnFailBlock(raw[i:], scope)
+			stmt, consumed, err := p.parseHandlerBlock(raw[i:], scope, StmtOnFail)
 			if err != nil {
 				return nil, err
 			}
@@ -765,28 +808,36 @@ func (p *Parser) parseEnvBlock(raw []rawLine) (BodyStatement, int, error) {
 	return stmt, endIdx, nil
 }
 
-func (p *Parser) parseOnFailBlock(raw []rawLine, scope string) (BodyStatement, int, error) {
+// parseHandlerBlock parses an `onfail { ... }` or `on_stop { ... }` block.
+func (p *Parser) parseHandlerBlock(raw []rawLine, scope, keyword string) (BodyStatement, int, error) {
 	headerLine := raw[0]
-	stmt := BodyStatement{Type: StmtOnFail, SourceLine: headerLine.num}
+	stmt := BodyStatement{Type: keyword, SourceLine: headerLine.num}
+	setBody := func(body []BodyStatement) {
+		if keyword == StmtOnFail {
+			stmt.OnFailBody = body
+		} else {
+			stmt.ThenBody = body
+		}
+	}
 
 	if body, ok := singleLineBody(headerLine.text); ok {
 		bodyStmts, err := p.parseBodyStatements(atLine(splitStatements(body), headerLine.num), scope)
 		if err != nil {
 			return BodyStatement{}, 0, err
 		}
-		stmt.OnFailBody = bodyStmts
+		setBody(bodyStmts)
 		return stmt, 1, nil
 	}
 
 	lines, endIdx, err := collectBodyLines(raw, 1)
 	if err != nil {
-		return BodyStatement{}, 0, fmt.Errorf("unclosed onfail block (missing '}')")
+		return BodyStatement{}, 0, fmt.Errorf("unclosed %s block (missing '}')", keyword)
 	}
 	bodyStmts, err := p.parseBodyStatements(lines, scope)
 	if err != nil {
 		return BodyStatement{}, 0, err
 	}
-	stmt.OnFailBody = bodyStmts
+	setBody(bodyStmts)
 	return stmt, endIdx, nil
 }
 
@@ -1264,7 +1315,7 @@ func collectBodyLines(raw []rawLine, start int) ([]rawLine, int, error) {
 
 func isNestedBlockHeader(t string) bool {
 	return (strings.HasPrefix(t, "if ") || strings.HasPrefix(t, "for ") || strings.HasPrefix(t, "matrix ") ||
-		strings.HasPrefix(t, "env ") || strings.HasPrefix(t, "onfail ") ||
+		strings.HasPrefix(t, "env ") || strings.HasPrefix(t, "onfail ") || strings.HasPrefix(t, "on_stop ") ||
 		strings.HasPrefix(t, "switch ") || strings.HasPrefix(t, "switch<") || strings.HasPrefix(t, "case ") ||
 		strings.HasPrefix(t, "default") || strings.HasPrefix(t, "in ") ||
 		strings.HasPrefix(t, "lock ") || strings.HasPrefix(t, "lock<") || strings.HasPrefix(t, "case{") ||
@@ -1294,3 +1345,8 @@ func extractIfCondition(line string) string {
 	}
 	return line
 }
+
+func validPort(s string) bool {
+	n, err := strconv.Atoi(s)
+	return err == nil && n >= 1 && n <= 65535
+}
@@ -18,6 +18,11 @@ func ParseCommandName(line string) string {
 	}
 
 	inIdx := strings.Index(line, " in ")
+	for _, kw := range []string{" matrix ", " if "} {
+		if kwIdx := findTopLevelKeyword(line, kw); kwIdx >= 0 && (inIdx < 0 || kwIdx < inIdx) {
+			inIdx = kwIdx // `matrix os in ...` / `if x in ...` is not a work dir
+		}
+	}
 	prodIdx := findProducesIdx(line)
 	ocIdx := findTopLevelKeyword(line, " onchange ")
 	timeoutIdx := findTopLevelKeyword(line, " timeout<")
@@ -170,6 +175,9 @@ func oldHeaderTimeout(line string) (string, bool) {
 }
 
 func extractArgumentString(line string) string {
+	if lt := ltIndex(line); lt >= 0 {
+		line = line[:lt] // prerequisite calls carry their own parens
+	}
 	start := strings.Index(line, "(")
 	if start == -1 {
 		return ""
@@ -183,15 +191,26 @@ func extractArgumentString(line string) string {
 	return strings.TrimSpace(line[start+1 : start+end])
 }
 
-func extractPrerequisites(line string) ([]string, map[string]string, error) {
+// headerPrereqs is the parsed `< ...` list of a command header. The maps are
+// keyed by prerequisite name and nil when no entry uses the modifier.
+type headerPrereqs struct {
+	names     []string
+	dirs      map[string]string // lint in tools
+	aliases   map[string]string // build(os=linux) as bl
+	conds     map[string]string // lint if "@CI" == "true"
+	orderOnly map[string]bool   // build < gen | mkdirs
+}
+
+func extractPrerequisites(line string) (headerPrereqs, error) {
+	var h headerPrereqs
 	start := ltIndex(line)
 	if start == -1 {
-		return nil, nil, nil
+		return h, nil
 	}
 
 	end := strings.Index(line[start:], "{")
 	if end == -1 {
-		return nil, nil, nil
+		return h, nil
 	}
 
 	segment := line[start+1 : start+end]
@@ -199,34 +218,186 @@ func extractPrerequisites(line string) ([]string, map[string]string, error) {
 		segment = segment[:oc]
 	}
 
-	dirs := make(map[string]string)
-	var result []string
-	for part := range strings.SplitSeq(segment, ",") {
-		part = strings.TrimSpace(part)
+	set := func(m *map[string]string, k, v string) {
+		if *m == nil {
+			*m = make(map[string]string)
+		}
+		(*m)[k] = v
+	}
+	parts := splitTopLevel(segment, ',')
+	ordered := len(parts) // parts from here on follow the `|`
+	if bar := orderOnlyIndex(segment); bar >= 0 {
+		parts = splitTopLevel(segment[:bar], ',')
+		ordered = len(parts)
+		parts = append(parts, splitTopLevel(segment[bar+1:], ',')...)
+	}
+	for i, part := range parts {
 		if part == "" || part == "in" {
 			continue
 		}
 		if strings.HasPrefix(part, "in ") {
 			continue
 		}
+		cond := ""
+		if ifIdx := findTopLevelKeyword(part, " if "); ifIdx >= 0 {
+			cond = strings.TrimSpace(part[ifIdx+len(" if "):])
+			part = strings.TrimSpace(part[:ifIdx])
+			if cond == "" {
+				return h, fmt.Errorf("prerequisite %q has an empty if condition", part)
+			}
+		}
+		alias := ""
+		if asIdx := findTopLevelKeyword(part, " as "); asIdx >= 0 {
+			alias = strings.TrimSpace(part[asIdx+len(" as "):])
+			part = strings.TrimSpace(part[:asIdx])
+			if !isValidIdent(alias) {
+				return h, fmt.Errorf("invalid prerequisite alias %q (expected an identifier)", alias)
+			}
+		}
 		dir := ""
-		if inIdx := strings.Index(part, " in "); inIdx >= 0 {
+		if inIdx := findTopLevelKeyword(part, " in "); inIdx >= 0 {
 			dir = strings.TrimSpace(part[inIdx+len(" in "):])
 			part = strings.TrimSpace(part[:inIdx])
 		}
 		if part == "" {
 			continue
 		}
-		result = append(result, part)
+		if base, args, ok, err := parsePrereqCall(part); err != nil {
+			return h, err
+		} else if ok {
+			part = prereqCallName(base, args)
+		}
+		h.names = append(h.names, part)
 		if dir != "" {
-			dirs[part] = dir
+			set(&h.dirs, part, dir)
+		}
+		if alias != "" {
+			set(&h.aliases, part, alias)
+		}
+		if cond != "" {
+			set(&h.conds, part, cond)
+		}
+		if i >= ordered {
+			if h.orderOnly == nil {
+				h.orderOnly = make(map[string]bool)
+			}
+			h.orderOnly[part] = true
+		}
+	}
+	return h, nil
+}
+
+// orderOnlyIndex returns the top-level `|` that starts the order-only
+// prerequisites, skipping `||` inside an `if` condition.
+func orderOnlyIndex(segment string) int {
+	for off := 0; off < len(segment); {
+		i := findTopLevelKeyword(segment[off:], "|")
+		if i < 0 {
+			return -1
+		}
+		i += off
+		if strings.HasPrefix(segment[i:], "||") {
+			off = i + 2
+			continue
 		}
+		return i
 	}
+	return -1
+}
 
-	if len(dirs) == 0 {
-		dirs = nil
+// extractGuard cuts a whole-command condition out of the header:
+//
+//	build if os("linux") < gen {
+//
+// The guard runs from the top-level " if " to the prerequisites or the body.
+func extractGuard(line string) (string, string) {
+	head := len(line)
+	if brace := strings.IndexByte(line, '{'); brace >= 0 {
+		head = brace
+	}
+	if lt := ltIndex(line[:head]); lt >= 0 {
+		head = lt
+	}
+	start := findTopLevelKeyword(line[:head], " if ")
+	if start < 0 {
+		return "", line
+	}
+	cond := strings.TrimSpace(line[start+len(" if ") : head])
+	return cond, line[:start] + " " + line[head:]
+}
+
+// PrereqBase returns the command a prerequisite names: build for
+// build(os=linux), the name itself otherwise.
+func PrereqBase(prereq string) string {
+	if open := strings.IndexByte(prereq, '('); open > 0 {
+		return prereq[:open]
 	}
-	return result, dirs, nil
+	return prereq
+}
+
+// parsePrereqCall splits a parameterized prerequisite such as
+// build(os=linux, arch=arm64) into its command name and bound arguments.
+// ok is false for plain names and file deps.
+func parsePrereqCall(s string) (base string, args [][2]string, ok bool, err error) {
+	s = strings.TrimSpace(s)
+	open := strings.IndexByte(s, '(')
+	if open <= 0 || !strings.HasSuffix(s, ")") || strings.ContainsAny(s[:open], " \t/*?\\\"'&@$<>{}") {
+		return "", nil, false, nil
+	}
+	base = s[:open]
+	seen := make(map[string]bool)
+	for _, pair := range splitTopLevel(s[open+1:len(s)-1], ',') {
+		if pair == "" {
+			continue
+		}
+		k, v, found := strings.Cut(pair, "=")
+		k = strings.TrimSpace(k)
+		if !found || !isValidIdent(k) {
+			return "", nil, false, fmt.Errorf("invalid prerequisite argument %q in %s (expected name=value)", pair, s)
+		}
+		if seen[k] {
+			return "", nil, false, fmt.Errorf("duplicate argument '%s' in %s", k, s)
+		}
+		seen[k] = true
+		args = append(args, [2]string{k, trimQuoted(strings.TrimSpace(v))})
+	}
+	if len(args) == 0 {
+		return "", nil, false, fmt.Errorf("prerequisite %s binds no arguments (write %s)", s, base)
+	}
+	return base, args, true, nil
+}
+
+// prereqCallName is the canonical node name for a parameterized call:
+// arguments sorted by name, so build(a=1, b=2) and build(b=2,a=1) share a node.
+func prereqCallName(base string, args [][2]string) string {
+	pairs := make([]string, len(args))
+	for i, kv := range args {
+		v := kv[1]
+		if v == "" || strings.ContainsAny(v, ",()= \t") {
+			v = `"` + v + `"`
+		}
+		pairs[i] = kv[0] + "=" + v
+	}
+	slices.Sort(pairs)
+	return base + "(" + strings.Join(pairs, ",") + ")"
+}
+
+// canonicalRefName normalizes the call part of a reference like
+// &build(os=linux, arch=arm64).0 to the node name outputs are seeded under.
+func canonicalRefName(name string) string {
+	open := strings.IndexByte(name, '(')
+	if open <= 0 {
+		return name
+	}
+	close := strings.IndexByte(name, ')')
+	if close < open {
+		return name
+	}
+	base, args, ok, err := parsePrereqCall(name[:close+1])
+	if !ok || err != nil {
+		return name
+	}
+	return prereqCallName(base, args) + name[close+1:]
 }
 
 func extractWorkDir(line string) string {
@@ -298,6 +469,8 @@ func parseArgumentList(argStr string) ([]*Argument, error) {
 		}
 
 		argName, isOptional, defaultVal := parseArgumentName(part)
+		// name... collects the remaining words (--cmd:name repeated, or `--`).
+		argName, variadic := strings.CutSuffix(argName, "...")
 		if argName == "" {
 			return nil, fmt.Errorf("invalid argument syntax: '%s'", part)
 		}
@@ -305,13 +478,121 @@ func parseArgumentList(argStr string) ([]*Argument, error) {
 			return nil, fmt.Errorf("duplicate argument '%s'", argName)
 		}
 		seen[argName] = true
+		if len(args) > 0 && args[len(args)-1].IsVariadic {
+			return nil, fmt.Errorf("variadic argument '%s...' must be the last argument", args[len(args)-1].Name)
+		}
 
 		args = append(args, &Argument{
 			Name:       argName,
-			IsOptional: isOptional,
+			IsOptional: isOptional || variadic,
+			IsVariadic: variadic,
 			Default:    defaultVal,
 		})
 	}
 
 	return args, nil
 }
+
+// extractMatrix cuts a header `matrix` clause out of line:
+//
+//	build matrix os in linux, windows; arch in amd64, arm64; exclude os=windows arch=arm64 < lint {
+//
+// The clause runs to the prerequisites, the body, or the next header keyword,
+// and line is returned without it so the other extractors never see it.
+func extractMatrix(line string) (*Matrix, string, error) {
+	start := findTopLevelKeyword(line, " matrix ")
+	if start < 0 {
+		return nil, line, nil
+	}
+	end := len(line)
+	if brace := strings.IndexByte(line[start:], '{'); brace >= 0 {
+		end = start + brace
+	}
+	if lt := ltIndex(line[start:end]); lt >= 0 {
+		end = start + lt
+	}
+	for _, kw := range []string{" produces ", " onchange ", " timeout<", " container "} {
+		if i := findTopLevelKeyword(line[start+1:end], kw); i >= 0 {
+			end = start + 1 + i
+		}
+	}
+	m, err := parseMatrixClause(line[start+len(" matrix ") : end])
+	if err != nil {
+		return nil, line, err
+	}
+	return m, line[:start] + " " + strings.TrimLeft(line[end:], " "), nil
+}
+
+func parseMatrixClause(clause string) (*Matrix, error) {
+	m := &Matrix{}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(clause, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if kw, rest, _ := strings.Cut(part, " "); kw == "include" || kw == "exclude" {
+			rule, err := parseMatrixRule(rest)
+			if err != nil {
+				return nil, fmt.Errorf("matrix %s: %w", kw, err)
+			}
+			if kw == "include" {
+				m.Include = append(m.Include, rule)
+			} else {
+				m.Exclude = append(m.Exclude, rule)
+			}
+			continue
+		}
+		name, items, ok := strings.Cut(part, " in ")
+		name = strings.TrimSpace(name)
+		if !ok || !isValidIdent(name) {
+			return nil, fmt.Errorf("malformed matrix clause %q (expected <var> in <values>)", part)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate matrix axis '%s'", name)
+		}
+		seen[name] = true
+		var values []string
+		for _, v := range splitTopLevel(items, ',') {
+			if v = trimQuoted(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		if len(values) == 0 {
+			return nil, fmt.Errorf("matrix axis '%s' has no values", name)
+		}
+		m.Axes = append(m.Axes, MatrixAxis{Name: name, Values: values})
+	}
+	if len(m.Axes) == 0 {
+		return nil, fmt.Errorf("matrix needs at least one axis (e.g. matrix os in linux, windows)")
+	}
+	for _, rule := range append(slices.Clone(m.Include), m.Exclude...) {
+		for k := range rule {
+			if !seen[k] {
+				return nil, fmt.Errorf("matrix rule names unknown axis '%s'", k)
+			}
+		}
+	}
+	for _, rule := range m.Include {
+		if len(rule) != len(m.Axes) {
+			return nil, fmt.Errorf("matrix include must set every axis (%d given, %d axes)", len(rule), len(m.Axes))
+		}
+	}
+	return m, nil
+}
+
+// parseMatrixRule reads `os=windows arch=arm64` (commas optional).
+func parseMatrixRule(s string) (map[string]string, error) {
+	rule := make(map[string]string)
+	for _, f := range strings.Fields(strings.ReplaceAll(s, ",", " ")) {
+		k, v, ok := strings.Cut(f, "=")
+		if !ok || k == "" || v == "" {
+			return nil, fmt.Errorf("invalid rule %q (expected axis=value)", f)
+		}
+		rule[k] = trimQuoted(v)
+	}
+	if len(rule) == 0 {
+		return nil, fmt.Errorf("empty rule")
+	}
+	return rule, nil
+}
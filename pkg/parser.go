@@ -12,8 +12,14 @@ type Parser struct {
 	InputFile   string
 	Data        *ParsedData
 	Lines       []string
-	importStack map[string]bool // recursion path, for cycle detection
-	imported    map[string]bool // files already merged, for diamond dedup
+	importStack map[string]bool            // recursion path, for cycle detection
+	imported    map[string]bool            // files already merged, for diamond dedup
+	overrides   map[string]*importOverride // globals set by the importer's `with (...)`
+	inService   bool                       // parsing a service body, where `stop` is a statement
+
+	Profile      string      // selected profile; defaults to SetProfile's
+	profiles     []*Profile  // this file's profile blocks
+	profileSpans map[int]int // profile block start line index -> lines consumed
 
 	ImportReader func(path string) ([]byte, error)
 }
@@ -32,6 +38,7 @@ func NewParserFromContent(file, content string) *Parser {
 		InputFile: file,
 		Data:      &ParsedData{},
 		Lines:     strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n"),
+		Profile:   activeProfile,
 	}
 }
 
@@ -190,6 +197,7 @@ func (p *Parser) evalVarValue(value string, varName *string, varScope *string, l
 }
 
 func (p *Parser) parseVar(line string, scope string, lineNum int) error {
+	line, private := strings.CutPrefix(strings.TrimSpace(line), "private ")
 	pieces := strings.SplitN(line, "=", 2)
 
 	variableName := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(pieces[0]), "var"))
@@ -201,7 +209,25 @@ func (p *Parser) parseVar(line string, scope string, lineNum int) error {
 	var isList bool
 	var list []string
 	var refs []string
-	if len(pieces) > 1 {
+	profileRaw, inProfile := "", false
+	if scope == "global" {
+		profileRaw, inProfile = p.profileValue(variableName)
+	}
+	if ov := p.overrides[variableName]; ov != nil && scope == "global" && private {
+		return fmt.Errorf("variable %q is private and cannot be overridden by the importer (%s:%d)", variableName, ov.file, ov.line)
+	} else if ov != nil && scope == "global" {
+		// The importer's `with (...)` replaces the value before anything
+		// in this file evaluates against it.
+		ov.used = true
+		variableValue, isList, list = ov.value.String(), ov.value.IsList, ov.value.L
+	} else if inProfile {
+		var err error
+		refs = VarRefNames(profileRaw)
+		variableValue, isList, list, err = p.evalVarValue(profileRaw, &variableName, &scope, lineNum)
+		if err != nil {
+			return fmt.Errorf("variable %q (profile %s): %w", variableName, p.Profile, err)
+		}
+	} else if len(pieces) > 1 {
 		var err error
 		refs = VarRefNames(pieces[1])
 		variableValue, isList, list, err = p.evalVarValue(pieces[1], &variableName, &scope, lineNum)
@@ -211,12 +237,14 @@ func (p *Parser) parseVar(line string, scope string, lineNum int) error {
 	}
 
 	p.Data.addVariable(&Variable{
-		Name:   variableName,
-		Value:  variableValue,
-		Scope:  scope,
-		IsList: isList,
-		List:   list,
-		refs:   refs,
+		Name:    variableName,
+		Value:   variableValue,
+		Scope:   scope,
+		IsList:  isList,
+		List:    list,
+		Private: private,
+		refs:    refs,
+		file:    p.InputFile,
 	})
 
 	return nil
@@ -228,6 +256,12 @@ func (p *Parser) parseCommand(idx int, line string, isDefault, manual, service b
 		return 0, nil
 	}
 
+	guard, line := extractGuard(line)
+	matrix, line, err := extractMatrix(line)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse matrix for '%s': %w", ParseCommandName(line), err)
+	}
+
 	commandName := ParseCommandName(line)
 	cloudAccessible := len(trimmedLine) >= 2 && trimmedLine[0] == '|'
 
@@ -236,7 +270,7 @@ func (p *Parser) parseCommand(idx int, line string, isDefault, manual, service b
 		return 0, fmt.Errorf("failed to parse arguments for '%s': %w", commandName, err)
 	}
 
-	prereqs, prereqDirs, err := extractPrerequisites(line)
+	prereqs, err := extractPrerequisites(line)
 	if err != nil {
 		return 0, fmt.Errorf("failed to parse prerequisites for '%s': %w", commandName, err)
 	}
@@ -255,6 +289,8 @@ func (p *Parser) parseCommand(idx int, line string, isDefault, manual, service b
 
 	var commandBody []BodyStatement
 	consumed := 1
+	p.inService = service
+	defer func() { p.inService = false }()
 	if body, ok := singleLineBody(trimmedLine); ok {
 		commandBody, err = p.parseBodyStatements(atLine(splitStatements(body), lineNum), commandName)
 		if err != nil {
@@ -273,10 +309,22 @@ func (p *Parser) parseCommand(idx int, line string, isDefault, manual, service b
 	}
 
 	if commandName != "" {
-		port := ""
+		port, publish := "", ""
+		var ready, health *Probe
+		var stop *StopSpec
 		for _, stmt := range commandBody {
-			if stmt.Type == StmtPort {
-				port = stmt.Shell
+			switch stmt.Type {
+			case StmtPort:
+				port, publish = stmt.Shell, ""
+				if host, _, mapped := strings.Cut(stmt.Shell, ":"); mapped {
+					port, publish = host, stmt.Shell
+				}
+			case StmtReady:
+				ready, _ = probeFromStmt(stmt)
+			case StmtHealth:
+				health, _ = probeFromStmt(stmt)
+			case StmtStop:
+				stop = &StopSpec{Signal: stmt.Shell, After: stmt.Timeout}
 			}
 		}
 		p.Data.addCommand(&Command{
@@ -288,9 +336,18 @@ func (p *Parser) parseCommand(idx int, line string, isDefault, manual, service b
 			IsDefault:       isDefault,
 			IsService:       service,
 			Port:            port,
+			Publish:         publish,
+			Ready:           ready,
+			Health:          health,
+			Stop:            stop,
 			Arguments:       commandArgs,
-			Prereqs:         prereqs,
-			PrereqDirs:      prereqDirs,
+			Prereqs:         prereqs.names,
+			PrereqDirs:      prereqs.dirs,
+			PrereqAliases:   prereqs.aliases,
+			PrereqConds:     prereqs.conds,
+			PrereqOrderOnly: prereqs.orderOnly,
+			Guard:           guard,
+			Matrix:          matrix,
 			WorkDir:         workDir,
 			Container:       container,
 			Manual:          manual,
@@ -319,7 +376,7 @@ func stripInlineComment(line string) string {
 			continue
 		}
 
-		if !inQuote && i+1 < len(line) && c == '/' && line[i+1] == '/' {
+		if !inQuote && i+1 < len(line) && c == '/' && line[i+1] == '/' && !labelAt(line[i:]) {
 			if i == 0 || line[i-1] == ' ' || line[i-1] == '\t' {
 				return strings.TrimSpace(line[:i])
 			}
@@ -342,12 +399,22 @@ func (p *Parser) parseLines() error {
 		p.imported = make(map[string]bool)
 	}
 
+	if err := p.scanProfiles(); err != nil {
+		return err
+	}
+
 	idx := 0
 	var pendingComment []string // doc comment lines before the next command
 	for idx < len(p.Lines) {
 		line := p.Lines[idx]
 		lineNum := idx + 1
 
+		if consumed, ok := p.profileSpans[idx]; ok {
+			pendingComment = nil
+			idx += consumed
+			continue
+		}
+
 		trimmed := strings.TrimSpace(line)
 		if strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "//") {
 			pendingComment = append(pendingComment, trimDocMarker(trimmed))
@@ -362,7 +429,7 @@ func (p *Parser) parseLines() error {
 		}
 
 		if strings.HasPrefix(line, "import ") || line == "import" {
-			if err := p.processImport(line); err != nil {
+			if err := p.processImport(line, lineNum); err != nil {
 				return p.parseErr(lineNum, err, line)
 			}
 			pendingComment = nil
@@ -370,7 +437,16 @@ func (p *Parser) parseLines() error {
 			continue
 		}
 
-		if strings.HasPrefix(line, "var ") {
+		if line == "workspace" || strings.HasPrefix(line, "workspace ") && !strings.Contains(line, "{") {
+			if err := p.processWorkspace(line); err != nil {
+				return p.parseErr(lineNum, err, line)
+			}
+			pendingComment = nil
+			idx++
+			continue
+		}
+
+		if strings.HasPrefix(line, "var ") || strings.HasPrefix(line, "private var ") {
 			if err := p.parseVar(line, "global", lineNum); err != nil {
 				return p.parseErr(lineNum, err, line)
 			}
@@ -379,6 +455,15 @@ func (p *Parser) parseLines() error {
 			continue
 		}
 
+		if strings.HasPrefix(line, "secret ") {
+			if err := p.parseSecret(line, lineNum); err != nil {
+				return p.parseErr(lineNum, err, line)
+			}
+			pendingComment = nil
+			idx++
+			continue
+		}
+
 		if strings.HasPrefix(line, "state ") {
 			inner := strings.TrimSpace(strings.TrimPrefix(line, "state"))
 			name, value, ok := strings.Cut(inner, "=")
@@ -398,24 +483,30 @@ func (p *Parser) parseLines() error {
 			continue
 		}
 
-		header, manual := StripManual(line)
+		header, private := StripPrivate(line)
+		header, manual := StripManual(header)
 		header, service := StripService(header)
 		cmdLine := strings.TrimSpace(header)
 		isDefault := strings.HasPrefix(cmdLine, "_") &&
 			(len(cmdLine) == 1 || cmdLine[1] == ' ' || cmdLine[1] == '\t' ||
 				cmdLine[1] == '(' || cmdLine[1] == '<' || cmdLine[1] == '{')
 
+		before := len(p.Data.Commands)
 		consumed, err := p.parseCommand(idx, header, isDefault, manual, service, lineNum, strings.Join(pendingComment, "\n"))
 		if err != nil {
 			return p.parseErr(lineNum, err, line)
 		}
+		if private && len(p.Data.Commands) > before {
+			p.Data.Commands[len(p.Data.Commands)-1].Private = true
+		}
 		pendingComment = nil
 		if consumed == 0 {
-			return p.parseErr(lineNum, fmt.Errorf("unrecognized top-level statement %q (expected var, import, state, or a command)", firstWord(line)), line)
+			return p.parseErr(lineNum, fmt.Errorf("unrecognized top-level statement %q (expected var, secret, import, state, or a command)", firstWord(line)), line)
 		}
 		idx += consumed
 	}
 
+	p.Data.Profiles = append(p.Data.Profiles, p.profiles...)
 	return nil
 }
 
@@ -429,6 +520,10 @@ func (p *Parser) Parse() (*ParsedData, error) {
 	if err := p.parseLines(); err != nil {
 		return nil, err
 	}
+	p.Data.Profile = p.Profile
+	if err := p.Data.checkProfile(); err != nil {
+		return nil, err
+	}
 
 	if !slices.Contains(p.Data.SourceFiles, p.InputFile) {
 		p.Data.SourceFiles = append(p.Data.SourceFiles, p.InputFile)
@@ -453,10 +548,23 @@ func (p *Parser) Parse() (*ParsedData, error) {
 		seenVars[key] = true
 	}
 
+	if err := p.resolveWorkspaceLabels(); err != nil {
+		return nil, err
+	}
+	if err := p.checkPrivateRefs(); err != nil {
+		return nil, err
+	}
+
 	if err := p.classifyPrereqs(); err != nil {
 		return nil, err
 	}
 	p.computeCacheGlobals()
+	if err := p.instantiatePrereqCalls(); err != nil {
+		return nil, err
+	}
+	if err := p.expandMatrices(); err != nil {
+		return nil, err
+	}
 	p.collectIndexedOutputRefs()
 
 	if err := p.detectCircularDependencies(); err != nil {
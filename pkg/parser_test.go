@@ -174,11 +174,18 @@ func TestExtractPrerequisites(t *testing.T) {
 			expected: "build, test",
 			dirs:     map[string]string{"build": "a", "test": "b"},
 		},
+		{
+			name:     "parameterized prereqs are canonical",
+			input:    "release < build(os=linux), build(os=windows, arch=arm64) in out {",
+			expected: "build(os=linux), build(arch=arm64,os=windows)",
+			dirs:     map[string]string{"build(arch=arm64,os=windows)": "out"},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			names, dirs, err := extractPrerequisites(tt.input)
+			h, err := extractPrerequisites(tt.input)
+			names, dirs := h.names, h.dirs
 			if err != nil {
 				t.Fatalf("extractPrerequisites(%q) unexpected error: %v", tt.input, err)
 			}
@@ -316,6 +323,20 @@ func TestParseArgumentList(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name:  "variadic last",
+			input: "env, pkgs...",
+			expected: []*Argument{
+				{Name: "env", IsOptional: false},
+				{Name: "pkgs", IsOptional: true, IsVariadic: true},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "variadic not last",
+			input:   "pkgs..., env",
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -338,6 +359,9 @@ func TestParseArgumentList(t *testing.T) {
 				if arg.IsOptional != tt.expected[i].IsOptional {
 					t.Errorf("arg[%d].IsOptional = %v, want %v", i, arg.IsOptional, tt.expected[i].IsOptional)
 				}
+				if arg.IsVariadic != tt.expected[i].IsVariadic {
+					t.Errorf("arg[%d].IsVariadic = %v, want %v", i, arg.IsVariadic, tt.expected[i].IsVariadic)
+				}
 			}
 		})
 	}
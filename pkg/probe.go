@@ -0,0 +1,183 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultReadyTimeout   = 90 * time.Second
+	defaultHealthInterval = 10 * time.Second
+	defaultHealthFailures = 3
+	probeRequestTimeout   = 5 * time.Second
+)
+
+// Probe is a service's `ready` or `health` check: an HTTP GET that must
+// answer 2xx or 3xx, or a shell command that must exit 0.
+type Probe struct {
+	HTTP     string `json:"http,omitempty"`
+	Shell    string `json:"shell,omitempty"`
+	Timeout  string `json:"timeout,omitempty"`  // ready: how long construct dev waits
+	Every    string `json:"every,omitempty"`    // health: time between checks
+	Failures int    `json:"failures,omitempty"` // health: consecutive failures before a restart
+}
+
+func (p *Probe) String() string {
+	if p.HTTP != "" {
+		return p.HTTP
+	}
+	return "$ " + p.Shell
+}
+
+// ReadyTimeout is how long construct dev waits for a `ready` probe.
+func (p *Probe) ReadyTimeout() time.Duration {
+	if d, err := time.ParseDuration(p.Timeout); err == nil {
+		return d
+	}
+	return defaultReadyTimeout
+}
+
+// Interval is the time between `health` checks.
+func (p *Probe) Interval() time.Duration {
+	if d, err := time.ParseDuration(p.Every); err == nil {
+		return d
+	}
+	return defaultHealthInterval
+}
+
+// FailureLimit is how many consecutive `health` failures restart a service.
+func (p *Probe) FailureLimit() int {
+	if p.Failures > 0 {
+		return p.Failures
+	}
+	return defaultHealthFailures
+}
+
+// parseProbe reads the statement after `ready` or `health`:
+// `<mod> http "URL"` or `<mod> $ cmd`. ok is false when rest is neither,
+// so a shell program named ready/health still runs as a command.
+func parseProbe(keyword, rest string) (*BodyStatement, bool, error) {
+	rest, mod, _, err := peelModifier(strings.TrimSpace(rest))
+	if err != nil {
+		return nil, true, fmt.Errorf("malformed %s modifier: %v", keyword, err)
+	}
+	rest = strings.TrimSpace(rest)
+	stmt := &BodyStatement{Type: keyword, Modifier: mod}
+	switch {
+	case rest == "http" || strings.HasPrefix(rest, "http "):
+		stmt.BuiltinArgs = "http"
+		stmt.Shell = strings.Trim(strings.TrimSpace(rest[len("http"):]), `"`)
+		if stmt.Shell == "" {
+			return nil, true, fmt.Errorf("%s http needs a URL", keyword)
+		}
+	case strings.HasPrefix(rest, "$"):
+		stmt.Shell = strings.TrimSpace(rest[1:])
+		if stmt.Shell == "" {
+			return nil, true, fmt.Errorf("%s $ needs a command", keyword)
+		}
+	default:
+		return nil, false, nil
+	}
+	if _, err := probeFromStmt(*stmt); err != nil {
+		return nil, true, err
+	}
+	return stmt, true, nil
+}
+
+// probeFromStmt builds the Probe a command carries from its statement. The
+// modifier is `<timeout>` for ready and `<interval, failures>` for health.
+func probeFromStmt(stmt BodyStatement) (*Probe, error) {
+	p := &Probe{}
+	if stmt.BuiltinArgs == "http" {
+		p.HTTP = stmt.Shell
+	} else {
+		p.Shell = stmt.Shell
+	}
+	if stmt.Modifier == "" {
+		return p, nil
+	}
+	first, second, hasSecond := strings.Cut(stmt.Modifier, ",")
+	first, second = strings.TrimSpace(first), strings.TrimSpace(second)
+	if d, err := time.ParseDuration(first); err != nil || d <= 0 {
+		return nil, fmt.Errorf("invalid %s modifier %q: expected a duration", stmt.Type, stmt.Modifier)
+	}
+	switch {
+	case stmt.Type == StmtReady && hasSecond:
+		return nil, fmt.Errorf("invalid ready modifier %q: expected <timeout>", stmt.Modifier)
+	case stmt.Type == StmtReady:
+		p.Timeout = first
+	default:
+		p.Every = first
+		if hasSecond {
+			n, err := strconv.Atoi(second)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid health modifier %q: expected <interval, failures>", stmt.Modifier)
+			}
+			p.Failures = n
+		}
+	}
+	return p, nil
+}
+
+// RunProbe runs one check of a service probe. HTTP URLs and shell lines
+// resolve &vars and @ENV like the service body; shell probes run on the
+// host with the command's environment.
+func (e *Executor) RunProbe(runCtx context.Context, command *Command, probe *Probe) error {
+	ctx := &execContext{
+		target:  command,
+		srcFile: command.SourceFile,
+		runCtx:  runCtx,
+	}
+	cmdEnv := slices.Clone(e.env)
+	ctx.env = &cmdEnv
+
+	if probe.HTTP != "" {
+		url := ResolveEnvRefs(resolveVarRefs(probe.HTTP, func(name string) (string, bool) {
+			return e.StructuredParse.LookupVariable(name, command.Name)
+		}))
+		reqCtx, cancel := context.WithTimeout(runCtx, probeRequestTimeout)
+		defer cancel()
+		req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("%s returned %s", url, resp.Status)
+		}
+		return nil
+	}
+
+	line := e.resolveShellLine(ctx, probe.Shell)
+	argv, _, cleanup, err := e.shellArgsFor(ctx, line)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	cmd, done := e.command(ctx, argv)
+	out, err := cmd.CombinedOutput()
+	done()
+	if err != nil {
+		if msg := strings.TrimSpace(MaskSecrets(string(out))); msg != "" {
+			return fmt.Errorf("%s (exit %d): %s", probe.Shell, exitCodeOf(err), lastLine(msg))
+		}
+		return fmt.Errorf("%s (exit %d)", probe.Shell, exitCodeOf(err))
+	}
+	return nil
+}
+
+func lastLine(s string) string {
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
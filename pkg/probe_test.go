@@ -0,0 +1,74 @@
+package pkg
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServiceProbes(t *testing.T) {
+	var healthy bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	src := `var base = ` + srv.URL + `
+service api {
+    ready<30s> http "&base/healthz"
+    health<2s, 5> $ test -f &marker
+    $ sleep 100
+}
+worker {
+    ready $ true
+    health http "&base"
+    $ echo work
+}
+ready {
+    $ echo the ready command is not a probe
+}
+`
+	data, err := NewParserFromContent("Constfile", src).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	api, _ := data.GetCommand("api")
+	if api.Ready == nil || api.Ready.HTTP != "&base/healthz" || api.Ready.ReadyTimeout().String() != "30s" {
+		t.Fatalf("api ready = %+v", api.Ready)
+	}
+	if h := api.Health; h == nil || h.Shell != "test -f &marker" || h.Interval().String() != "2s" || h.FailureLimit() != 5 {
+		t.Fatalf("api health = %+v", api.Health)
+	}
+	worker, _ := data.GetCommand("worker")
+	if worker.Ready.Shell != "true" || worker.Health.Interval() != defaultHealthInterval || worker.Health.FailureLimit() != defaultHealthFailures {
+		t.Errorf("worker probes = %+v %+v", worker.Ready, worker.Health)
+	}
+
+	e := NewExecutor(data, false, false)
+	e.SetBaseDir(t.TempDir())
+	ctx := context.Background()
+	if err := e.RunProbe(ctx, api, api.Ready); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("unhealthy http probe err = %v", err)
+	}
+	healthy = true
+	if err := e.RunProbe(ctx, api, api.Ready); err != nil {
+		t.Errorf("healthy http probe: %v", err)
+	}
+	if err := e.RunProbe(ctx, worker, worker.Ready); err != nil {
+		t.Errorf("shell probe: %v", err)
+	}
+	if err := e.RunProbe(ctx, worker, &Probe{Shell: "echo booting; exit 3"}); err == nil || !strings.Contains(err.Error(), "(exit 3): booting") {
+		t.Errorf("failing shell probe err = %v", err)
+	}
+
+	for _, bad := range []string{"ready<30s, 2> $ true", "health<soon> $ true", "health<1s, x> $ true", "ready http"} {
+		src := "service s {\n    " + bad + "\n    $ sleep 1\n}\n"
+		if _, err := NewParserFromContent("Constfile", src).Parse(); err == nil {
+			t.Errorf("%q should not parse", bad)
+		}
+	}
+}
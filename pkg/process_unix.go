@@ -20,3 +20,21 @@ func killProcessGroup(cmd *exec.Cmd) error {
 	}
 	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
 }
+
+var stopSignalValues = map[string]syscall.Signal{
+	"SIGTERM": syscall.SIGTERM, "SIGINT": syscall.SIGINT, "SIGQUIT": syscall.SIGQUIT,
+	"SIGHUP": syscall.SIGHUP, "SIGKILL": syscall.SIGKILL, "SIGUSR1": syscall.SIGUSR1,
+	"SIGUSR2": syscall.SIGUSR2,
+}
+
+// signalProcessGroup sends a `stop` signal to the child's process group.
+func signalProcessGroup(cmd *exec.Cmd, signal string) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	sig, ok := stopSignalValues[signal]
+	if !ok {
+		sig = syscall.SIGTERM
+	}
+	return syscall.Kill(-cmd.Process.Pid, sig)
+}
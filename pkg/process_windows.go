@@ -25,3 +25,9 @@ func killProcessGroup(cmd *exec.Cmd) error {
 	_ = kill.Run()
 	return nil
 }
+
+// signalProcessGroup kills the child's process tree: Windows cannot deliver
+// a `stop` signal to it.
+func signalProcessGroup(cmd *exec.Cmd, _ string) error {
+	return killProcessGroup(cmd)
+}
@@ -0,0 +1,159 @@
+package pkg
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Profile is a top-level `profile name { var ... }` block. When it is
+// selected (--profile name or CONSTRUCT_PROFILE), its values replace the
+// declaring file's globals before they are evaluated.
+type Profile struct {
+	Name       string            `json:"name"`
+	Vars       map[string]string `json:"vars"` // raw values, as written
+	SourceFile string            `json:"source_file,omitempty"`
+	SourceLine int               `json:"source_line,omitempty"`
+
+	order     []string        // Vars keys in declaration order
+	lines     map[string]int  // var -> source line, for lint
+	overrides map[string]bool // vars that matched a global in the file
+}
+
+var activeProfile string
+
+// SetProfile selects the profile every parser applies from then on. The
+// CLI calls it once after flag parsing.
+func SetProfile(name string) {
+	activeProfile = name
+}
+
+// scanProfiles collects the file's profile blocks before anything is
+// evaluated, since a profile may follow the globals it overrides.
+func (p *Parser) scanProfiles() error {
+	for idx := 0; idx < len(p.Lines); idx++ {
+		line := stripInlineComment(p.Lines[idx])
+		if !isProfileHeader(line) {
+			continue
+		}
+		prof, consumed, err := p.parseProfile(idx, line)
+		if err != nil {
+			return p.parseErr(idx+1, err, line)
+		}
+		for _, other := range p.profiles {
+			if other.Name == prof.Name {
+				return p.parseErr(idx+1, fmt.Errorf("duplicate profile %q", prof.Name), line)
+			}
+		}
+		if p.profileSpans == nil {
+			p.profileSpans = map[int]int{}
+		}
+		p.profileSpans[idx] = consumed
+		p.profiles = append(p.profiles, prof)
+		idx += consumed - 1
+	}
+	return nil
+}
+
+func isProfileHeader(line string) bool {
+	return strings.HasPrefix(line, "profile ") && strings.Contains(line, "{")
+}
+
+func (p *Parser) parseProfile(idx int, line string) (*Profile, int, error) {
+	header, _, _ := strings.Cut(line, "{")
+	name := strings.TrimSpace(strings.TrimPrefix(header, "profile"))
+	if !isValidIdent(name) {
+		return nil, 0, fmt.Errorf("invalid profile name %q (expected an identifier)", name)
+	}
+	prof := &Profile{
+		Name:       name,
+		Vars:       map[string]string{},
+		SourceFile: p.InputFile,
+		SourceLine: idx + 1,
+		lines:      map[string]int{},
+		overrides:  map[string]bool{},
+	}
+
+	var raw []rawLine
+	consumed := 1
+	if body, ok := singleLineBody(line); ok {
+		raw = atLine(splitStatements(body), idx+1)
+	} else {
+		body, endIdx, err := p.parseCommandBody(idx+1, "profile "+name)
+		if err != nil {
+			return nil, 0, err
+		}
+		raw, consumed = body, endIdx-idx
+	}
+	for _, r := range raw {
+		text := stripInlineComment(r.text)
+		if text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, "//") {
+			continue
+		}
+		rest, ok := strings.CutPrefix(text, "var ")
+		varName, value, hasValue := strings.Cut(rest, "=")
+		varName = strings.TrimSpace(varName)
+		if !ok || !hasValue || varName == "" {
+			return nil, 0, fmt.Errorf("profile %s: expected `var name = value`, got %q", name, text)
+		}
+		if _, dup := prof.Vars[varName]; dup {
+			return nil, 0, fmt.Errorf("profile %s: %q is set twice", name, varName)
+		}
+		prof.Vars[varName] = strings.TrimSpace(value)
+		prof.order = append(prof.order, varName)
+		prof.lines[varName] = r.num
+	}
+	return prof, consumed, nil
+}
+
+// profileValue returns the selected profile's raw value for a global, and
+// notes the global as overridable in every profile that sets it.
+func (p *Parser) profileValue(name string) (string, bool) {
+	var value string
+	found := false
+	for _, prof := range p.profiles {
+		v, ok := prof.Vars[name]
+		if !ok {
+			continue
+		}
+		prof.overrides[name] = true
+		if prof.Name == p.Profile {
+			value, found = v, true
+		}
+	}
+	return value, found
+}
+
+// checkProfile rejects a selected profile that no loaded file declares.
+func (d *ParsedData) checkProfile() error {
+	if d.Profile == "" {
+		return nil
+	}
+	var names []string
+	for _, prof := range d.Profiles {
+		if prof.Name == d.Profile {
+			return nil
+		}
+		if !slices.Contains(names, prof.Name) {
+			names = append(names, prof.Name)
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("unknown profile %q (the Constfile declares no profiles)", d.Profile)
+	}
+	slices.Sort(names)
+	return fmt.Errorf("unknown profile %q (available: %s)", d.Profile, strings.Join(names, ", "))
+}
+
+// ProfileNames lists the declared profiles, sorted and deduplicated across
+// files.
+func (d *ParsedData) ProfileNames() []string {
+	var names []string
+	for _, prof := range d.Profiles {
+		if !slices.Contains(names, prof.Name) {
+			names = append(names, prof.Name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
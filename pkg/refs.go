@@ -83,6 +83,10 @@ func scanRefs(s string, marker byte, firstSeg, dotSeg func(rune) bool, lookup fu
 			}
 			if j > firstStart {
 				firstEnd := j // first segment ends here, before any dots
+				call := false
+				if marker == '&' && dotSeg != nil {
+					j, call = skipCallArgs(runes, j)
+				}
 				if dotSeg != nil {
 					for j < len(runes) && runes[j] == '.' && j+1 < len(runes) && dotSeg(runes[j+1]) {
 						j++
@@ -98,7 +102,11 @@ func scanRefs(s string, marker byte, firstSeg, dotSeg func(rune) bool, lookup fu
 						j++
 					}
 				}
-				if val, ok := lookup(string(runes[firstStart:j])); ok {
+				name := string(runes[firstStart:j])
+				if call {
+					name = canonicalRefName(name)
+				}
+				if val, ok := lookup(name); ok {
 					result.WriteString(val)
 					i = j
 					continue
@@ -142,13 +150,18 @@ func VarRefNames(s string) []string {
 		if j == i+1 {
 			continue
 		}
+		j, call := skipCallArgs(runes, j)
 		for j < len(runes) && runes[j] == '.' && j+1 < len(runes) && isPlainRune(runes[j+1]) {
 			j++
 			for j < len(runes) && isPlainRune(runes[j]) {
 				j++
 			}
 		}
-		names = append(names, string(runes[i+1:j]))
+		name := string(runes[i+1 : j])
+		if call {
+			name = canonicalRefName(name)
+		}
+		names = append(names, name)
 		i = j - 1
 	}
 	return names
@@ -171,6 +184,7 @@ func wildcardRefNames(s string) []string {
 		if j == i+1 {
 			continue
 		}
+		j, _ = skipCallArgs(runes, j)
 		for j < len(runes) && runes[j] == '.' && j+1 < len(runes) && isPlainRune(runes[j+1]) {
 			j++
 			for j < len(runes) && isPlainRune(runes[j]) {
@@ -178,13 +192,37 @@ func wildcardRefNames(s string) []string {
 			}
 		}
 		if j+1 < len(runes) && runes[j] == '.' && runes[j+1] == '*' {
-			names = append(names, string(runes[i+1:j]))
+			names = append(names, canonicalRefName(string(runes[i+1:j])))
 			i = j + 1
 		}
 	}
 	return names
 }
 
+// skipCallArgs steps over the (k=v, ...) of a parameterized output ref such
+// as &build(os=linux).0. It only matches when an output segment follows, so
+// shell text like &x (y) is left alone.
+func skipCallArgs(runes []rune, j int) (int, bool) {
+	if j >= len(runes) || runes[j] != '(' {
+		return j, false
+	}
+	sawEq := false
+	for k := j + 1; k < len(runes); k++ {
+		switch runes[k] {
+		case ')':
+			if sawEq && k+2 < len(runes) && runes[k+1] == '.' && (isPlainRune(runes[k+2]) || runes[k+2] == '*') {
+				return k + 1, true
+			}
+			return j, false
+		case '=':
+			sawEq = true
+		case '(', '&', '@', '$', '"', '\'', '`', '\n', ';', '|':
+			return j, false
+		}
+	}
+	return j, false
+}
+
 func isEnvDefaultEnd(r rune) bool {
 	switch r {
 	case ' ', '\t', '\r', '\n', '"', '\'', ',', ';', '&', '@', '$':
@@ -78,5 +78,5 @@ func (e *Executor) takeRunLog(name string) string {
 	if b == nil {
 		return ""
 	}
-	return b.String()
+	return MaskSecrets(b.String())
 }
@@ -3,6 +3,7 @@ package pkg
 import (
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestRunLogBufferCap(t *testing.T) {
@@ -82,3 +83,24 @@ func TestRunLogRecordsFailure(t *testing.T) {
 		t.Errorf("exit marker missing from log: %q", rec.Log)
 	}
 }
+
+func TestMergeRunHistory(t *testing.T) {
+	t0 := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	hist := map[string][]RunRecord{
+		"test": {{Status: "ok", End: t0}, {Status: "ok", End: t0.Add(2 * time.Hour)}},
+	}
+	cloud := map[string][]RunRecord{
+		"test":  {{Status: "failed", Exit: 1, End: t0.Add(time.Hour), Log: "FAIL"}},
+		"build": {{Status: "ok", End: t0}},
+	}
+	if n := MergeRunHistory(hist, cloud, "cloud run #42"); n != 2 {
+		t.Fatalf("added %d, want 2", n)
+	}
+	got := hist["test"]
+	if len(got) != 3 || got[1].Status != "failed" || got[1].Source != "cloud run #42" || got[2].Source != "" {
+		t.Fatalf("test records = %+v, want the cloud failure between the local runs", got)
+	}
+	if n := MergeRunHistory(hist, cloud, "cloud run #42"); n != 0 || len(hist["test"]) != 3 {
+		t.Errorf("second merge added %d records", n)
+	}
+}
@@ -20,20 +20,36 @@ import (
 	"github.com/spf13/pflag"
 )
 
-func (e *Executor) command(ctx *execContext, argv []string) *exec.Cmd {
+// command builds the process for argv. The returned func must be called once
+// the command has been waited on: it stops a pending `stop` grace-period kill,
+// so a group that exited on its own (and whose pid may be reused) is left alone.
+func (e *Executor) command(ctx *execContext, argv []string) (*exec.Cmd, func()) {
 	runCtx := e.effectiveRunCtx(ctx)
 	cmd := exec.CommandContext(runCtx, argv[0], argv[1:]...)
 	prepareProcessGroup(cmd)
 	cmd.Cancel = func() error {
 		return killProcessGroup(cmd)
 	}
+	// Wait doesn't return until Cancel has, so done sees the timer it set.
+	var kill *time.Timer
+	done := func() {
+		if kill != nil {
+			kill.Stop()
+		}
+	}
+	if stop := ctx.stop; stop != nil {
+		cmd.Cancel = func() error {
+			kill = time.AfterFunc(stop.Grace(), func() { _ = killProcessGroup(cmd) })
+			return signalProcessGroup(cmd, stop.SignalName())
+		}
+	}
 	if ctx.workDir != "" {
 		cmd.Dir = e.resolveWorkDir(e.resolveBodyValue(ctx, ctx.workDir, ctx.target.Name))
 	} else if e.baseDir != "" {
 		cmd.Dir = e.baseDir
 	}
 	cmd.Env = *ctx.env
-	return cmd
+	return cmd, done
 }
 
 func (e *Executor) effectiveRunCtx(ctx *execContext) context.Context {
@@ -104,6 +120,11 @@ func (e *Executor) shellArgsFor(ctx *execContext, script string) (argv []string,
 			argv = append(argv, "-w", wd)
 		}
 	}
+	// `port 8080:80` publishes the container's port, so the readiness check
+	// and its dependents reach it on localhost.
+	if t := ctx.target; t != nil && t.IsService && t.Publish != "" {
+		argv = append(argv, "-p", t.Publish)
+	}
 	argv = append(argv, image, "/bin/sh", "-c", script)
 	return argv, rt + " run " + image + " /bin/sh -c " + script, noop, nil
 }
@@ -155,11 +176,14 @@ func (e *Executor) resolveShellLine(ctx *execContext, line string) string {
 			continue
 		}
 		e.debugf("Handling argument --%s for command %s\n", arg.Name, cmd.Name)
-		fs := e.flagSet
-		if fs == nil {
-			fs = pflag.CommandLine
+		if arg.IsVariadic {
+			line = replaceArgRef(line, arg.Name, shellQuoteWords(e.variadicWords(cmd, arg)))
+			continue
+		}
+		v, err := e.argFlags().GetString(cmd.flagScope() + ":" + arg.Name)
+		if err != nil {
+			v = arg.Default // flags never registered (embedded use)
 		}
-		v, _ := fs.GetString(cmd.flagScope() + ":" + arg.Name)
 		line = replaceArgRef(line, arg.Name, escapeShellValue(v))
 	}
 
@@ -167,6 +191,51 @@ func (e *Executor) resolveShellLine(ctx *execContext, line string) string {
 	return e.resolveBodyEnvRef(ctx, line)
 }
 
+func (e *Executor) argFlags() *pflag.FlagSet {
+	if e.flagSet != nil {
+		return e.flagSet
+	}
+	return pflag.CommandLine
+}
+
+// variadicWords returns the words bound to a name... argument: repeated
+// --cmd:name flags plus any `--` passthrough, else its default split on spaces.
+func (e *Executor) variadicWords(cmd *Command, arg *Argument) []string {
+	if bound, ok := cmd.BoundArgs[arg.Name]; ok {
+		return strings.Fields(bound)
+	}
+	if words, err := e.argFlags().GetStringArray(cmd.flagScope() + ":" + arg.Name); err == nil && len(words) > 0 {
+		return words
+	}
+	return strings.Fields(arg.Default)
+}
+
+// shellQuoteWords joins words for a shell line, single-quoting any word the
+// shell would otherwise split, glob, or expand.
+func shellQuoteWords(words []string) string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		if shellSafeWord(w) {
+			quoted[i] = w
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(w, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
+
+func shellSafeWord(w string) bool {
+	if w == "" {
+		return false
+	}
+	for i := 0; i < len(w); i++ {
+		if !isWordByte(w[i]) && strings.IndexByte("-./:=@%+,", w[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
 func findArgRef(line, name string) int {
 	pattern := "&" + name
 	for start := 0; ; {
@@ -318,20 +387,24 @@ func (e *Executor) runShellGroup(ctx *execContext, lines []string, strict bool,
 		return fmt.Errorf("command %q: %w", ctx.target.Name, err)
 	}
 	defer cleanup() // scoped to this group: Windows temp scripts go away promptly
-	cmd := e.command(ctx, argv)
+	cmd, done := e.command(ctx, argv)
+	defer done()
 
 	var buf bytes.Buffer
 	sink := e.streamSink(ctx, true)
 	rec := e.logRecorder(ctx.target.Name)
 	e.appendRunLog(ctx.target.Name, "$ "+strings.Join(lines, "\n$ ")+"\n")
-	cmd.Stdout = io.MultiWriter(sink, &buf, rec)
-	cmd.Stderr = io.MultiWriter(e.errSinkFor(ctx), rec)
+	stdout, stderr := newSecretMasker(sink), newSecretMasker(e.errSinkFor(ctx))
+	cmd.Stdout = io.MultiWriter(stdout, &buf, rec)
+	cmd.Stderr = io.MultiWriter(stderr, rec)
 
 	e.debugf("Running command %s (batched): %s\n", ctx.target.Name, fullCommand)
 
 	release := e.acquire()
 	defer release()
-	err = cmd.Run()
+	err = e.runStreamed(ctx, cmd)
+	stdout.Flush()
+	stderr.Flush()
 	if pw, ok := sink.(*linePrefixWriter); ok {
 		pw.flush()
 	}
@@ -350,6 +423,21 @@ func (e *Executor) runShellGroup(ctx *execContext, lines []string, strict bool,
 	return nil
 }
 
+// runStreamed runs a streamed command, telling a ProcessObserver its pid
+// once it has started.
+func (e *Executor) runStreamed(ctx *execContext, cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	e.mu.Lock()
+	observer := e.observer
+	e.mu.Unlock()
+	if po, ok := observer.(ProcessObserver); ok {
+		po.ProcessStarted(ctx.target.Name, cmd.Process.Pid)
+	}
+	return cmd.Wait()
+}
+
 type linePrefixWriter struct {
 	mu     sync.Mutex
 	w      io.Writer
@@ -437,7 +525,8 @@ func (e *Executor) runShellOnce(ctx *execContext, stmt BodyStatement) error {
 		return fmt.Errorf("command %q: %w", ctx.target.Name, err)
 	}
 	defer cleanup()
-	cmd := e.command(stmtCtx, argv)
+	cmd, done := e.command(stmtCtx, argv)
+	defer done()
 	if e.debug {
 		switch {
 		case ctx.isPrereq:
@@ -457,9 +546,12 @@ func (e *Executor) runShellOnce(ctx *execContext, stmt BodyStatement) error {
 		sink := e.streamSink(ctx, false)
 		rec := e.logRecorder(ctx.target.Name)
 		e.appendRunLog(ctx.target.Name, "$ "+display+"\n")
-		cmd.Stdout = io.MultiWriter(sink, &buf, rec)
-		cmd.Stderr = io.MultiWriter(e.errSinkFor(ctx), rec)
-		err := cmd.Run()
+		stdout, stderr := newSecretMasker(sink), newSecretMasker(e.errSinkFor(ctx))
+		cmd.Stdout = io.MultiWriter(stdout, &buf, rec)
+		cmd.Stderr = io.MultiWriter(stderr, rec)
+		err := e.runStreamed(ctx, cmd)
+		stdout.Flush()
+		stderr.Flush()
 		if pw, ok := sink.(*linePrefixWriter); ok {
 			pw.flush()
 		}
@@ -470,6 +562,9 @@ func (e *Executor) runShellOnce(ctx *execContext, stmt BodyStatement) error {
 		if err != nil && !ignoreErr {
 			return e.commandError(fullCommand, stmtCtx, stmt, err, "")
 		}
+		if stmt.OutputName != "" {
+			e.setNamedOutput(ctx.target, stmt.OutputName, strings.TrimSpace(buf.String()))
+		}
 		return nil
 	}
 
@@ -520,13 +615,10 @@ func (e *Executor) runShellOnce(ctx *execContext, stmt BodyStatement) error {
 	case ctx.isPrereq:
 		e.mu.Lock()
 		ctx.target.PrereqOutput = append(ctx.target.PrereqOutput, strOutput)
+		e.mu.Unlock()
 		if stmt.OutputName != "" {
-			if ctx.target.NamedOutput == nil {
-				ctx.target.NamedOutput = make(map[string]string)
-			}
-			ctx.target.NamedOutput[stmt.OutputName] = strOutput
+			e.setNamedOutput(ctx.target, stmt.OutputName, strOutput)
 		}
-		e.mu.Unlock()
 		e.debugf("Prereq output: %s\n", strOutput)
 		if stmt.OutputName != "" {
 			e.debugf("Named output %s.%s = %s\n", ctx.target.Name, stmt.OutputName, strOutput)
@@ -538,15 +630,29 @@ func (e *Executor) runShellOnce(ctx *execContext, stmt BodyStatement) error {
 		if !e.quiet {
 			fmt.Fprintln(e.outSink(), strOutput)
 		}
+		if stmt.OutputName != "" {
+			e.setNamedOutput(ctx.target, stmt.OutputName, strOutput)
+		}
 	}
 	return nil
 }
 
+// setNamedOutput keeps a statement's `as name` output for &cmd.name refs
+// and for the command's run record.
+func (e *Executor) setNamedOutput(target *Command, name, out string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if target.NamedOutput == nil {
+		target.NamedOutput = make(map[string]string)
+	}
+	target.NamedOutput[name] = out
+}
+
 func (e *Executor) commandError(fullCommand string, stmtCtx *execContext, stmt BodyStatement, err error, stderr string) error {
 	ce := &CommandError{
-		Cmd:      fullCommand,
+		Cmd:      MaskSecrets(fullCommand),
 		ExitCode: exitCodeOf(err),
-		Stderr:   stderr,
+		Stderr:   MaskSecrets(stderr),
 		File:     stmtCtx.srcFile,
 		Line:     stmt.SourceLine,
 	}
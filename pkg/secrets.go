@@ -0,0 +1,349 @@
+package pkg
+
+import (
+	"cmp"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// SecretsFileName is the encrypted store bare `secret name` declarations
+// read from, next to the declaring Constfile.
+const SecretsFileName = ".construct-secrets"
+
+const (
+	secretsKDFRounds  = 600_000
+	secretsFormatV1   = 1
+	minSecretMaskSize = 3 // shorter values would mangle unrelated output
+)
+
+var (
+	secretMu     sync.RWMutex
+	secretValues []string // longest first, so overlapping values mask fully
+
+	secretsKeyFile string
+	secretsPrompt  func() (string, error)
+	secretsPass    string                           // cached once unlocked
+	secretStores   = map[string]map[string]string{} // abs path -> decrypted store
+)
+
+// ErrSecretsLocked means a store is needed but no passphrase is available.
+var ErrSecretsLocked = errors.New("no passphrase (set CONSTRUCT_SECRETS_KEY, CONSTRUCT_SECRETS_KEYFILE, or --secrets-key-file)")
+
+// RegisterSecret adds a value to the set masked in everything construct
+// writes: streamed output, run logs, errors, dry-run, --explain, and the
+// MCP and UI servers.
+func RegisterSecret(v string) {
+	v = strings.TrimSpace(v)
+	if len(v) < minSecretMaskSize {
+		return
+	}
+	secretMu.Lock()
+	defer secretMu.Unlock()
+	if slices.Contains(secretValues, v) {
+		return
+	}
+	secretValues = append(secretValues, v)
+	slices.SortStableFunc(secretValues, func(a, b string) int { return len(b) - len(a) })
+}
+
+// MaskSecrets replaces every registered secret value in s.
+func MaskSecrets(s string) string {
+	secretMu.RLock()
+	defer secretMu.RUnlock()
+	return RedactValues(s, secretValues)
+}
+
+// secretDigest hides values that contain a secret behind their hash, for
+// places that persist them (cache keys).
+func secretDigest(v string) string {
+	if MaskSecrets(v) == v {
+		return v
+	}
+	sum := sha256.Sum256([]byte(v))
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
+
+// secretPrefixLen is the length of the longest suffix of s that could be
+// the start of a secret, which a streaming writer must hold back.
+func secretPrefixLen(s string) int {
+	secretMu.RLock()
+	defer secretMu.RUnlock()
+	hold := 0
+	for _, v := range secretValues {
+		for k := min(len(v)-1, len(s)); k > hold; k-- {
+			if strings.HasSuffix(s, v[:k]) {
+				hold = k
+				break
+			}
+		}
+	}
+	return hold
+}
+
+// secretMasker masks a byte stream whose writes may split a secret. It
+// holds back only a tail that could begin one; Flush releases it.
+type secretMasker struct {
+	mu      sync.Mutex
+	w       io.Writer
+	pending []byte
+}
+
+func newSecretMasker(w io.Writer) *secretMasker {
+	return &secretMasker{w: w}
+}
+
+func (m *secretMasker) Write(b []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	masked := MaskSecrets(string(append(m.pending, b...)))
+	cut := len(masked) - secretPrefixLen(masked)
+	m.pending = append(m.pending[:0], masked[cut:]...)
+	if cut > 0 {
+		if _, err := io.WriteString(m.w, masked[:cut]); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+func (m *secretMasker) Flush() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.pending) > 0 {
+		_, _ = io.WriteString(m.w, MaskSecrets(string(m.pending)))
+		m.pending = nil
+	}
+}
+
+// maskedWriter masks each write on its own, for callers that write whole
+// messages (status lines, captured output) rather than a stream.
+type maskedWriter struct{ w io.Writer }
+
+func (m maskedWriter) Write(b []byte) (int, error) {
+	if _, err := io.WriteString(m.w, MaskSecrets(string(b))); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// SetSecretsKeyFile names a file whose contents unlock .construct-secrets.
+func SetSecretsKeyFile(path string) {
+	secretsKeyFile = path
+}
+
+// SetSecretsPrompt installs an interactive fallback for the passphrase. The
+// CLI sets it only for runs that execute commands.
+func SetSecretsPrompt(fn func() (string, error)) {
+	secretsPrompt = fn
+}
+
+// SecretsPassphrase returns the store passphrase from --secrets-key-file,
+// CONSTRUCT_SECRETS_KEYFILE, CONSTRUCT_SECRETS_KEY, or the prompt, in that
+// order.
+func SecretsPassphrase() (string, error) {
+	if secretsPass != "" {
+		return secretsPass, nil
+	}
+	if path := cmp.Or(secretsKeyFile, os.Getenv("CONSTRUCT_SECRETS_KEYFILE")); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("secrets key file: %w", err)
+		}
+		secretsPass = strings.TrimSpace(string(data))
+	} else if key := os.Getenv("CONSTRUCT_SECRETS_KEY"); key != "" {
+		secretsPass = key
+	} else if secretsPrompt != nil {
+		key, err := secretsPrompt()
+		if err != nil {
+			return "", err
+		}
+		secretsPass = key
+	}
+	if secretsPass == "" {
+		return "", ErrSecretsLocked
+	}
+	return secretsPass, nil
+}
+
+type secretsFile struct {
+	Version int    `json:"version"`
+	Salt    string `json:"salt"`
+	Nonce   string `json:"nonce"`
+	Data    string `json:"data"`
+}
+
+func secretsAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, secretsKDFRounds, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ReadSecretStore decrypts a .construct-secrets file.
+func ReadSecretStore(path, passphrase string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f secretsFile
+	if err := json.Unmarshal(data, &f); err != nil || f.Version != secretsFormatV1 {
+		return nil, fmt.Errorf("%s is not a construct secrets file", path)
+	}
+	salt, err1 := base64.StdEncoding.DecodeString(f.Salt)
+	nonce, err2 := base64.StdEncoding.DecodeString(f.Nonce)
+	sealed, err3 := base64.StdEncoding.DecodeString(f.Data)
+	if err := errors.Join(err1, err2, err3); err != nil {
+		return nil, fmt.Errorf("%s is corrupted: %w", path, err)
+	}
+	aead, err := secretsAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("%s is corrupted: bad nonce", path)
+	}
+	plain, err := aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt %s: wrong passphrase or corrupted file", path)
+	}
+	values := map[string]string{}
+	if err := json.Unmarshal(plain, &values); err != nil {
+		return nil, fmt.Errorf("%s is corrupted: %w", path, err)
+	}
+	return values, nil
+}
+
+// WriteSecretStore encrypts values into path with a fresh salt and nonce.
+func WriteSecretStore(path, passphrase string, values map[string]string) error {
+	plain, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	aead, err := secretsAEAD(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(secretsFile{
+		Version: secretsFormatV1,
+		Salt:    base64.StdEncoding.EncodeToString(salt),
+		Nonce:   base64.StdEncoding.EncodeToString(nonce),
+		Data:    base64.StdEncoding.EncodeToString(aead.Seal(nil, nonce, plain, nil)),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(out, '\n'), 0600); err != nil {
+		return err
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		secretMu.Lock()
+		delete(secretStores, abs)
+		secretMu.Unlock()
+	}
+	return nil
+}
+
+// storeSecret looks name up in the store next to the declaring file,
+// decrypting it on first use.
+func (p *Parser) storeSecret(name string) (string, error) {
+	path, err := filepath.Abs(filepath.Join(filepath.Dir(p.InputFile), SecretsFileName))
+	if err != nil {
+		return "", err
+	}
+	secretMu.RLock()
+	values, ok := secretStores[path]
+	secretMu.RUnlock()
+	if !ok {
+		if !fileExistsAt(path) {
+			return "", fmt.Errorf("%s does not exist (add the value with `construct secrets set %s`)", relOrAbs(importBaseDir(p.InputFile), path), name)
+		}
+		pass, err := SecretsPassphrase()
+		if err != nil {
+			return "", err
+		}
+		if values, err = ReadSecretStore(path, pass); err != nil {
+			return "", err
+		}
+		secretMu.Lock()
+		secretStores[path] = values
+		secretMu.Unlock()
+	}
+	v, ok := values[name]
+	if !ok {
+		return "", fmt.Errorf("%s has no entry %q", SecretsFileName, name)
+	}
+	return v, nil
+}
+
+// parseSecret handles `secret name = value` (any var value: @ENV,
+// file("path"), ...) and bare `secret name`, which reads the store. A
+// locked store defers the error to execution so --list, lint, and
+// --dry-run still work without the passphrase.
+func (p *Parser) parseSecret(line string, lineNum int) error {
+	rest := strings.TrimSpace(strings.TrimPrefix(line, "secret"))
+	name, _, hasValue := strings.Cut(rest, "=")
+	name = strings.TrimSpace(name)
+	if !isValidIdent(name) {
+		return fmt.Errorf("invalid secret name %q (expected an identifier)", name)
+	}
+	if hasValue {
+		if err := p.parseVar("var "+rest, "global", lineNum); err != nil {
+			return err
+		}
+	} else {
+		value, err := p.storeSecret(name)
+		if errors.Is(err, ErrSecretsLocked) {
+			p.Data.lockedSecrets = append(p.Data.lockedSecrets, name)
+		} else if err != nil {
+			return fmt.Errorf("secret %q: %w", name, err)
+		}
+		p.Data.addVariable(&Variable{Name: name, Value: value, Scope: "global", file: p.InputFile})
+	}
+	p.Data.mu.Lock()
+	v := p.Data.variableMap["global."+name]
+	v.Secret = true
+	p.Data.mu.Unlock()
+	RegisterSecret(v.Value)
+	// A quoted value keeps its quotes, which the shell strips from output.
+	RegisterSecret(strings.Trim(v.Value, `"`))
+	for _, item := range v.List {
+		RegisterSecret(item)
+	}
+	return nil
+}
+
+// checkSecretsUnlocked fails a run whose Constfile declares store secrets
+// that could not be decrypted.
+func (d *ParsedData) checkSecretsUnlocked() error {
+	if len(d.lockedSecrets) == 0 {
+		return nil
+	}
+	return fmt.Errorf("secret %s is stored in %s, which is locked: %w", strings.Join(d.lockedSecrets, ", "), SecretsFileName, ErrSecretsLocked)
+}
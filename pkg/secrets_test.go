@@ -0,0 +1,122 @@
+package pkg
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSecretStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), SecretsFileName)
+	if err := WriteSecretStore(path, "correct horse", map[string]string{"token": "abc123"}); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := os.ReadFile(path)
+	if bytes.Contains(raw, []byte("abc123")) {
+		t.Fatal("store holds the value in plain text")
+	}
+	values, err := ReadSecretStore(path, "correct horse")
+	if err != nil || values["token"] != "abc123" {
+		t.Fatalf("ReadSecretStore = %v, %v", values, err)
+	}
+	if _, err := ReadSecretStore(path, "wrong"); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Errorf("wrong passphrase err = %v", err)
+	}
+}
+
+func TestSecretMaskerSplitWrites(t *testing.T) {
+	RegisterSecret("split-secret-value")
+	var out bytes.Buffer
+	m := newSecretMasker(&out)
+	for _, chunk := range []string{"a split-sec", "ret-val", "ue b\nprompt> split"} {
+		m.Write([]byte(chunk))
+	}
+	if got := out.String(); got != "a ***** b\nprompt> " {
+		t.Errorf("before flush = %q", got)
+	}
+	m.Flush()
+	if got := out.String(); got != "a ***** b\nprompt> split" {
+		t.Errorf("after flush = %q", got)
+	}
+}
+
+func TestSecretDeclarations(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("CONSTRUCT_SECRETS_KEY", "")
+	t.Setenv("TEST_DB_PASS", "env-pass-4821")
+	secretsPass = ""
+	t.Cleanup(func() { secretsPass = "" })
+
+	src := `secret db_pass = @TEST_DB_PASS
+secret api_token
+var header = Bearer &api_token
+
+show {
+    $ echo &db_pass &header
+    echo last=&last.output
+}
+`
+	file := filepath.Join(dir, "Constfile")
+	os.WriteFile(file, []byte(src), 0644)
+	parse := func() (*ParsedData, error) {
+		p, err := NewParser(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p.Parse()
+	}
+
+	if _, err := parse(); err == nil || !strings.Contains(err.Error(), "construct secrets set api_token") {
+		t.Fatalf("missing store err = %v", err)
+	}
+
+	if err := WriteSecretStore(filepath.Join(dir, SecretsFileName), "pw", map[string]string{"api_token": "tok-9f8e7d"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := data.checkSecretsUnlocked(); err == nil || !strings.Contains(err.Error(), "locked") {
+		t.Fatalf("locked store err = %v", err)
+	}
+
+	t.Setenv("CONSTRUCT_SECRETS_KEY", "pw")
+	data, err = parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := data.GetVariable("api_token", "global"); !v.Secret || v.Value != "tok-9f8e7d" {
+		t.Fatalf("api_token = %+v", v)
+	}
+
+	var out bytes.Buffer
+	e := NewExecutor(data, false, false)
+	e.SetBaseDir(t.TempDir())
+	e.SetStdoutSink(&out)
+	e.SetRecordRuns(true)
+	cmd, _ := data.GetCommand("show")
+	if err := e.Execute([]string{"show"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != "***** Bearer *****\nlast=***** Bearer *****\n" {
+		t.Errorf("output = %q", got)
+	}
+	if log := e.RunRecords()["show"].Log; strings.Contains(log, "tok-9f8e7d") || strings.Contains(log, "env-pass-4821") {
+		t.Errorf("run log leaks a secret: %q", log)
+	}
+	if key := e.cacheKey(cmd); strings.Contains(key, "tok-9f8e7d") {
+		t.Errorf("cache key leaks a secret: %q", key)
+	}
+}
+
+func TestQuotedSecretMasked(t *testing.T) {
+	if _, err := NewParserFromContent("Constfile", "secret dsn = \"pg://quoted-secret-77\"\n").Parse(); err != nil {
+		t.Fatal(err)
+	}
+	if got := MaskSecrets("dsn=pg://quoted-secret-77"); got != "dsn=*****" {
+		t.Errorf("masked = %q", got)
+	}
+}
@@ -0,0 +1,417 @@
+package pkg
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var procfileLineRe = regexp.MustCompile(`^([A-Za-z0-9_-]+):\s*(.*)$`)
+
+var procfilePortRe = regexp.MustCompile(`\$PORT\b|\$\{PORT\}`)
+
+// ImportProcfile converts a Procfile's `name: command` process types to
+// services. A process that reads $PORT gets `port auto`, with PORT set to
+// the picked port the way foreman sets it.
+func ImportProcfile(content string) (ImportResult, error) {
+	var out, flags strings.Builder
+	out.WriteString("# Imported from a Procfile by `construct import`. Review construct-import comments.\n\n")
+	taken := map[string]bool{}
+	res := ImportResult{}
+	for _, raw := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		m := procfileLineRe.FindStringSubmatch(line)
+		if m == nil || strings.TrimSpace(m[2]) == "" {
+			res.Flagged++
+			flags.WriteString("# construct-import: not a `name: command` process line\n# ")
+			flags.WriteString(line)
+			flags.WriteString("\n\n")
+			continue
+		}
+		name := uniqueName(commandName(m[1], false), taken)
+		script := strings.TrimSpace(m[2])
+		res.Commands++
+		// Heroku runs `release` once per deploy; it is not a process to keep up.
+		if m[1] == "release" {
+			res.Flagged++
+			out.WriteString("# construct-import: release phase imported as a regular command\n")
+			fmt.Fprintf(&out, "%s {\n    $ %s\n}\n\n", name, script)
+			continue
+		}
+		fmt.Fprintf(&out, "service %s {\n", name)
+		if procfilePortRe.MatchString(script) {
+			out.WriteString("    port auto\n")
+			fmt.Fprintf(&out, "    env { PORT=&%s.port }\n", name)
+		}
+		fmt.Fprintf(&out, "    $ %s\n}\n\n", script)
+	}
+	if res.Commands == 0 {
+		return ImportResult{}, fmt.Errorf("no Procfile process types found")
+	}
+	if flags.Len() > 0 {
+		out.WriteString("\n# ---- flagged during import ----\n")
+		out.WriteString(flags.String())
+	}
+	res.Constfile = FormatConstfile(out.String())
+	return parseChecked(res)
+}
+
+// composeService is the subset of a docker-compose service that converts.
+// Every other key is flagged in the service body.
+type composeService struct {
+	Image       string    `yaml:"image"`
+	Command     yaml.Node `yaml:"command"`
+	Ports       []any     `yaml:"ports"`
+	Environment yaml.Node `yaml:"environment"`
+	DependsOn   yaml.Node `yaml:"depends_on"`
+}
+
+var composeConvertedKeys = []string{"image", "command", "ports", "environment", "depends_on"}
+
+// composeIgnoredKeys describe the container rather than how to run it;
+// construct names and restarts services itself.
+var composeIgnoredKeys = []string{"container_name", "hostname", "restart", "labels", "stdin_open", "tty", "init"}
+
+var composeTopLevelKeys = []string{"version", "name", "services"}
+
+var composeInterpRe = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-[^}]*)?\}|\$\{[^}]*\}`)
+
+// ImportCompose converts a docker-compose file's services: `image` becomes
+// `container "image"`, the first published port becomes `port`, depends_on
+// becomes prerequisites, and environment becomes an env block. Builds,
+// volumes, networks, and healthchecks have no counterpart and are flagged.
+func ImportCompose(content string) (ImportResult, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return ImportResult{}, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return ImportResult{}, fmt.Errorf("no compose services found")
+	}
+	root := doc.Content[0]
+
+	var out, flags strings.Builder
+	out.WriteString("# Imported from a docker-compose file by `construct import`. Review construct-import comments.\n\n")
+	res := ImportResult{}
+	var services *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key := root.Content[i].Value
+		switch {
+		case key == "services":
+			services = root.Content[i+1]
+		case !slices.Contains(composeTopLevelKeys, key) && !strings.HasPrefix(key, "x-"):
+			res.Flagged++
+			fmt.Fprintf(&flags, "# construct-import: skipped top-level %s\n\n", key)
+		}
+	}
+	if services == nil || services.Kind != yaml.MappingNode || len(services.Content) == 0 {
+		return ImportResult{}, fmt.Errorf("no compose services found")
+	}
+
+	mapping := map[string]string{}
+	taken := map[string]bool{}
+	ports := map[string]composePortMap{} // service -> its first port, for hostname rewrites
+	for i := 0; i+1 < len(services.Content); i += 2 {
+		name := services.Content[i].Value
+		mapping[name] = uniqueName(commandName(name, false), taken)
+		var svc composeService
+		ports[name] = composePortMap{}
+		if services.Content[i+1].Decode(&svc) == nil && len(svc.Ports) > 0 {
+			if host, target, ok := composePort(svc.Ports[0]); ok {
+				ports[name] = composePortMap{host, target}
+			}
+		}
+	}
+	for i := 0; i+1 < len(services.Content); i += 2 {
+		name, node := services.Content[i].Value, services.Content[i+1]
+		var svc composeService
+		if err := node.Decode(&svc); err != nil {
+			return ImportResult{}, fmt.Errorf("service %s: %w", name, err)
+		}
+		var body strings.Builder
+		flag := func(format string, args ...any) {
+			res.Flagged++
+			body.WriteString("    # construct-import: ")
+			fmt.Fprintf(&body, format, args...)
+			body.WriteString("\n")
+		}
+
+		if node.Kind == yaml.MappingNode {
+			for j := 0; j+1 < len(node.Content); j += 2 {
+				key := node.Content[j].Value
+				if !slices.Contains(composeConvertedKeys, key) && !slices.Contains(composeIgnoredKeys, key) {
+					flag("skipped %s", composeKeyHint(key))
+				}
+			}
+		}
+
+		header := "service " + mapping[name]
+		if svc.Image != "" {
+			header += fmt.Sprintf(" container %q", svc.Image)
+		}
+		var deps []string
+		for _, d := range composeNames(&svc.DependsOn) {
+			if m, ok := mapping[d]; ok {
+				deps = append(deps, m)
+			} else {
+				flag("depends_on names unknown service %s", d)
+			}
+		}
+		if len(deps) > 0 {
+			header += " < " + strings.Join(deps, ", ")
+		}
+
+		for j, p := range svc.Ports {
+			host, target, ok := composePort(p)
+			switch {
+			case !ok:
+				flag("unsupported port mapping %v", p)
+			case j > 0:
+				flag("only the first port is checked for readiness; also published: %s", host)
+			case svc.Image != "":
+				fmt.Fprintf(&body, "    port %s:%s\n", host, target)
+			default:
+				fmt.Fprintf(&body, "    port %s\n", host)
+				if target != host {
+					flag("compose mapped host port %s to container port %s; without a container the service must listen on %s", host, target, host)
+				}
+			}
+		}
+
+		env, envFlags := composeEnv(&svc.Environment, ports)
+		for _, f := range envFlags {
+			flag("%s", f)
+		}
+		if len(env) > 0 {
+			body.WriteString("    env {\n")
+			for _, kv := range env {
+				body.WriteString("        ")
+				body.WriteString(kv)
+				body.WriteString("\n")
+			}
+			body.WriteString("    }\n")
+		}
+
+		switch script := composeCommand(&svc.Command); {
+		case script != "":
+			body.WriteString("    $ ")
+			body.WriteString(script)
+			body.WriteString("\n")
+		case svc.Image != "":
+			flag("the image's default command does not run under container; add it as a $ line")
+		default:
+			flag("no image or command to run")
+		}
+
+		out.WriteString(header)
+		out.WriteString(" {\n")
+		out.WriteString(body.String())
+		out.WriteString("}\n\n")
+		res.Commands++
+	}
+
+	if flags.Len() > 0 {
+		out.WriteString("\n# ---- flagged during import ----\n")
+		out.WriteString(flags.String())
+	}
+	res.Constfile = FormatConstfile(out.String())
+	return parseChecked(res)
+}
+
+// composeKeyHint says what to do instead for the compose keys that come up
+// most.
+func composeKeyHint(key string) string {
+	switch key {
+	case "build":
+		return "build (build the image in a regular command and name its tag in container)"
+	case "volumes":
+		return "volumes (the Constfile directory is mounted at /work)"
+	case "healthcheck":
+		return "healthcheck (use ready or health with a probe run on the host)"
+	case "env_file":
+		return "env_file (construct loads .env next to the Constfile)"
+	case "working_dir":
+		return "working_dir (use `in <dir>` relative to /work)"
+	case "entrypoint":
+		return "entrypoint (fold it into the $ line)"
+	}
+	return key
+}
+
+// composeNames reads depends_on in either its list or its map form.
+func composeNames(node *yaml.Node) []string {
+	var names []string
+	switch node.Kind {
+	case yaml.SequenceNode:
+		for _, n := range node.Content {
+			names = append(names, n.Value)
+		}
+	case yaml.MappingNode:
+		for i := 0; i < len(node.Content); i += 2 {
+			names = append(names, node.Content[i].Value)
+		}
+	}
+	return names
+}
+
+// composePort returns the host and container side of a ports entry:
+// "8080", "8080:80", "127.0.0.1:8080:80/tcp", or the long form.
+func composePort(p any) (host, target string, ok bool) {
+	switch v := p.(type) {
+	case int:
+		s := strconv.Itoa(v)
+		return s, s, true
+	case string:
+		v, _, _ = strings.Cut(v, "/")
+		parts := strings.Split(v, ":")
+		target = parts[len(parts)-1]
+		host = target
+		if len(parts) > 1 {
+			host = parts[len(parts)-2]
+		}
+		if _, err := strconv.Atoi(host); err != nil {
+			return "", "", false
+		}
+		if _, err := strconv.Atoi(target); err != nil {
+			return "", "", false
+		}
+		return host, target, true
+	case map[string]any:
+		t, tok := v["target"].(int)
+		h, hok := v["published"].(int)
+		if s, ok := v["published"].(string); ok {
+			n, err := strconv.Atoi(s)
+			h, hok = n, err == nil
+		}
+		if !tok {
+			return "", "", false
+		}
+		if !hok {
+			h = t
+		}
+		return strconv.Itoa(h), strconv.Itoa(t), true
+	}
+	return "", "", false
+}
+
+// composeEnv converts an environment list or map to KEY=VALUE pairs.
+// Entries without a value pass the host's variable through, which the
+// service's environment already does.
+func composeEnv(node *yaml.Node, ports map[string]composePortMap) (pairs, flagged []string) {
+	add := func(key, value string, hasValue bool) {
+		if !hasValue {
+			return
+		}
+		converted, ok := composeInterpolate(value)
+		if !ok {
+			flagged = append(flagged, fmt.Sprintf("environment %s=%s needs manual translation", key, value))
+			return
+		}
+		converted, unpublished := composeLocalhost(key, converted, ports)
+		for _, name := range unpublished {
+			flagged = append(flagged, fmt.Sprintf("environment %s points at service %s, which publishes no port", key, name))
+		}
+		pairs = append(pairs, key+"="+converted)
+	}
+	switch node.Kind {
+	case yaml.SequenceNode:
+		for _, n := range node.Content {
+			key, value, ok := strings.Cut(n.Value, "=")
+			add(key, value, ok)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			v := node.Content[i+1]
+			add(node.Content[i].Value, v.Value, v.Tag != "!!null")
+		}
+	}
+	return pairs, flagged
+}
+
+// composePortMap is a service's first compose port entry: the host port and
+// the container port it maps to, both empty for a service without ports.
+type composePortMap struct{ host, target string }
+
+// composeHostRe matches a hostname at the start of a value or after a URL's
+// // or user@, with an optional :port.
+var composeHostRe = regexp.MustCompile(`(^|//|@)([A-Za-z0-9_-]+)(:[0-9]+)?`)
+
+// composeLocalhost rewrites compose service hostnames in an env value
+// (postgres://db:5432/app, db:5432, or DB_HOST=db) to localhost, where
+// construct dev runs them, and a service's container port to the host port
+// it publishes. A bare value is only a hostname under a *HOST* key, so
+// CACHE_DRIVER=redis stays. unpublished lists the services referred to that
+// publish no port, which localhost won't reach.
+func composeLocalhost(key, value string, ports map[string]composePortMap) (string, []string) {
+	var out strings.Builder
+	var unpublished []string
+	last := 0
+	for _, m := range composeHostRe.FindAllStringSubmatchIndex(value, -1) {
+		name := value[m[4]:m[5]]
+		pm, isService := ports[name]
+		if !isService || m[1] < len(value) && !strings.ContainsRune("/?#", rune(value[m[1]])) {
+			continue // not a service, or part of a longer name (db.example.com)
+		}
+		if m[3] == 0 && m[6] < 0 && !strings.Contains(strings.ToUpper(key), "HOST") {
+			continue
+		}
+		out.WriteString(value[last:m[4]])
+		out.WriteString("localhost")
+		last = m[5]
+		switch {
+		case pm.host == "":
+			unpublished = append(unpublished, name)
+		case m[6] >= 0 && value[m[6]+1:m[7]] == pm.target:
+			out.WriteString(":" + pm.host)
+			last = m[7]
+		}
+	}
+	out.WriteString(value[last:])
+	return out.String(), unpublished
+}
+
+// composeInterpolate rewrites compose's ${VAR} and ${VAR:-default} to
+// @VAR and @VAR:-default. Other forms (${VAR?err}, ${VAR:+alt}) do not
+// convert.
+func composeInterpolate(s string) (string, bool) {
+	s = strings.ReplaceAll(s, "$$", "\x00")
+	ok := true
+	s = composeInterpRe.ReplaceAllStringFunc(s, func(m string) string {
+		sub := composeInterpRe.FindStringSubmatch(m)
+		if sub[1] == "" {
+			ok = false
+			return m
+		}
+		return "@" + sub[1] + sub[2]
+	})
+	if strings.Contains(s, "$") || strings.Contains(s, " #") {
+		ok = false
+	}
+	return strings.ReplaceAll(s, "\x00", "$"), ok
+}
+
+// composeCommand returns a service's command as a shell line; the exec
+// (list) form is quoted word by word.
+func composeCommand(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		// Compose splits a string command into words without a shell, so
+		// line breaks are only whitespace.
+		script := strings.ReplaceAll(strings.TrimSpace(node.Value), "\n", " ")
+		return strings.ReplaceAll(script, "$$", "$")
+	case yaml.SequenceNode:
+		words := make([]string, 0, len(node.Content))
+		for _, n := range node.Content {
+			words = append(words, strings.ReplaceAll(n.Value, "$$", "$"))
+		}
+		return shellQuoteWords(words)
+	}
+	return ""
+}
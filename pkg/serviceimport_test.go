@@ -0,0 +1,137 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+)
+
+func parseImported(t *testing.T, res ImportResult) *ParsedData {
+	t.Helper()
+	p := NewParserFromContent("imported.constfile", res.Constfile)
+	sp, err := p.Parse()
+	if err != nil {
+		t.Fatalf("generated Constfile does not parse: %v\n%s", err, res.Constfile)
+	}
+	return sp
+}
+
+func TestImportProcfile(t *testing.T) {
+	res, err := ImportProcfile(`# processes
+web: bundle exec puma -p $PORT
+worker: bundle exec sidekiq
+release: rake db:migrate
+not a process
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parseImported(t, res)
+	if res.Commands != 3 || res.Flagged != 2 {
+		t.Errorf("commands=%d flagged=%d, want 3 and 2:\n%s", res.Commands, res.Flagged, res.Constfile)
+	}
+	for _, want := range []string{
+		"service web {\n    port auto\n    env { PORT=&web.port }\n    $ bundle exec puma -p $PORT\n}",
+		"service worker {\n    $ bundle exec sidekiq\n}",
+		"# construct-import: release phase imported as a regular command\nrelease {",
+		"# construct-import: not a `name: command` process line\n# not a process",
+	} {
+		if !strings.Contains(res.Constfile, want) {
+			t.Errorf("missing %q:\n%s", want, res.Constfile)
+		}
+	}
+	if _, err := ImportProcfile("# nothing here\n"); err == nil {
+		t.Error("empty Procfile imported without error")
+	}
+}
+
+func TestImportCompose(t *testing.T) {
+	res, err := ImportCompose(`version: "3.9"
+services:
+  db:
+    image: postgres:16
+    ports: ["5432:5432"]
+    environment:
+      POSTGRES_PASSWORD: dev
+      POSTGRES_DB: ${DB_NAME:-app}
+    volumes:
+      - pgdata:/var/lib/postgresql/data
+    command: postgres -c log_statement=all
+  api:
+    build: .
+    image: acme/api
+    command: ["./api", "--listen", ":8080"]
+    ports:
+      - "8080:80"
+      - 9090
+    environment:
+      - DATABASE_URL=postgres://db/app
+      - PGADDR=db:5432
+      - DB_HOST=db
+      - CACHE_DRIVER=cache
+      - REDIS_URL=redis://cache:6379/0
+      - HOME
+    depends_on:
+      db:
+        condition: service_healthy
+  cache:
+    image: redis:7
+    environment:
+      API_URL: http://api:80/v1
+      DOCS: https://api.example.com
+volumes:
+  pgdata:
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp := parseImported(t, res)
+	if res.Commands != 3 {
+		t.Errorf("commands = %d, want 3", res.Commands)
+	}
+	for _, want := range []string{
+		"service db container \"postgres:16\" {",
+		"    port 5432:5432\n",
+		"        POSTGRES_DB=@DB_NAME:-app\n",
+		"    # construct-import: skipped volumes",
+		"    $ postgres -c log_statement=all\n",
+		"service api container \"acme/api\" < db {",
+		"    # construct-import: skipped build",
+		"    port 8080:80\n",
+		"also published: 9090",
+		"        DATABASE_URL=postgres://localhost/app\n",
+		"        PGADDR=localhost:5432\n",
+		"        DB_HOST=localhost\n",
+		"        CACHE_DRIVER=cache\n",
+		"        REDIS_URL=redis://localhost:6379/0\n",
+		"environment REDIS_URL points at service cache, which publishes no port",
+		"        API_URL=http://localhost:8080/v1\n",
+		"        DOCS=https://api.example.com\n",
+		"    $ ./api --listen :8080\n",
+		"service cache container \"redis:7\" {",
+		"the image's default command does not run under container",
+		"# construct-import: skipped top-level volumes",
+	} {
+		if !strings.Contains(res.Constfile, want) {
+			t.Errorf("missing %q:\n%s", want, res.Constfile)
+		}
+	}
+	if strings.Contains(res.Constfile, "HOME") {
+		t.Errorf("pass-through variable emitted:\n%s", res.Constfile)
+	}
+	var api *Command
+	for _, cmd := range sp.Commands {
+		if cmd.Name == "api" {
+			api = cmd
+		}
+	}
+	if api == nil || !api.IsService || api.Port != "8080" || api.Publish != "8080:80" || len(api.Prereqs) != 1 {
+		t.Fatalf("api = %+v", api)
+	}
+	if res.Flagged != 6 {
+		t.Errorf("flagged = %d, want 6:\n%s", res.Flagged, res.Constfile)
+	}
+
+	if _, err := ImportCompose("volumes: {}\n"); err == nil {
+		t.Error("compose file without services imported without error")
+	}
+}
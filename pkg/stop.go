@@ -0,0 +1,106 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	defaultStopSignal = "SIGTERM"
+	defaultStopGrace  = 10 * time.Second
+)
+
+// stopSignals are the signals `stop` accepts. Windows has no signals to
+// send a process tree, so there a stop always kills.
+var stopSignals = []string{"SIGTERM", "SIGINT", "SIGQUIT", "SIGHUP", "SIGKILL", "SIGUSR1", "SIGUSR2"}
+
+// StopSpec is a service's `stop SIGNAL after DURATION`: the signal
+// construct dev sends its process group, and how long it waits before
+// killing it.
+type StopSpec struct {
+	Signal string `json:"signal,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// StopSpecFor returns cmd's stop spec, or the default (SIGTERM after 10s).
+func StopSpecFor(cmd *Command) *StopSpec {
+	if cmd.Stop != nil {
+		return cmd.Stop
+	}
+	return &StopSpec{}
+}
+
+func (s *StopSpec) SignalName() string {
+	if s.Signal != "" {
+		return s.Signal
+	}
+	return defaultStopSignal
+}
+
+// Grace is how long a stopping service has to exit before it is killed.
+func (s *StopSpec) Grace() time.Duration {
+	if d, err := time.ParseDuration(s.After); err == nil {
+		return d
+	}
+	return defaultStopGrace
+}
+
+func (s *StopSpec) String() string {
+	return fmt.Sprintf("%s after %s", s.SignalName(), s.Grace())
+}
+
+// parseStop reads `stop SIGNAL`, `stop SIGNAL after 10s`, or
+// `stop after 10s`. ok is false for anything else, so a shell program
+// named stop still runs as a command.
+func parseStop(rest string) (*BodyStatement, bool, error) {
+	fields := strings.Fields(rest)
+	stmt := &BodyStatement{Type: StmtStop}
+	if len(fields) > 0 && fields[0] != "after" {
+		sig := strings.ToUpper(fields[0])
+		if !strings.HasPrefix(sig, "SIG") {
+			sig = "SIG" + sig
+		}
+		if !slices.Contains(stopSignals, sig) {
+			return nil, false, nil
+		}
+		stmt.Shell = sig
+		fields = fields[1:]
+	}
+	switch {
+	case len(fields) == 0 && stmt.Shell != "":
+	case len(fields) == 2 && fields[0] == "after":
+		if d, err := time.ParseDuration(fields[1]); err != nil || d < 0 {
+			return nil, true, fmt.Errorf("invalid stop grace period %q: expected a duration", fields[1])
+		}
+		stmt.Timeout = fields[1]
+	case stmt.Shell != "":
+		return nil, true, fmt.Errorf("malformed stop statement: expected `stop %s after <duration>`", stmt.Shell)
+	default:
+		return nil, false, nil
+	}
+	return stmt, true, nil
+}
+
+// OnStopBody returns the statements of a command's on_stop blocks.
+func OnStopBody(cmd *Command) []BodyStatement {
+	var body []BodyStatement
+	for _, stmt := range cmd.Body {
+		if stmt.Type == StmtOnStop {
+			body = append(body, stmt.ThenBody...)
+		}
+	}
+	return body
+}
+
+// RunServiceOnStop runs a service's on_stop blocks after it has exited.
+// They run to completion even while construct dev is shutting down.
+func (e *Executor) RunServiceOnStop(command *Command) error {
+	body := OnStopBody(command)
+	if len(body) == 0 {
+		return nil
+	}
+	return e.runServiceStmts(command, body, context.Background())
+}
@@ -0,0 +1,101 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServiceStopSpec(t *testing.T) {
+	src := `service db {
+    stop int after 30s
+    $ postgres
+    on_stop { $ echo flushed }
+}
+service web {
+    stop after 2s
+    $ serve
+}
+service api {
+    $ stop-and-start
+    stop the presses
+}
+`
+	data, err := NewParserFromContent("Constfile", src).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, _ := data.GetCommand("db")
+	if db.Stop == nil || db.Stop.String() != "SIGINT after 30s" {
+		t.Errorf("db stop = %+v", db.Stop)
+	}
+	if body := OnStopBody(db); len(body) != 1 || body[0].Shell != "$ echo flushed" {
+		t.Errorf("db on_stop = %+v", body)
+	}
+	web, _ := data.GetCommand("web")
+	if got := StopSpecFor(web).String(); got != "SIGTERM after 2s" {
+		t.Errorf("web stop = %s", got)
+	}
+	api, _ := data.GetCommand("api")
+	if api.Stop != nil || StopSpecFor(api).String() != "SIGTERM after 10s" {
+		t.Errorf("api stop = %+v (a shell line starting with stop is not a stop statement)", api.Stop)
+	}
+
+	for _, bad := range []string{"stop SIGTERM after soon", "stop SIGTERM in 10s"} {
+		if _, err := NewParserFromContent("Constfile", "service x {\n    "+bad+"\n    $ run\n}\n").Parse(); err == nil {
+			t.Errorf("%q parsed without an error", bad)
+		}
+	}
+
+	for _, src := range []string{"build {\n    stop int\n}\n", "test {\n    if true {\n        stop term after 1s\n    }\n}\n"} {
+		if _, err := NewParserFromContent("Constfile", src).Parse(); err == nil || !strings.Contains(err.Error(), "only allowed in a service") {
+			t.Errorf("stop outside a service: err = %v", err)
+		}
+	}
+	if _, err := NewParserFromContent("Constfile", "build {\n    stop the presses\n}\n").Parse(); err != nil {
+		t.Errorf("a shell line starting with stop: %v", err)
+	}
+}
+
+func TestServiceStopSignalAndOnStop(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no signals to trap on windows")
+	}
+	src := `service db {
+    stop SIGINT after 5s
+    $ trap 'echo flushing; exit 0' INT; echo up; while true; do sleep 0.05; done
+    on_stop {
+        $ echo on_stop ran
+    }
+}
+`
+	data, err := NewParserFromContent("Constfile", src).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, _ := data.GetCommand("db")
+	var out bytes.Buffer
+	e := NewExecutor(data, false, false)
+	e.SetBaseDir(t.TempDir())
+	e.SetStdoutSink(&out)
+	ctx, cancel := context.WithCancel(context.Background())
+	e.SetRunContext(ctx)
+	done := make(chan error, 1)
+	go func() { done <- e.RunServiceBody(db) }()
+	time.Sleep(300 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(4 * time.Second):
+		t.Fatal("service did not stop on SIGINT")
+	}
+	if err := e.RunServiceOnStop(db); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); !strings.Contains(got, "[db] flushing\n") || !strings.HasSuffix(got, "[db] on_stop ran\n") {
+		t.Errorf("output = %q", got)
+	}
+}
@@ -0,0 +1,318 @@
+package pkg
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var taskTemplateRe = regexp.MustCompile(`\{\{\s*([^{}]*?)\s*\}\}`)
+
+// taskIgnoredKeys only affect how go-task prints a task.
+var taskIgnoredKeys = []string{"desc", "summary", "silent", "label", "prefix"}
+
+var taskConvertedKeys = []string{"cmds", "cmd", "deps", "sources", "generates", "dir", "env"}
+
+// ImportTaskfile converts Taskfile.yml tasks to commands: deps become
+// prerequisites, sources file dependencies, generates `produces`, dir
+// `in`, and env an env block. The default task becomes `_`, and
+// {{.CLI_ARGS}} a variadic `args...` argument.
+func ImportTaskfile(content string) (ImportResult, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return ImportResult{}, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return ImportResult{}, fmt.Errorf("no Taskfile tasks found")
+	}
+	root := doc.Content[0]
+
+	var out, flags strings.Builder
+	res := ImportResult{}
+	flag := func(desc, orig string) {
+		res.Flagged++
+		flags.WriteString("# construct-import: ")
+		flags.WriteString(desc)
+		flags.WriteString("\n")
+		if orig != "" {
+			flags.WriteString("# ")
+			flags.WriteString(orig)
+			flags.WriteString("\n")
+		}
+		flags.WriteString("\n")
+	}
+
+	var tasks *yaml.Node
+	var globalEnv []string
+	known := map[string]bool{}
+	out.WriteString("# Imported from a Taskfile by `construct import`. Review construct-import comments.\n\n")
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i].Value, root.Content[i+1]
+		switch key {
+		case "version", "output", "silent":
+		case "tasks":
+			tasks = value
+		case "vars":
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				name, v := value.Content[j].Value, value.Content[j+1]
+				converted, ok := taskTemplate(v.Value, known, "")
+				if v.Kind != yaml.ScalarNode || !ok {
+					flag("variable "+name+" needs manual translation", "")
+					continue
+				}
+				known[name] = true
+				fmt.Fprintf(&out, "var %s = %s\n", name, converted)
+				res.Variables++
+			}
+			out.WriteString("\n")
+		case "env":
+			// Taskfile-level env applies to every task.
+			pairs, bad := taskEnv(value, known)
+			globalEnv = pairs
+			for _, b := range bad {
+				flag("environment "+b+" needs manual translation", "")
+			}
+		default:
+			flag("skipped top-level "+key, "")
+		}
+	}
+	if tasks == nil || tasks.Kind != yaml.MappingNode || len(tasks.Content) == 0 {
+		return ImportResult{}, fmt.Errorf("no Taskfile tasks found")
+	}
+
+	// `task` with no arguments runs the default task; construct runs `_`.
+	mapping := map[string]string{"default": "_"}
+	taken := map[string]bool{"_": true}
+	for i := 0; i+1 < len(tasks.Content); i += 2 {
+		if name := tasks.Content[i].Value; name != "default" {
+			mapping[name] = uniqueName(commandName(safeCommandName(name), true), taken)
+		}
+	}
+	for i := 0; i+1 < len(tasks.Content); i += 2 {
+		name, node := tasks.Content[i].Value, tasks.Content[i+1]
+		emitTask(&out, &res, name, node, mapping, known, globalEnv)
+	}
+	if flags.Len() > 0 {
+		out.WriteString("\n# ---- flagged during import ----\n")
+		out.WriteString(flags.String())
+	}
+	res.Constfile = FormatConstfile(out.String())
+	return parseChecked(res)
+}
+
+func emitTask(out *strings.Builder, res *ImportResult, name string, node *yaml.Node, mapping map[string]string, known map[string]bool, globalEnv []string) {
+	var body strings.Builder
+	flag := func(desc string, orig ...string) {
+		res.Flagged++
+		body.WriteString("    # construct-import: ")
+		body.WriteString(desc)
+		body.WriteString("\n")
+		for _, o := range orig {
+			body.WriteString("    # $ ")
+			body.WriteString(o)
+			body.WriteString("\n")
+		}
+	}
+	field := func(key string) *yaml.Node {
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			if node.Content[j].Value == key {
+				return node.Content[j+1]
+			}
+		}
+		return nil
+	}
+
+	// A task written as a bare list is just its cmds.
+	cmds := node
+	if node.Kind == yaml.MappingNode {
+		cmds = field("cmds")
+		if c := field("cmd"); c != nil {
+			cmds = &yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{c}}
+		}
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			key := node.Content[j].Value
+			if !slices.Contains(taskConvertedKeys, key) && !slices.Contains(taskIgnoredKeys, key) {
+				flag("skipped " + key)
+			}
+		}
+		if d := field("desc"); d != nil {
+			fmt.Fprintf(out, "# %s\n", d.Value)
+		}
+	}
+
+	header := mapping[name]
+	var args bool
+	scalars := func(n *yaml.Node) []string {
+		var vals []string
+		if n == nil {
+			return nil
+		}
+		for _, c := range n.Content {
+			if c.Kind != yaml.ScalarNode {
+				flag("skipped non-list entry in a list")
+				continue
+			}
+			v, ok := taskTemplate(c.Value, known, name)
+			if !ok {
+				flag("needs manual translation: " + c.Value)
+				continue
+			}
+			vals = append(vals, v)
+		}
+		return vals
+	}
+	if d := field("dir"); d != nil {
+		if v, ok := taskTemplate(d.Value, known, name); ok {
+			header += " in " + v
+		} else {
+			flag("dir needs manual translation: " + d.Value)
+		}
+	}
+	if gen := scalars(field("generates")); len(gen) > 0 {
+		header += " produces " + strings.Join(gen, ", ")
+	}
+	var prereqs []string
+	if deps := field("deps"); deps != nil {
+		for _, d := range deps.Content {
+			dep := d.Value
+			if d.Kind == yaml.MappingNode {
+				dep = ""
+				for j := 0; j+1 < len(d.Content); j += 2 {
+					switch d.Content[j].Value {
+					case "task":
+						dep = d.Content[j+1].Value
+					case "vars":
+						flag("dependency variables skipped")
+					}
+				}
+			}
+			if m, ok := mapping[dep]; ok {
+				prereqs = append(prereqs, m)
+			} else {
+				flag("dependency " + dep + " has no task")
+			}
+		}
+	}
+	// A source without a slash, glob, or dot (`Dockerfile`) would read as
+	// a command name.
+	for _, s := range scalars(field("sources")) {
+		if !strings.ContainsAny(s, "/*.") {
+			s = "./" + s
+		}
+		prereqs = append(prereqs, s)
+	}
+
+	env := slices.Clone(globalEnv)
+	if e := field("env"); e != nil {
+		pairs, bad := taskEnv(e, known)
+		env = append(env, pairs...)
+		for _, b := range bad {
+			flag("environment " + b + " needs manual translation")
+		}
+	}
+	if len(env) > 0 {
+		body.WriteString("    env {\n")
+		for _, kv := range env {
+			body.WriteString("        ")
+			body.WriteString(kv)
+			body.WriteString("\n")
+		}
+		body.WriteString("    }\n")
+	}
+
+	if cmds != nil {
+		for _, c := range cmds.Content {
+			script := c.Value
+			if c.Kind == yaml.MappingNode {
+				script = ""
+				for j := 0; j+1 < len(c.Content); j += 2 {
+					k, v := c.Content[j].Value, c.Content[j+1]
+					switch k {
+					case "cmd":
+						script = v.Value
+					case "task":
+						// The called task runs with its own deps and
+						// up-to-date check, wherever this body has cd'd.
+						if m, ok := mapping[v.Value]; ok {
+							fmt.Fprintf(&body, "    invoke %s\n", m)
+						} else {
+							flag("task " + v.Value + " has no task")
+						}
+					case "vars":
+						flag("task call variables skipped")
+					default:
+						flag("skipped " + k + " step")
+					}
+				}
+				if script == "" {
+					continue
+				}
+			}
+			script = strings.TrimSpace(script)
+			if strings.Contains(script, "{{.CLI_ARGS}}") {
+				args = true
+				script = strings.ReplaceAll(script, "{{.CLI_ARGS}}", "&args")
+			}
+			converted, ok := taskTemplate(script, known, name)
+			if !ok || strings.Contains(script, "\n") {
+				flag("needs manual translation", strings.Split(script, "\n")...)
+				continue
+			}
+			body.WriteString("    $ ")
+			body.WriteString(converted)
+			body.WriteString("\n")
+		}
+	}
+
+	if args {
+		header = strings.Replace(header, mapping[name], mapping[name]+" (args...)", 1)
+	}
+	if len(prereqs) > 0 {
+		header += " < " + strings.Join(prereqs, ", ")
+	}
+	out.WriteString(header)
+	out.WriteString(" {\n")
+	out.WriteString(body.String())
+	out.WriteString("}\n\n")
+	res.Commands++
+}
+
+// taskTemplate rewrites {{.VAR}} to &VAR for Taskfile variables and
+// {{.TASK}} to the task's name; other template actions do not convert.
+func taskTemplate(s string, known map[string]bool, task string) (string, bool) {
+	ok := true
+	s = taskTemplateRe.ReplaceAllStringFunc(s, func(m string) string {
+		expr := taskTemplateRe.FindStringSubmatch(m)[1]
+		name, isField := strings.CutPrefix(expr, ".")
+		switch {
+		case isField && known[name]:
+			return "&" + name
+		case isField && name == "TASK" && task != "":
+			return task
+		}
+		ok = false
+		return m
+	})
+	return s, ok
+}
+
+// taskEnv converts an env mapping; `sh:` values are computed by go-task
+// and come back as bad.
+func taskEnv(node *yaml.Node, known map[string]bool) (pairs, bad []string) {
+	for j := 0; j+1 < len(node.Content); j += 2 {
+		key, v := node.Content[j].Value, node.Content[j+1]
+		converted, ok := taskTemplate(v.Value, known, "")
+		if v.Kind != yaml.ScalarNode || !ok {
+			bad = append(bad, key)
+			continue
+		}
+		pairs = append(pairs, key+"="+converted)
+	}
+	return pairs, bad
+}
@@ -0,0 +1,65 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestImportTaskfile(t *testing.T) {
+	res, err := ImportTaskfile(`version: '3'
+vars:
+  BIN: app
+env:
+  CGO_ENABLED: "0"
+includes:
+  docs: ./docs
+tasks:
+  default:
+    cmds:
+      - task: build
+  build:
+    desc: Build the binary
+    deps: [gen]
+    sources: ["**/*.go", Dockerfile]
+    generates: ["bin/{{.BIN}}"]
+    cmds:
+      - go build -o bin/{{.BIN}} .
+  gen:
+    dir: internal
+    env:
+      GOFLAGS: -mod=mod
+    cmds:
+      - go generate ./...
+      - cmd: echo {{.TASK}}
+  test:
+    cmds:
+      - go test {{.CLI_ARGS}}
+      - echo {{.UNKNOWN}}
+    status:
+      - test -f done
+  "docs:serve":
+    - mkdocs serve
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Commands != 5 || res.Variables != 1 || res.Flagged != 3 {
+		t.Errorf("commands=%d vars=%d flagged=%d, want 5, 1, 3:\n%s", res.Commands, res.Variables, res.Flagged, res.Constfile)
+	}
+	for _, want := range []string{
+		"var BIN = app",
+		"_ {\n    env {\n        CGO_ENABLED=0\n    }\n    invoke build\n}",
+		"# Build the binary\nbuild produces bin/&BIN < gen, **/*.go, ./Dockerfile {",
+		"    $ go build -o bin/&BIN .\n",
+		"gen in internal {\n    env {\n        CGO_ENABLED=0\n        GOFLAGS=-mod=mod\n    }\n    $ go generate ./...\n    $ echo gen\n}",
+		"test (args...) {\n    # construct-import: skipped status\n",
+		"    $ go test &args\n",
+		"    # $ echo {{.UNKNOWN}}",
+		"docs-serve {",
+		"# construct-import: skipped top-level includes",
+	} {
+		if !strings.Contains(res.Constfile, want) {
+			t.Errorf("missing %q:\n%s", want, res.Constfile)
+		}
+	}
+}
@@ -3,6 +3,9 @@ package pkg
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"path"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -14,10 +17,17 @@ type ParsedData struct {
 	StateDecls []*Variable `json:"state,omitempty"`
 
 	SourceFiles []string `json:"source_files,omitempty"`
+	Packages    []string `json:"packages,omitempty"` // workspace package paths; nil outside a workspace
+
+	Profiles []*Profile `json:"profiles,omitempty"`
+	Profile  string     `json:"profile,omitempty"` // the selected profile, if any
 
 	variableMap       map[string]*Variable // key: "scope.name"
 	commandMap        map[string]*Command  // key: command name
+	instances         []*Command           // parameterized prereq nodes, e.g. build(os=linux)
 	indexedOutputRefs map[string]bool      // commands referenced as &name.N / &name.*
+	importOverrides   []*importOverride    // import ... with (...) entries, for lint
+	lockedSecrets     []string             // store secrets left unset for want of a passphrase
 
 	mu sync.RWMutex
 }
@@ -71,6 +81,13 @@ func (p *ParsedData) computeIndexedOutputRefs() map[string]bool {
 			}
 		}
 	}
+	for _, cmd := range p.Commands {
+		for prereq, alias := range cmd.PrereqAliases {
+			if refs[alias] {
+				refs[prereq] = true
+			}
+		}
+	}
 	return refs
 }
 
@@ -88,6 +105,9 @@ func (p *ParsedData) ensureIndexMapsLocked() {
 		for _, cmd := range p.Commands {
 			p.commandMap[cmd.Name] = cmd
 		}
+		for _, cmd := range p.instances {
+			p.commandMap[cmd.Name] = cmd
+		}
 	}
 }
 
@@ -103,6 +123,9 @@ func (p *ParsedData) buildIndexMaps() {
 	for _, cmd := range p.Commands {
 		p.commandMap[cmd.Name] = cmd
 	}
+	for _, cmd := range p.instances {
+		p.commandMap[cmd.Name] = cmd
+	}
 }
 
 func (p *ParsedData) addVariable(v *Variable) {
@@ -214,6 +237,118 @@ func (p *ParsedData) GetCommand(commandName string) (*Command, error) {
 	return nil, fmt.Errorf("cannot find command with name %s", commandName)
 }
 
+// Instance returns the node for a parameterized call such as build(os=linux),
+// creating it from the base command on first use. Each distinct binding is
+// its own command: it runs once, caches under its own key, and keeps its own
+// outputs. Instances stay out of Commands, so listings show only the base.
+func (p *ParsedData) Instance(call string) (*Command, error) {
+	base, args, ok, err := parsePrereqCall(call)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return p.GetCommand(call)
+	}
+	name := prereqCallName(base, args)
+	if cmd, err := p.GetCommand(name); err == nil {
+		return cmd, nil
+	}
+	baseCmd, err := p.GetCommand(base)
+	if err != nil {
+		return nil, err
+	}
+	if baseCmd.BaseName != "" {
+		return nil, fmt.Errorf("cannot parameterize %s: it is already an instance", base)
+	}
+	if baseCmd.Matrix != nil {
+		return nil, fmt.Errorf("cannot parameterize matrix command %s (select cells with %s[...])", base, base)
+	}
+	bound := make(map[string]string, len(args))
+	for _, kv := range args {
+		if !slices.ContainsFunc(baseCmd.Arguments, func(a *Argument) bool { return a.Name == kv[0] }) {
+			return nil, fmt.Errorf("command '%s' has no argument '%s'", base, kv[0])
+		}
+		bound[kv[0]] = kv[1]
+	}
+
+	inst := *baseCmd
+	inst.Name = name
+	inst.BaseName = base
+	inst.BoundArgs = bound
+	inst.IsDefault = false
+	inst.Prereqs = slices.Clone(baseCmd.Prereqs)
+	inst.PrereqOutput, inst.NamedOutput, inst.PrereqCmds = nil, nil, nil
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ensureIndexMapsLocked()
+	if cmd, ok := p.commandMap[name]; ok {
+		return cmd, nil // another goroutine won the race
+	}
+	p.instances = append(p.instances, &inst)
+	p.commandMap[name] = &inst
+	return &inst, nil
+}
+
+// Instances returns the nodes created from other commands: parameterized
+// prerequisites and matrix cells. They are not part of Commands.
+func (p *ParsedData) Instances() []*Command {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return slices.Clone(p.instances)
+}
+
+func (p *ParsedData) addInstance(cmd *Command) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ensureIndexMapsLocked()
+	if _, ok := p.commandMap[cmd.Name]; ok {
+		return fmt.Errorf("duplicate command %q", cmd.Name)
+	}
+	p.instances = append(p.instances, cmd)
+	p.commandMap[cmd.Name] = cmd
+	return nil
+}
+
+// MatrixTargets expands a cell pattern like build[linux,*] into the matching
+// cell names (path.Match per axis). Anything else comes back unchanged.
+func (p *ParsedData) MatrixTargets(pattern string) []string {
+	open := strings.IndexByte(pattern, '[')
+	if open <= 0 || !strings.HasSuffix(pattern, "]") || !strings.ContainsAny(pattern, "*?") {
+		return []string{pattern}
+	}
+	base, err := p.GetCommand(pattern[:open])
+	if err != nil || base.Matrix == nil {
+		return []string{pattern}
+	}
+	want := strings.Split(pattern[open+1:len(pattern)-1], ",")
+	var out []string
+	for _, cell := range base.Prereqs {
+		c, err := p.GetCommand(cell)
+		if err != nil || c.BaseName != base.Name {
+			continue
+		}
+		values := c.matrixValues()
+		if len(values) != len(want) {
+			continue
+		}
+		match := true
+		for i, w := range want {
+			if ok, _ := path.Match(strings.TrimSpace(w), values[i]); !ok {
+				match = false
+				break
+			}
+		}
+		if match {
+			out = append(out, cell)
+		}
+	}
+	if len(out) == 0 {
+		return []string{pattern}
+	}
+	return out
+}
+
 func (p *ParsedData) GetDefaultCommand() (*Command, error) {
 	for _, command := range p.Commands {
 		if command.IsDefault {
@@ -240,17 +375,21 @@ func (p *ParsedData) GlobalVariableSnapshot() map[string]string {
 type Argument struct {
 	Name       string `json:"name"`
 	IsOptional bool   `json:"is_optional"`
+	IsVariadic bool   `json:"is_variadic,omitempty"` // name...: binds a list of words
 	Default    string `json:"default,omitempty"`
 }
 
 type Variable struct {
-	Name   string   `json:"name"`
-	Value  string   `json:"value"`
-	Scope  string   `json:"scope"`
-	IsList bool     `json:"is_list,omitempty"`
-	List   []string `json:"list,omitempty"`
+	Name    string   `json:"name"`
+	Value   string   `json:"value"`
+	Scope   string   `json:"scope"`
+	IsList  bool     `json:"is_list,omitempty"`
+	List    []string `json:"list,omitempty"`
+	Private bool     `json:"private,omitempty"` // `private var`: unreachable from importing files
+	Secret  bool     `json:"secret,omitempty"`  // `secret`: value is masked in all output
 
 	refs []string // &names in the raw value, for cache-key scoping
+	file string   // declaring Constfile, for private checks
 }
 
 func (p *ParsedData) SetVariableValue(name, scope string, v Value) {
@@ -316,6 +455,19 @@ func StripService(line string) (string, bool) {
 	return rest, true
 }
 
+func StripPrivate(line string) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+	rest, ok := strings.CutPrefix(trimmed, "private ")
+	if !ok {
+		return line, false
+	}
+	rest = strings.TrimLeft(rest, " 	")
+	if rest == "" || !isCommandNameStart(rest[0]) {
+		return line, false
+	}
+	return rest, true
+}
+
 func isCommandNameStart(c byte) bool {
 	return c == '_' || c == '|' ||
 		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
@@ -351,8 +503,16 @@ const (
 	StmtPrompt     = "prompt"
 	StmtInput      = "input"
 	StmtPort       = "port"
+	StmtReady      = "ready"
+	StmtHealth     = "health"
+	StmtStop       = "stop"
+	StmtOnStop     = "on_stop"
 )
 
+// PortAuto is the `port auto` value: construct dev gives the service a free
+// port at startup.
+const PortAuto = "auto"
+
 type SwitchCase struct {
 	Values     []string        `json:"values,omitempty"`
 	IsDefault  bool            `json:"is_default,omitempty"`
@@ -390,20 +550,33 @@ type BodyStatement struct {
 type Command struct {
 	Name            string      `json:"name"`
 	SourceFile      string      `json:"source_file,omitempty"`
+	Package         string      `json:"package,omitempty"` // workspace package path (services/api); "" for the root
 	CloudAccessible bool        `json:"cloud_accessible"`
 	IsDefault       bool        `json:"is_default"`
 	IsService       bool        `json:"is_service,omitempty"`
-	Port            string      `json:"port,omitempty"`
+	Port            string      `json:"port,omitempty"`    // a number, or PortAuto
+	Publish         string      `json:"publish,omitempty"` // HOST:CONTAINER from `port 8080:80`, published by a container run
+	Ready           *Probe      `json:"ready,omitempty"`   // service readiness check, instead of the port
+	Health          *Probe      `json:"health,omitempty"`  // periodic liveness check under construct dev
+	Stop            *StopSpec   `json:"stop,omitempty"`
 	LazyEval        *LazyOutput `json:"lazy_output"`
 
 	cacheGlobals      []string          // globals the command's refs reach, for cache keys
 	cacheGlobalsExact bool              // false (hand-built data) keys on every global
 	argKey            string            // flag-set scope, fixed at registration so renames keep args resolvable
+	matrixPrereqs     []string          // header prereqs of an expanded matrix; Prereqs then lists its cells
 	PrereqOutput      []string          `json:"prereq_output"`
 	NamedOutput       map[string]string `json:"named_output"`
 	Arguments         []*Argument       `json:"arguments"`
 	Prereqs           []string          `json:"prereqs"`
 	PrereqDirs        map[string]string `json:"prereq_dirs,omitempty"`
+	PrereqAliases     map[string]string `json:"prereq_aliases,omitempty"`    // prereq -> `as` name for its outputs
+	PrereqConds       map[string]string `json:"prereq_conds,omitempty"`      // prereq -> `if` condition; the edge is pruned when false
+	PrereqOrderOnly   map[string]bool   `json:"prereq_order_only,omitempty"` // prereqs after `|`: run first, but never make this command stale
+	Guard             string            `json:"guard,omitempty"`             // header `if` condition; the command is skipped when false
+	BaseName          string            `json:"base_name,omitempty"`         // set on parameterized instances
+	BoundArgs         map[string]string `json:"bound_args,omitempty"`        // instance args, or a matrix cell's axis values
+	Matrix            *Matrix           `json:"matrix,omitempty"`
 	FileDeps          []string          `json:"file_deps"`
 	Produces          []string          `json:"produces,omitempty"`
 	OnChange          []string          `json:"onchange,omitempty"`
@@ -411,15 +584,95 @@ type Command struct {
 	WorkDir           string            `json:"work_dir"`
 	Container         string            `json:"container,omitempty"`
 	Manual            bool              `json:"manual,omitempty"`
+	Private           bool              `json:"private,omitempty"` // callable only from its own file; hidden from --list
 	Timeout           string            `json:"timeout,omitempty"`
 	Body              []BodyStatement   `json:"body"`
 	SourceLine        int               `json:"source_line,omitempty"`
 	Description       string            `json:"description,omitempty"`
+	Signature         *CloudSignature   `json:"signature,omitempty"` // cloud file definitions only
+}
+
+// Matrix is a header `matrix` clause. The command expands into one node per
+// combination of axis values (build[linux,amd64]), after include/exclude.
+type Matrix struct {
+	Axes    []MatrixAxis        `json:"axes"`
+	Include []map[string]string `json:"include,omitempty"`
+	Exclude []map[string]string `json:"exclude,omitempty"`
+}
+
+type MatrixAxis struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// Cells returns the axis bindings in order: the cross product (last axis
+// varying fastest) minus excluded combinations, plus any included ones.
+func (m *Matrix) Cells() []map[string]string {
+	cells := []map[string]string{{}}
+	for _, axis := range m.Axes {
+		var next []map[string]string
+		for _, cell := range cells {
+			for _, v := range axis.Values {
+				c := maps.Clone(cell)
+				c[axis.Name] = v
+				next = append(next, c)
+			}
+		}
+		cells = next
+	}
+	cells = slices.DeleteFunc(cells, func(cell map[string]string) bool {
+		return slices.ContainsFunc(m.Exclude, func(rule map[string]string) bool { return matrixRuleMatches(rule, cell) })
+	})
+	for _, inc := range m.Include {
+		if !slices.ContainsFunc(cells, func(cell map[string]string) bool { return maps.Equal(cell, inc) }) {
+			cells = append(cells, inc)
+		}
+	}
+	return cells
+}
+
+func (m *Matrix) CellName(base string, cell map[string]string) string {
+	values := make([]string, len(m.Axes))
+	for i, axis := range m.Axes {
+		values[i] = cell[axis.Name]
+	}
+	return base + "[" + strings.Join(values, ",") + "]"
+}
+
+func matrixRuleMatches(rule, cell map[string]string) bool {
+	for k, v := range rule {
+		if cell[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// HeaderPrereqs returns the prerequisites written in the command's header.
+// They differ from Prereqs only for an expanded matrix, whose Prereqs are
+// its cells.
+func (c *Command) HeaderPrereqs() []string {
+	if c.matrixPrereqs != nil {
+		return c.matrixPrereqs
+	}
+	return c.Prereqs
+}
+
+// matrixValues returns a cell's axis values in the name's order.
+func (c *Command) matrixValues() []string {
+	open := strings.IndexByte(c.Name, '[')
+	if open < 0 || !strings.HasSuffix(c.Name, "]") {
+		return nil
+	}
+	return strings.Split(c.Name[open+1:len(c.Name)-1], ",")
 }
 
 func (c *Command) flagScope() string {
 	if c.argKey != "" {
 		return c.argKey
 	}
+	if c.BaseName != "" {
+		return c.BaseName // unbound args of an instance come from the base's flags
+	}
 	return c.Name
 }
@@ -35,6 +35,7 @@ type UIHeaderPatch struct {
 	IsDefault       *bool             `json:"is_default,omitempty"`
 	CloudAccessible *bool             `json:"cloud_accessible,omitempty"`
 	Manual          *bool             `json:"manual,omitempty"`
+	Private         *bool             `json:"private,omitempty"`
 	Arguments       []*Argument       `json:"arguments,omitempty"`
 	Prereqs         *[]string         `json:"prereqs,omitempty"`
 	FileDeps        *[]string         `json:"file_deps,omitempty"`
@@ -72,6 +73,7 @@ type UIHeaderSummary struct {
 	IsDefault  bool              `json:"is_default,omitempty"`
 	Cloud      bool              `json:"cloud,omitempty"`
 	Manual     bool              `json:"manual,omitempty"`
+	Private    bool              `json:"private,omitempty"`
 }
 
 type UICommandState struct {
@@ -159,7 +161,7 @@ func (d *UIDoc) scanImports(path string) {
 			continue
 		}
 		spec, err := parseImportSpec(t)
-		if err != nil || spec.isGit {
+		if err != nil || spec.isGit || spec.isURL {
 			continue
 		}
 		p := spec.path
@@ -227,6 +229,8 @@ func uiClassifyPrereqsLenient(data *ParsedData) {
 			}
 			if _, err := data.GetCommand(prereq); err == nil {
 				cmdDeps = append(cmdDeps, prereq)
+			} else if _, _, ok, _ := parsePrereqCall(prereq); ok {
+				cmdDeps = append(cmdDeps, prereq)
 			} else if IsFileDep(prereq) {
 				fileDeps = append(fileDeps, prereq)
 			} else {
@@ -375,6 +379,9 @@ func uiApplyHeaderPatch(c *Command, h *UIHeaderPatch) {
 	if h.Manual != nil {
 		c.Manual = *h.Manual
 	}
+	if h.Private != nil {
+		c.Private = *h.Private
+	}
 	if h.Arguments != nil {
 		c.Arguments = h.Arguments
 	}
@@ -748,7 +755,7 @@ func uiStmtTree(stmts []BodyStatement, bodyEnd int, lines []string) []UIStmt {
 			}
 		case StmtOnFail:
 			st.Children = uiStmtTree(s.OnFailBody, 0, lines)
-		case StmtInDir, StmtLock:
+		case StmtInDir, StmtLock, StmtOnStop:
 			st.Children = uiStmtTree(s.ThenBody, 0, lines)
 		}
 		for _, k := range st.Children {
@@ -833,6 +840,8 @@ func uiStmtSummary(s *BodyStatement) string {
 		return "lock " + s.Shell
 	case StmtOnFail:
 		return "onfail { ... }"
+	case StmtOnStop:
+		return "on_stop { ... }"
 	case StmtFail:
 		return "fail " + s.Message
 	case StmtContinue:
@@ -1052,6 +1061,7 @@ func uiHeaderSummary(c *Command) *UIHeaderSummary {
 		IsDefault:  c.IsDefault,
 		Cloud:      c.CloudAccessible,
 		Manual:     c.Manual,
+		Private:    c.Private,
 	}
 }
 
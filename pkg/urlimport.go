@@ -0,0 +1,250 @@
+package pkg
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// urlSource is a parsed `import url` spec:
+//
+//	import url "https://host/recipes-1.2.tar.gz#sha256=<hex>" as recipes
+type urlSource struct {
+	spec   string
+	url    string // without the fragment
+	sha256 string // expected archive digest from #sha256=
+	name   string // archive file name, which picks the extractor
+}
+
+func parseURLSpec(spec string) (urlSource, error) {
+	src := urlSource{spec: strings.Trim(strings.TrimSpace(spec), `"`)}
+	raw, frag, _ := strings.Cut(src.spec, "#")
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return src, fmt.Errorf("url import %q: expected an http(s) URL", src.spec)
+	}
+	src.url = raw
+	src.name = path.Base(u.Path)
+	if frag == "" {
+		return src, fmt.Errorf("url import %q: archives must be pinned with #sha256=<hex>", src.spec)
+	}
+	sum, ok := strings.CutPrefix(frag, "sha256=")
+	if !ok {
+		return src, fmt.Errorf("url import %q: unknown fragment %q (expected #sha256=<hex>)", src.spec, frag)
+	}
+	if b, err := hex.DecodeString(sum); err != nil || len(b) != sha256.Size {
+		return src, fmt.Errorf("url import %q: #sha256= needs 64 hex digits", src.spec)
+	}
+	src.sha256 = strings.ToLower(sum)
+	if !isArchiveName(src.name) {
+		return src, fmt.Errorf("url import %q: unsupported archive %q (supported: .zip, .tar, .tar.gz, .tgz, .tar.bz2)", src.spec, src.name)
+	}
+	return src, nil
+}
+
+func isArchiveName(name string) bool {
+	lower := strings.ToLower(name)
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz", ".tar.bz2"} {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// archiveExt returns the archive extension name ends with, as written.
+func archiveExt(name string) string {
+	for _, ext := range []string{".tar.gz", ".tar.bz2", ".tgz", ".tar", ".zip"} {
+		if strings.HasSuffix(strings.ToLower(name), ext) {
+			return name[len(name)-len(ext):]
+		}
+	}
+	return ""
+}
+
+// defaultNamespace names an import by its archive: recipes-1.2.tar.gz -> recipes.
+func (src urlSource) defaultNamespace() string {
+	name := strings.TrimSuffix(src.name, archiveExt(src.name))
+	if i := strings.LastIndexAny(name, "-_"); i > 0 && i+1 < len(name) && name[i+1] >= '0' && name[i+1] <= '9' {
+		name = name[:i]
+	}
+	return name
+}
+
+// ensureURLImport downloads, verifies, and extracts an archive import under
+// .construct-cache/imports/, pinning its digest in .construct.lock. Once
+// extracted, the cached copy is used without touching the network.
+func ensureURLImport(spec, baseDir string) (string, string, error) {
+	return urlImportFile(spec, baseDir, true)
+}
+
+// urlImportFile is gitImportFile for archive imports.
+func urlImportFile(spec, baseDir string, preferVendor bool) (string, string, error) {
+	src, err := parseURLSpec(spec)
+	if err != nil {
+		return "", "", err
+	}
+	root := importRootDir(baseDir)
+	lockPath := importLockPath(root)
+	lock := loadImportLock(lockPath)
+	entry, locked := lock.Imports[src.spec]
+	if frozenLockfile && !locked {
+		return "", "", fmt.Errorf("import %q is not pinned in %s (--frozen-lockfile)", src.spec, filepath.Base(lockPath))
+	}
+	if locked && preferVendor {
+		if file, ok, err := vendoredImport(root, src.spec, entry); ok || err != nil {
+			return file, src.defaultNamespace(), err
+		}
+	}
+
+	dir := filepath.Join(root, CacheDirName(), "imports", shortHash(src.spec))
+	srcDir := filepath.Join(dir, "src")
+	fetched := false
+	if !locked || !dirExists(srcDir) {
+		want := src.sha256
+		if locked {
+			want = strings.TrimPrefix(entry.Rev, "sha256:")
+		}
+		sum, err := fetchArchive(src, dir, srcDir, want)
+		if err != nil {
+			return "", "", fmt.Errorf("import %q: %w", src.spec, err)
+		}
+		if !locked {
+			entry = ImportLockEntry{Kind: "url", URL: src.url, Rev: "sha256:" + sum}
+		}
+		entry.Dir = dir
+		fetched = true
+		fmt.Fprintf(os.Stderr, "(import: fetched %s, sha256 %s)\n", src.name, sum[:12])
+	}
+
+	file, err := archiveConstfile(srcDir)
+	if err != nil {
+		return "", "", fmt.Errorf("import %q: %w", src.spec, err)
+	}
+	if err := pinImport(lock, lockPath, src.spec, entry, file, fetched); err != nil {
+		return "", "", err
+	}
+	return file, src.defaultNamespace(), nil
+}
+
+// fetchArchive downloads src into dir, checks its sha256 against want (when
+// set), and extracts it to srcDir. It returns the archive's digest.
+func fetchArchive(src urlSource, dir, srcDir, want string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	archive := filepath.Join(dir, src.name)
+	sum, err := downloadFile(src.url, archive)
+	if err != nil {
+		return "", err
+	}
+	if want != "" && sum != want {
+		os.Remove(archive)
+		return "", fmt.Errorf("sha256 mismatch: downloaded %s, expected %s", sum, want)
+	}
+
+	// Extract next to the final location and rename, so an interrupted
+	// extract never leaves a half-populated src/ that looks cached.
+	tmp, err := os.MkdirTemp(dir, "extract-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+	if err := extractArchive(archive, src.name, tmp); err != nil {
+		return "", err
+	}
+	os.RemoveAll(srcDir)
+	if err := os.Rename(tmp, srcDir); err != nil {
+		return "", err
+	}
+	return sum, nil
+}
+
+func downloadFile(rawURL, dst string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GET %s: %s", rawURL, resp.Status)
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), resp.Body); err != nil {
+		f.Close()
+		return "", fmt.Errorf("GET %s: %w", rawURL, err)
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// archiveConstfile finds the Constfile in an extracted archive: at the top
+// level, or inside the single directory most release tarballs wrap it in.
+func archiveConstfile(srcDir string) (string, error) {
+	file := filepath.Join(srcDir, "Constfile")
+	if _, err := os.Stat(file); err == nil {
+		return file, nil
+	}
+	entries, err := os.ReadDir(srcDir)
+	if err == nil && len(entries) == 1 && entries[0].IsDir() {
+		file = filepath.Join(srcDir, entries[0].Name(), "Constfile")
+		if _, err := os.Stat(file); err == nil {
+			return file, nil
+		}
+	}
+	return "", fmt.Errorf("no Constfile found in the archive")
+}
+
+// verifyURLImport is `import verify` for an archive import: the kept archive
+// must still hash to the pinned digest and the extracted recipe must match
+// the lock's content hash. A missing extraction is fetched first.
+func verifyURLImport(root, key string, entry ImportLockEntry) bool {
+	src, err := parseURLSpec(key)
+	if err != nil {
+		fmt.Printf("%s: %v\n", key, err)
+		return false
+	}
+	dir := entry.Dir
+	if dir == "" {
+		dir = filepath.Join(root, CacheDirName(), "imports", shortHash(key))
+	}
+	srcDir := filepath.Join(dir, "src")
+	want := strings.TrimPrefix(entry.Rev, "sha256:")
+	if !dirExists(srcDir) {
+		if _, err := fetchArchive(src, dir, srcDir, want); err != nil {
+			fmt.Printf("%s: fetch failed: %v\n", key, err)
+			return false
+		}
+	}
+	if sum := hashFile(filepath.Join(dir, src.name)); sum != "" && sum != want {
+		fmt.Printf("%s: cached archive sha256 %s does not match locked %s\n", key, sum[:12], shortRev(want))
+		return false
+	}
+	file, err := archiveConstfile(srcDir)
+	if err != nil {
+		fmt.Printf("%s: %v\n", key, err)
+		return false
+	}
+	return verifyImportHash(key, entry, file)
+}
@@ -0,0 +1,132 @@
+package pkg
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func tarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for name, body := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(body)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(body))
+	}
+	tw.Close()
+	gw.Close()
+	return buf.Bytes()
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestParseURLSpec(t *testing.T) {
+	sum := strings.Repeat("ab", 32)
+	src, err := parseURLSpec(`"https://host/recipes-1.2.tar.gz#sha256=` + sum + `"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src.url != "https://host/recipes-1.2.tar.gz" || src.sha256 != sum || src.defaultNamespace() != "recipes" {
+		t.Errorf("got %+v (ns %q)", src, src.defaultNamespace())
+	}
+	for _, bad := range []string{
+		"ftp://host/r.tar.gz",
+		"https://host/r.tar.gz",
+		"https://host/r.tar.gz#md5=abc",
+		"https://host/r.tar.gz#sha256=abc",
+		"https://host/recipes.rar",
+	} {
+		if _, err := parseURLSpec(bad); err == nil {
+			t.Errorf("%s: expected an error", bad)
+		}
+	}
+}
+
+func TestURLImport(t *testing.T) {
+	archive := tarGz(t, map[string]string{
+		"recipes-1.2/Constfile":     "import \"lib.constfile\"\n\nhello {\n  $ echo hi\n}\n",
+		"recipes-1.2/lib.constfile": "lib-cmd {\n  $ echo lib\n}\n",
+	})
+	sum := sha256.Sum256(archive)
+	digest := hex.EncodeToString(sum[:])
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(archive)
+	}))
+	defer srv.Close()
+
+	root := t.TempDir()
+	spec := srv.URL + "/recipes-1.2.tar.gz#sha256=" + digest
+	parse := func(constfile string) (*ParsedData, error) {
+		os.WriteFile(filepath.Join(root, "Constfile"), []byte(constfile), 0644)
+		p, err := NewParser(filepath.Join(root, "Constfile"))
+		if err != nil {
+			return nil, err
+		}
+		return p.Parse()
+	}
+
+	data, err := parse("import url \"" + spec + "\"\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"recipes.hello", "recipes.lib-cmd"} {
+		if _, err := data.GetCommand(name); err != nil {
+			t.Errorf("%s missing: %v", name, err)
+		}
+	}
+	entry := loadImportLock(importLockPath(root)).Imports[spec]
+	if entry.Kind != "url" || entry.Rev != "sha256:"+digest || !strings.HasPrefix(entry.Hash, "sha256:") {
+		t.Fatalf("lock entry = %+v", entry)
+	}
+
+	// Offline after the first fetch.
+	srv.Close()
+	if _, err := parse("import url \"" + spec + "\" as r\n"); err != nil {
+		t.Fatalf("cached archive import: %v", err)
+	}
+	if failed, err := VerifyImports(root); err != nil || failed != 0 {
+		t.Errorf("verify: failed=%d err=%v", failed, err)
+	}
+}
+
+func TestURLImportRejectsBadArchives(t *testing.T) {
+	good := tarGz(t, map[string]string{"Constfile": "hello {\n  $ echo hi\n}\n"})
+	evil := tarGz(t, map[string]string{"../escape/Constfile": "x {\n  $ true\n}\n"})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "evil") {
+			w.Write(evil)
+			return
+		}
+		w.Write(good)
+	}))
+	defer srv.Close()
+
+	cases := map[string]string{
+		"checksum mismatch": srv.URL + "/good.tar.gz#sha256=" + strings.Repeat("0", 64),
+		"path traversal":    srv.URL + "/evil.tar.gz#sha256=" + sha256Hex(evil),
+	}
+	for name, spec := range cases {
+		root := t.TempDir()
+		if _, _, err := ensureURLImport(spec, root); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+		if _, err := os.Stat(filepath.Join(root, "escape")); err == nil {
+			t.Errorf("%s: archive wrote outside the cache", name)
+		}
+	}
+}
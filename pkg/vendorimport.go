@@ -0,0 +1,138 @@
+package pkg
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// vendorDirName holds checked-in copies of remote imports, one directory per
+// lock entry, for builds that cannot reach git or the artifact server.
+const vendorDirName = "construct_vendor"
+
+func vendorDir(root, key string) string {
+	return filepath.Join(root, vendorDirName, vendorName(key))
+}
+
+// vendorName names a vendored import after its source, so a reviewer can tell
+// the directories apart: acme/recipes/go@v1.2 -> acme_recipes_go@v1.2, and
+// https://host/recipes-1.2.tar.gz#sha256=... -> recipes-1.2.
+func vendorName(key string) string {
+	if src, err := parseURLSpec(key); err == nil {
+		return sanitizeVendorName(strings.TrimSuffix(src.name, archiveExt(src.name)))
+	}
+	src, err := parseGitSpec(key)
+	if err != nil {
+		return shortHash(key)
+	}
+	name := src.repo
+	if rest := strings.TrimSuffix(src.url, ".git"); strings.Count(rest, "/") > 0 {
+		// The owner keeps forks of the same repository apart.
+		rest = strings.TrimSuffix(rest, "/"+src.repo)
+		if i := strings.LastIndexAny(rest, "/:"); i >= 0 && i+1 < len(rest) {
+			name = rest[i+1:] + "_" + name
+		}
+	}
+	if src.subPath != "" {
+		name += "_" + src.subPath
+	}
+	if src.ref != "" {
+		name += "@" + src.ref
+	}
+	return sanitizeVendorName(name)
+}
+
+func sanitizeVendorName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '@':
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// vendoredImport returns the vendored Constfile for a locked import. ok is
+// false when the import is not vendored; a vendored copy whose content no
+// longer matches the lock is an error rather than a silent fallback.
+func vendoredImport(root, key string, entry ImportLockEntry) (file string, ok bool, err error) {
+	file = filepath.Join(vendorDir(root, key), "Constfile")
+	if _, statErr := os.Stat(file); statErr != nil {
+		return "", false, nil
+	}
+	hash, err := importClosureHash(file)
+	if err != nil {
+		return "", true, fmt.Errorf("import %q: %w", key, err)
+	}
+	if hash != entry.Hash {
+		return "", true, fmt.Errorf("import %q: vendored copy in %s does not match .construct.lock (got %s, locked %s); rerun `construct import vendor`",
+			key, relOrAbs(root, filepath.Dir(file)), shortHashValue(hash), shortHashValue(entry.Hash))
+	}
+	return file, true, nil
+}
+
+// VendorImports copies the Constfile closure of each locked import (or just
+// specs, when given) into construct_vendor/, fetching any that are not cached
+// yet. The parser then prefers the vendored copies.
+func VendorImports(baseDir string, specs []string) (int, error) {
+	root := importRootDir(baseDir)
+	lockPath := importLockPath(root)
+	lock := loadImportLock(lockPath)
+	if len(lock.Imports) == 0 {
+		return 0, fmt.Errorf("no remote imports recorded in %s", filepath.Base(lockPath))
+	}
+
+	owners := make(map[string]string)
+	for k := range lock.Imports {
+		name := vendorName(k)
+		if other, ok := owners[name]; ok {
+			a, b := min(k, other), max(k, other)
+			return 0, fmt.Errorf("imports %q and %q would both be vendored as %s/%s", a, b, vendorDirName, name)
+		}
+		owners[name] = k
+	}
+
+	vendored := 0
+	for _, k := range slices.Sorted(maps.Keys(lock.Imports)) {
+		if len(specs) > 0 && !slices.Contains(specs, k) && !slices.Contains(specs, trimAtRef(k)) {
+			continue
+		}
+		resolve := gitImportFile
+		if lock.Imports[k].Kind == "url" {
+			resolve = urlImportFile
+		}
+		file, _, err := resolve(k, root, false)
+		if err != nil {
+			return vendored, err
+		}
+		closure, err := importClosure(file)
+		if err != nil {
+			return vendored, fmt.Errorf("import %q: %w", k, err)
+		}
+		dst := vendorDir(root, k)
+		if err := os.RemoveAll(dst); err != nil {
+			return vendored, err
+		}
+		for rel, data := range closure {
+			if strings.HasPrefix(rel, "../") || filepath.IsAbs(rel) {
+				return vendored, fmt.Errorf("import %q: %s lies outside the recipe and cannot be vendored", k, rel)
+			}
+			target := filepath.Join(dst, filepath.FromSlash(rel))
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return vendored, err
+			}
+			if err := os.WriteFile(target, data, 0644); err != nil {
+				return vendored, err
+			}
+		}
+		fmt.Printf("%s -> %s\n", k, relOrAbs(root, dst))
+		vendored++
+	}
+	if vendored > 0 {
+		fmt.Printf("vendored %d import(s); commit %s/ to build without fetching\n", vendored, vendorDirName)
+	}
+	return vendored, nil
+}
@@ -0,0 +1,168 @@
+package pkg
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVendorImports(t *testing.T) {
+	archive := tarGz(t, map[string]string{
+		"Constfile":            "import \"sub/lib.constfile\"\n\nhello {\n  $ echo hi\n}\n",
+		"sub/lib.constfile":    "lib-cmd {\n  $ echo lib\n}\n",
+		"docs/not-imported.md": "# ignored\n",
+	})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(archive)
+	}))
+	defer srv.Close()
+
+	root := t.TempDir()
+	spec := srv.URL + "/recipes.tar.gz#sha256=" + sha256Hex(archive)
+	os.WriteFile(filepath.Join(root, "Constfile"), []byte("import url \""+spec+"\" as r\n"), 0644)
+	parse := func() (*ParsedData, error) {
+		p, err := NewParser(filepath.Join(root, "Constfile"))
+		if err != nil {
+			return nil, err
+		}
+		return p.Parse()
+	}
+	if _, err := parse(); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := VendorImports(root, nil); err != nil || n != 1 {
+		t.Fatalf("vendor: n=%d err=%v", n, err)
+	}
+	vendored := vendorDir(root, spec)
+	if _, err := os.Stat(filepath.Join(vendored, "sub", "lib.constfile")); err != nil {
+		t.Errorf("imported file not vendored: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(vendored, "docs")); err == nil {
+		t.Error("files outside the import closure were vendored")
+	}
+
+	// With the server gone and the cache cleared, the vendored copy builds.
+	srv.Close()
+	os.RemoveAll(filepath.Join(root, CacheDirName()))
+	data, err := parse()
+	if err != nil {
+		t.Fatalf("parse from vendor: %v", err)
+	}
+	cmd, err := data.GetCommand("r.lib-cmd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(cmd.SourceFile, vendored) {
+		t.Errorf("command came from %s, not the vendored copy", cmd.SourceFile)
+	}
+	if failed, err := VerifyImports(root); err != nil || failed != 0 {
+		t.Errorf("verify vendored: failed=%d err=%v", failed, err)
+	}
+
+	os.WriteFile(filepath.Join(vendored, "sub", "lib.constfile"), []byte("lib-cmd {\n  $ echo changed\n}\n"), 0644)
+	if _, err := parse(); err == nil || !strings.Contains(err.Error(), "vendored copy") {
+		t.Errorf("edited vendored copy: err = %v", err)
+	}
+	if failed, _ := VerifyImports(root); failed != 1 {
+		t.Errorf("verify of an edited vendored copy: failed = %d", failed)
+	}
+}
+
+func TestVendorGitImport(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	remote := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = remote
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	run("init", "-q")
+	os.WriteFile(filepath.Join(remote, "Constfile"), []byte("import \"lib.constfile\"\n\nremote-cmd {\n  $ echo hi\n}\n"), 0644)
+	os.WriteFile(filepath.Join(remote, "lib.constfile"), []byte("lib-cmd {\n  $ echo lib\n}\n"), 0644)
+	run("add", "-A")
+	run("config", "user.email", "t@t")
+	run("config", "user.name", "t")
+	run("commit", "-qm", "v1")
+	run("tag", "v1")
+
+	root := t.TempDir()
+	spec := "file://" + filepath.ToSlash(remote) + "@v1"
+	os.WriteFile(filepath.Join(root, "Constfile"), []byte("import git \""+spec+"\" as r\n"), 0644)
+	parse := func() (*ParsedData, error) {
+		p, err := NewParser(filepath.Join(root, "Constfile"))
+		if err != nil {
+			return nil, err
+		}
+		return p.Parse()
+	}
+	if _, err := parse(); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := VendorImports(root, nil); err != nil || n != 1 {
+		t.Fatalf("vendor: n=%d err=%v", n, err)
+	}
+	want := sanitizeVendorName(filepath.Base(filepath.Dir(remote)) + "_" + filepath.Base(remote) + "@v1")
+	vendored := filepath.Join(root, vendorDirName, want)
+	if _, err := os.Stat(filepath.Join(vendored, "lib.constfile")); err != nil {
+		entries, _ := os.ReadDir(filepath.Join(root, vendorDirName))
+		t.Fatalf("not vendored as %s (%v): %v", want, entries, err)
+	}
+	if _, err := os.Stat(filepath.Join(vendored, ".git")); err == nil {
+		t.Error("the git checkout was vendored along with the recipe")
+	}
+
+	// With the remote and the cache gone, the vendored copy builds.
+	os.RemoveAll(remote)
+	os.RemoveAll(filepath.Join(root, CacheDirName()))
+	data, err := parse()
+	if err != nil {
+		t.Fatalf("parse from vendor: %v", err)
+	}
+	cmd, err := data.GetCommand("r.lib-cmd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(cmd.SourceFile, vendored) {
+		t.Errorf("command came from %s, not the vendored copy", cmd.SourceFile)
+	}
+	if failed, err := VerifyImports(root); err != nil || failed != 0 {
+		t.Errorf("verify vendored: failed=%d err=%v", failed, err)
+	}
+}
+
+func TestVendorName(t *testing.T) {
+	for key, want := range map[string]string{
+		"acme/recipes":                    "acme_recipes",
+		"acme/recipes/go@v1.2":            "acme_recipes_go@v1.2",
+		"gitlab.com/team/tools.git@main":  "team_tools@main",
+		"git@github.com:acme/recipes.git": "acme_recipes",
+		"https://example.com/dl/recipes-1.2.tar.gz#sha256=" + strings.Repeat("ab", 32): "recipes-1.2",
+	} {
+		if got := vendorName(key); got != want {
+			t.Errorf("vendorName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestVendorImportsRejectsNameClash(t *testing.T) {
+	root := t.TempDir()
+	lock := &ImportLock{Imports: map[string]ImportLockEntry{
+		"github.com/acme/recipes": {URL: "https://github.com/acme/recipes"},
+		"gitlab.com/acme/recipes": {URL: "https://gitlab.com/acme/recipes"},
+	}}
+	if err := saveImportLock(importLockPath(root), lock); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VendorImports(root, nil); err == nil || !strings.Contains(err.Error(), "would both be vendored as") {
+		t.Errorf("err = %v, want the clash reported", err)
+	}
+}
@@ -0,0 +1,469 @@
+package pkg
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// A workspace is a root Constfile with a `workspace` statement. Every nested
+// Constfile it finds becomes a package: its commands load under a namespace
+// derived from the package path, run in the package directory, and are
+// addressed on the command line and in prerequisites by label,
+// //services/api:build.
+
+const workspaceFile = "Constfile"
+
+// workspaceNamespace is the namespace a package's names live under:
+// services/api -> services.api. Characters a reference cannot carry past the
+// first dot (user-service) become underscores.
+func workspaceNamespace(pkgPath string) string {
+	segs := strings.Split(pkgPath, "/")
+	for i, seg := range segs {
+		segs[i] = strings.Map(func(r rune) rune {
+			if isPlainRune(r) {
+				return r
+			}
+			return '_'
+		}, seg)
+	}
+	return strings.Join(segs, ".")
+}
+
+// Label is the name a command is addressed by on the command line: its
+// package label (//services/api:build) in a workspace, its name otherwise.
+func (c *Command) Label() string {
+	if c.Package == "" {
+		return c.Name
+	}
+	return "//" + c.Package + ":" + strings.TrimPrefix(c.Name, workspaceNamespace(c.Package)+".")
+}
+
+// IsLabel reports whether a target is written as a workspace label.
+func IsLabel(target string) bool {
+	return strings.HasPrefix(target, "//")
+}
+
+// labelAt reports whether s starts with a label (//services/api:build)
+// rather than a // comment: a path, a colon, then a command name.
+func labelAt(s string) bool {
+	rest, ok := strings.CutPrefix(s, "//")
+	if !ok {
+		return false
+	}
+	for i := 0; i < len(rest); i++ {
+		c := rest[i]
+		switch {
+		case c == ':':
+			return i+1 < len(rest) && isCommandNameStart(rest[i+1])
+		case c == '/' || c == '.' || isVarIdentByte(c):
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// parseWorkspace reads `workspace` or `workspace "services/*", "libs/*"`.
+// No patterns means every nested Constfile.
+func parseWorkspace(line string) ([]string, error) {
+	rest := strings.TrimSpace(strings.TrimPrefix(line, "workspace"))
+	var patterns []string
+	for _, part := range splitTopLevel(rest, ',') {
+		if part == "" {
+			continue
+		}
+		pat := strings.Trim(part, `"`)
+		if pat == "" || strings.Contains(pat, `"`) || filepath.IsAbs(pat) || strings.HasPrefix(filepath.Clean(pat), "..") {
+			return nil, fmt.Errorf("workspace: invalid package pattern %s (expected a quoted path under the root)", part)
+		}
+		patterns = append(patterns, filepath.ToSlash(filepath.Clean(pat)))
+	}
+	return patterns, nil
+}
+
+// workspaceSkip reports directories discovery never descends into.
+func workspaceSkip(name string) bool {
+	return strings.HasPrefix(name, ".") || name == "node_modules" || name == vendorDirName || name == cacheDir
+}
+
+// discoverPackages returns the package paths (slash-separated, relative to
+// root) of the nested Constfiles the patterns select, sorted.
+func discoverPackages(root string, patterns []string) ([]string, error) {
+	var pkgs []string
+	if len(patterns) == 0 {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() || path == root {
+				return nil
+			}
+			if workspaceSkip(d.Name()) {
+				return filepath.SkipDir
+			}
+			if fileExistsAt(filepath.Join(path, workspaceFile)) {
+				rel, _ := filepath.Rel(root, path)
+				pkgs = append(pkgs, filepath.ToSlash(rel))
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("workspace: %w", err)
+		}
+		return pkgs, nil
+	}
+	for _, pat := range patterns {
+		matches, err := filepath.Glob(filepath.Join(root, filepath.FromSlash(pat)))
+		if err != nil {
+			return nil, fmt.Errorf("workspace: bad pattern %q: %w", pat, err)
+		}
+		found := false
+		for _, m := range matches {
+			if !fileExistsAt(filepath.Join(m, workspaceFile)) {
+				continue
+			}
+			rel, _ := filepath.Rel(root, m)
+			if rel == "." {
+				continue
+			}
+			found = true
+			if rel = filepath.ToSlash(rel); !slices.Contains(pkgs, rel) {
+				pkgs = append(pkgs, rel)
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("workspace: pattern %q matches no directory with a %s", pat, workspaceFile)
+		}
+	}
+	slices.Sort(pkgs)
+	return pkgs, nil
+}
+
+func fileExistsAt(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// processWorkspace loads every package of the workspace into p.
+func (p *Parser) processWorkspace(line string) error {
+	if len(p.importStack) > 0 {
+		return fmt.Errorf("workspace is only allowed in the root Constfile")
+	}
+	if p.Data.Packages != nil {
+		return fmt.Errorf("duplicate workspace statement")
+	}
+	patterns, err := parseWorkspace(line)
+	if err != nil {
+		return err
+	}
+	root := importBaseDir(p.InputFile)
+	pkgs, err := discoverPackages(root, patterns)
+	if err != nil {
+		return err
+	}
+	p.Data.Packages = []string{}
+
+	readFile := p.ImportReader
+	if readFile == nil {
+		readFile = os.ReadFile
+	}
+	namespaces := map[string]string{}
+	for _, pkgPath := range pkgs {
+		ns := workspaceNamespace(pkgPath)
+		if other, ok := namespaces[ns]; ok {
+			return fmt.Errorf("workspace: packages //%s and //%s both map to namespace %q", other, pkgPath, ns)
+		}
+		namespaces[ns] = pkgPath
+
+		file := filepath.Clean(filepath.Join(root, filepath.FromSlash(pkgPath), workspaceFile))
+		content, err := readFile(file)
+		if err != nil {
+			return fmt.Errorf("workspace: failed to read //%s: %w", pkgPath, err)
+		}
+		// Each package gets its own dedup set: two packages importing the
+		// same library each need their own namespaced copy.
+		child := NewParserFromContent(file, string(content))
+		child.importStack = map[string]bool{file: true}
+		child.imported = map[string]bool{}
+		child.ImportReader = p.ImportReader
+		child.Profile = p.Profile
+		if err := child.parseLines(); err != nil {
+			return err
+		}
+		// Directories resolve against the workspace root at run time, so
+		// anchor the package's relative ones in its directory.
+		for _, cmd := range child.Data.Commands {
+			cmd.Package = pkgPath
+			if cmd.WorkDir == "" {
+				cmd.WorkDir = pkgPath
+			} else {
+				cmd.WorkDir = packageDir(pkgPath, cmd.WorkDir)
+			}
+			for prereq, dir := range cmd.PrereqDirs {
+				cmd.PrereqDirs[prereq] = packageDir(pkgPath, dir)
+			}
+			anchorInDirs(pkgPath, cmd.Body)
+		}
+		if err := p.mergeImported(child, ns, file, "package //"+pkgPath); err != nil {
+			return err
+		}
+		p.Data.Packages = append(p.Data.Packages, pkgPath)
+	}
+	return nil
+}
+
+// packageDir anchors a relative directory in a package. Absolute paths and
+// ones that start with a reference are left alone.
+func packageDir(pkgPath, dir string) string {
+	if dir == "" || filepath.IsAbs(dir) || strings.ContainsAny(dir[:1], "&@$") {
+		return dir
+	}
+	return filepath.ToSlash(filepath.Join(pkgPath, dir))
+}
+
+func anchorInDirs(pkgPath string, stmts []BodyStatement) {
+	for i := range stmts {
+		stmt := &stmts[i]
+		if stmt.Type == StmtInDir {
+			stmt.Shell = packageDir(pkgPath, stmt.Shell)
+		}
+		for j := range stmt.Cases {
+			anchorInDirs(pkgPath, stmt.Cases[j].Body)
+		}
+		anchorInDirs(pkgPath, stmt.ThenBody)
+		anchorInDirs(pkgPath, stmt.ElseBody)
+		anchorInDirs(pkgPath, stmt.LoopBody)
+		anchorInDirs(pkgPath, stmt.OnFailBody)
+	}
+}
+
+// resolveLabel maps a workspace label to the command name it addresses.
+// Call arguments stay attached: //libs/core:build(os=linux).
+func (d *ParsedData) resolveLabel(label string) (string, error) {
+	rest := strings.TrimPrefix(label, "//")
+	head := rest
+	if open := strings.IndexByte(rest, '('); open >= 0 {
+		head = rest[:open]
+	}
+	colon := strings.IndexByte(head, ':')
+	if colon < 0 || colon == len(head)-1 {
+		return "", fmt.Errorf("label %q needs a target (//path:name)", label)
+	}
+	pkgPath, name := rest[:colon], rest[colon+1:]
+	if pkgPath == "" {
+		return name, nil
+	}
+	if !slices.Contains(d.Packages, pkgPath) {
+		if d.Packages == nil {
+			return "", fmt.Errorf("label %q used outside a workspace", label)
+		}
+		return "", fmt.Errorf("unknown package //%s in %q", pkgPath, label)
+	}
+	return workspaceNamespace(pkgPath) + "." + name, nil
+}
+
+// resolveWorkspaceLabels rewrites label prerequisites and invoke targets
+// (< //libs/core:build) to the namespaced commands they address.
+func (p *Parser) resolveWorkspaceLabels() error {
+	for _, cmd := range p.Data.Commands {
+		labels := map[string]string{}
+		for _, prereq := range cmd.Prereqs {
+			base := strings.TrimSpace(prereq)
+			if open := strings.IndexByte(base, '('); open > 0 {
+				base = base[:open]
+			}
+			if !IsLabel(base) {
+				continue
+			}
+			name, err := p.Data.resolveLabel(base)
+			if err != nil {
+				return NewParseError(cmd.SourceFile, cmd.SourceLine, 1, fmt.Sprintf("command '%s': %v", cmd.Name, err), base)
+			}
+			labels[base] = name
+		}
+		if len(labels) > 0 {
+			for i, prereq := range cmd.Prereqs {
+				if n, ok := renamePrereq(strings.TrimSpace(prereq), labels); ok {
+					cmd.Prereqs[i] = n
+				}
+			}
+			cmd.PrereqDirs = renamePrereqKeys(cmd.PrereqDirs, labels)
+			cmd.PrereqAliases = renamePrereqKeys(cmd.PrereqAliases, labels)
+			cmd.PrereqConds = renamePrereqKeys(cmd.PrereqConds, labels)
+			cmd.PrereqOrderOnly = renamePrereqKeys(cmd.PrereqOrderOnly, labels)
+		}
+		if err := p.resolveInvokeLabels(cmd, cmd.Body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Parser) resolveInvokeLabels(cmd *Command, stmts []BodyStatement) error {
+	for i := range stmts {
+		stmt := &stmts[i]
+		if stmt.Type == StmtInvoke && IsLabel(strings.TrimSpace(stmt.Shell)) {
+			name, err := p.Data.resolveLabel(strings.TrimSpace(stmt.Shell))
+			if err != nil {
+				return NewParseError(cmd.SourceFile, stmt.SourceLine, 1, fmt.Sprintf("command '%s': invoke: %v", cmd.Name, err), stmt.Shell)
+			}
+			stmt.Shell = name
+		}
+		for j := range stmt.Cases {
+			if err := p.resolveInvokeLabels(cmd, stmt.Cases[j].Body); err != nil {
+				return err
+			}
+		}
+		for _, body := range [][]BodyStatement{stmt.ThenBody, stmt.ElseBody, stmt.LoopBody, stmt.OnFailBody} {
+			if err := p.resolveInvokeLabels(cmd, body); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ExpandTargets resolves command-line targets written as labels. A `...`
+// package pattern (//...:test, //services/...:test) selects the target in
+// every package under the prefix that defines it. Other targets pass
+// through unchanged.
+func (d *ParsedData) ExpandTargets(targets []string) ([]string, error) {
+	var out []string
+	for _, t := range targets {
+		if !IsLabel(t) {
+			out = append(out, t)
+			continue
+		}
+		rest := strings.TrimPrefix(t, "//")
+		pattern, name, ok := strings.Cut(rest, ":")
+		prefix, recursive := strings.CutSuffix(pattern, "...")
+		if !recursive {
+			resolved, err := d.resolveLabel(t)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, resolved)
+			continue
+		}
+		if !ok || name == "" {
+			return nil, fmt.Errorf("label %q needs a target (//%s:name)", t, pattern)
+		}
+		if prefix != "" && !strings.HasSuffix(prefix, "/") {
+			return nil, fmt.Errorf("invalid package pattern %q (expected //path/...)", "//"+pattern)
+		}
+		prefix = strings.TrimSuffix(prefix, "/")
+		var matched []string
+		base, args := name, ""
+		if open := strings.IndexByte(name, '('); open > 0 {
+			base, args = name[:open], name[open:]
+		}
+		if prefix == "" {
+			if cmd, err := d.GetCommand(base); err == nil && cmd != nil {
+				matched = append(matched, name)
+			}
+		}
+		for _, pkgPath := range d.Packages {
+			if prefix != "" && pkgPath != prefix && !strings.HasPrefix(pkgPath, prefix+"/") {
+				continue
+			}
+			full := workspaceNamespace(pkgPath) + "." + base
+			if cmd, err := d.GetCommand(full); err == nil && cmd != nil {
+				matched = append(matched, full+args)
+			}
+		}
+		if len(matched) == 0 {
+			return nil, fmt.Errorf("no package under //%s defines %q", pattern, base)
+		}
+		out = append(out, matched...)
+	}
+	return out, nil
+}
+
+// packageOf returns the innermost package containing rel (slash-separated,
+// relative to the workspace root), or "" for the root.
+func packageOf(rel string, pkgs []string) string {
+	best := ""
+	for _, p := range pkgs {
+		if (rel == p || strings.HasPrefix(rel, p+"/")) && len(p) > len(best) {
+			best = p
+		}
+	}
+	return best
+}
+
+// FindWorkspaceRoot walks up from dir to the nearest Constfile that declares
+// a workspace. ok is false when there is none.
+func FindWorkspaceRoot(dir string) (root string, ok bool) {
+	root, _, ok = findWorkspace(dir)
+	return root, ok
+}
+
+// WorkspacePackage finds the workspace containing dir and the directory of
+// the nearest Constfile at or above it, relative to the root ("" for the
+// root itself). usesLabels reports whether that Constfile refers to labels,
+// which resolve only from the root. Packages aren't discovered here: the
+// parse does that, and PackageOf maps the directory to its package.
+func WorkspacePackage(dir string) (root, pkgPath string, usesLabels, ok bool) {
+	root, _, ok = findWorkspace(dir)
+	if !ok {
+		return "", "", false, false
+	}
+	d, _ := filepath.Abs(dir)
+	for d != root && strings.HasPrefix(d, root) && !fileExistsAt(filepath.Join(d, workspaceFile)) {
+		d = filepath.Dir(d)
+	}
+	data, _ := os.ReadFile(filepath.Join(d, workspaceFile))
+	rel, _ := filepath.Rel(root, d)
+	if rel == "." {
+		rel = ""
+	}
+	return root, filepath.ToSlash(rel), containsLabel(string(data)), true
+}
+
+// containsLabel reports whether src mentions a //package:command label.
+func containsLabel(src string) bool {
+	for i := strings.Index(src, "//"); i >= 0; {
+		if labelAt(src[i:]) {
+			return true
+		}
+		next := strings.Index(src[i+2:], "//")
+		if next < 0 {
+			break
+		}
+		i += 2 + next
+	}
+	return false
+}
+
+// PackageOf returns the innermost package of the workspace containing dir
+// (slash-separated, relative to the root), or "" for the root.
+func (d *ParsedData) PackageOf(dir string) string {
+	return packageOf(dir, d.Packages)
+}
+
+// findWorkspace returns the workspace root above dir and its workspace line.
+func findWorkspace(dir string) (root, line string, ok bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", "", false
+	}
+	for {
+		if data, err := os.ReadFile(filepath.Join(dir, workspaceFile)); err == nil {
+			for line := range strings.SplitSeq(string(data), "\n") {
+				line = strings.TrimSpace(line)
+				if line == "workspace" || strings.HasPrefix(line, "workspace ") && !strings.Contains(line, "{") {
+					return dir, line, true
+				}
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", false
+		}
+		dir = parent
+	}
+}
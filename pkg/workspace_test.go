@@ -0,0 +1,191 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeWorkspace(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, body := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func parseWorkspaceRoot(t *testing.T, root string) (*ParsedData, error) {
+	t.Helper()
+	p, err := NewParser(filepath.Join(root, "Constfile"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.Parse()
+}
+
+func TestWorkspacePackages(t *testing.T) {
+	root := writeWorkspace(t, map[string]string{
+		"Constfile": "workspace\n\nall < //services/api:build {\n}\n",
+		"libs/core/Constfile": `import "../shared.constfile" as shared
+var version = 1.0
+build in gen < shared.setup {
+    in out {
+        echo &version
+    }
+}
+`,
+		"libs/shared.constfile": "setup {\n}\n",
+		"services/api/Constfile": `import "../../libs/shared.constfile" as shared
+build < //libs/core:build as core, shared.setup {
+    invoke //libs/core:build
+}
+test < build {
+}
+`,
+		"services/user-service/Constfile": "test {\n}\n",
+		"node_modules/dep/Constfile":      "broken {\n",
+	})
+	data, err := parseWorkspaceRoot(t, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"libs/core", "services/api", "services/user-service"}; !slices.Equal(data.Packages, want) {
+		t.Errorf("packages = %v, want %v", data.Packages, want)
+	}
+
+	core, err := data.GetCommand("libs.core.build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if core.Label() != "//libs/core:build" || core.Package != "libs/core" {
+		t.Errorf("label = %q, package = %q", core.Label(), core.Package)
+	}
+	if core.WorkDir != "libs/core/gen" {
+		t.Errorf("work dir = %q, want libs/core/gen", core.WorkDir)
+	}
+	if in := core.Body[0]; in.Type != StmtInDir || in.Shell != "libs/core/out" {
+		t.Errorf("in dir = %+v", in)
+	}
+	if !strings.Contains(core.Body[0].ThenBody[0].Shell, "&libs.core.version") {
+		t.Errorf("package global not namespaced: %q", core.Body[0].ThenBody[0].Shell)
+	}
+
+	api, _ := data.GetCommand("services.api.build")
+	if want := []string{"libs.core.build", "services.api.shared.setup"}; !slices.Equal(api.Prereqs, want) {
+		t.Errorf("api prereqs = %v, want %v", api.Prereqs, want)
+	}
+	if api.PrereqAliases["libs.core.build"] != "core" {
+		t.Errorf("aliases = %v", api.PrereqAliases)
+	}
+	if api.Body[0].Shell != "libs.core.build" {
+		t.Errorf("invoke target = %q", api.Body[0].Shell)
+	}
+	if users, err := data.GetCommand("services.user_service.test"); err != nil || users.Label() != "//services/user-service:test" {
+		t.Errorf("user-service test = %v, %v", users, err)
+	}
+	if all, _ := data.GetCommand("all"); all.Prereqs[0] != "services.api.build" || all.WorkDir != "" {
+		t.Errorf("root command = %+v", all)
+	}
+
+	for _, tc := range []struct {
+		targets []string
+		want    []string
+	}{
+		{[]string{"//services/api:build", "all"}, []string{"services.api.build", "all"}},
+		{[]string{"//...:test"}, []string{"services.api.test", "services.user_service.test"}},
+		{[]string{"//libs/...:build"}, []string{"libs.core.build"}},
+		{[]string{"//:all"}, []string{"all"}},
+	} {
+		got, err := data.ExpandTargets(tc.targets)
+		if err != nil || !slices.Equal(got, tc.want) {
+			t.Errorf("ExpandTargets(%v) = %v, %v; want %v", tc.targets, got, err, tc.want)
+		}
+	}
+	for _, bad := range []string{"//nope:build", "//libs/...:deploy", "//services/api"} {
+		if _, err := data.ExpandTargets([]string{bad}); err == nil {
+			t.Errorf("ExpandTargets(%q) should fail", bad)
+		}
+	}
+
+	changed := map[string]bool{filepath.Join(root, "libs", "core", "main.go"): true}
+	affected := AffectedCommands(data, changed, root)
+	if !affected["libs.core.build"] || !affected["services.api.test"] || affected["services.user_service.test"] {
+		t.Errorf("affected = %v", affected)
+	}
+
+	if got, ok := FindWorkspaceRoot(filepath.Join(root, "services", "api")); !ok || got != root {
+		t.Errorf("FindWorkspaceRoot = %q, %v", got, ok)
+	}
+	os.MkdirAll(filepath.Join(root, "services", "api", "cmd", "server"), 0755)
+	for _, tc := range []struct {
+		dir, want  string
+		usesLabels bool
+	}{
+		{filepath.Join(root, "services", "api", "cmd", "server"), "services/api", true},
+		{filepath.Join(root, "libs", "core"), "libs/core", false},
+		{root, "", true},
+	} {
+		gotRoot, pkgPath, usesLabels, ok := WorkspacePackage(tc.dir)
+		if !ok || gotRoot != root || pkgPath != tc.want || usesLabels != tc.usesLabels {
+			t.Errorf("WorkspacePackage(%s) = %q, %q, %v, %v; want package %q (labels %v)", tc.dir, gotRoot, pkgPath, usesLabels, ok, tc.want, tc.usesLabels)
+		}
+		if got := data.PackageOf(filepath.ToSlash(pkgPath + "/cmd")); pkgPath != "" && got != pkgPath {
+			t.Errorf("PackageOf(%s/cmd) = %q", pkgPath, got)
+		}
+	}
+}
+
+func TestWorkspaceErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{"unknown package", map[string]string{
+			"Constfile":   "workspace\n",
+			"a/Constfile": "build < //b:build {\n}\n",
+		}, "unknown package //b"},
+		{"private", map[string]string{
+			"Constfile":   "workspace\n",
+			"a/Constfile": "private setup {\n}\n",
+			"b/Constfile": "build < //a:setup {\n}\n",
+		}, `command "//a:setup" is private to a/Constfile`},
+		{"nested workspace", map[string]string{
+			"Constfile":   "workspace\n",
+			"a/Constfile": "workspace\n",
+		}, "only allowed in the root"},
+		{"pattern", map[string]string{
+			"Constfile":   "workspace \"services/*\"\n",
+			"a/Constfile": "build {\n}\n",
+		}, "matches no directory"},
+		{"outside", map[string]string{
+			"Constfile": "build < //a:build {\n}\n",
+		}, "outside a workspace"},
+	} {
+		_, err := parseWorkspaceRoot(t, writeWorkspace(t, tc.files))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: err = %v, want %q", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestLabelNotComment(t *testing.T) {
+	for line, want := range map[string]string{
+		"build < //libs/core:build {": "build < //libs/core:build {",
+		"build { // see: docs":        "build {",
+		"build {  //TODO: later":      "build {",
+	} {
+		if got := stripInlineComment(line); got != want {
+			t.Errorf("stripInlineComment(%q) = %q, want %q", line, got, want)
+		}
+	}
+}
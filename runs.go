@@ -1,9 +1,13 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io/fs"
+	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -13,7 +17,13 @@ import (
 
 func runRuns(args []string, o *options) error {
 	fileName, rest := splitConstfileArgs(args)
-	hist := pkg.LoadRunHistory(filepath.Join(filepath.Dir(fileName), pkg.CacheDirName()))
+	cacheDir := filepath.Join(filepath.Dir(fileName), pkg.CacheDirName())
+	if o.fromCloud != "" {
+		if err := mergeCloudRuns(o, cacheDir); err != nil {
+			return err
+		}
+	}
+	hist := withCloudRuns(pkg.LoadRunHistory(cacheDir), pkg.LoadCloudRunHistory(cacheDir))
 
 	if len(rest) == 0 {
 		return runsList(hist, o)
@@ -34,6 +44,70 @@ func runRuns(args []string, o *options) error {
 	}
 }
 
+// mergeCloudRuns downloads the artifacts of cloud run o.fromCloud and merges
+// every run-state.json in them into the cloud history next to the local one,
+// so runs show and diff can put a CI run next to local ones.
+func mergeCloudRuns(o *options, cacheDir string) error {
+	id, err := strconv.ParseInt(o.fromCloud, 10, 64)
+	if err != nil {
+		return exitAt(2, "invalid run id %q", o.fromCloud)
+	}
+	client, err := cloudBackend(o, "")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp("", "construct-cloud-run-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	if _, err := pkg.DownloadCloudArtifacts(context.Background(), client, id, tmp); err != nil {
+		return err
+	}
+
+	hist := pkg.LoadCloudRunHistory(cacheDir)
+	if hist == nil {
+		hist = make(map[string][]pkg.RunRecord)
+	}
+	found, added := false, 0
+	source := fmt.Sprintf("cloud run #%d", id)
+	err = filepath.WalkDir(tmp, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || d.Name() != "run-state.json" {
+			return err
+		}
+		found = true
+		added += pkg.MergeRunHistory(hist, pkg.LoadRunHistory(filepath.Dir(path)), source)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return exitAt(1, "%s uploaded no run-state.json (is .construct-cache among its artifacts?)", source)
+	}
+	pkg.SaveCloudRunHistory(cacheDir, hist)
+	fmt.Fprintf(os.Stderr, "merged %d record(s) from %s\n", added, source)
+	return nil
+}
+
+// withCloudRuns adds merged cloud records to the local history, each
+// command's records in End order.
+func withCloudRuns(local, cloud map[string][]pkg.RunRecord) map[string][]pkg.RunRecord {
+	if len(cloud) == 0 {
+		return local
+	}
+	out := make(map[string][]pkg.RunRecord, len(local)+len(cloud))
+	for name, recs := range local {
+		out[name] = recs
+	}
+	for name, recs := range cloud {
+		merged := append(slices.Clone(out[name]), recs...)
+		sort.SliceStable(merged, func(i, j int) bool { return merged[i].End.Before(merged[j].End) })
+		out[name] = merged
+	}
+	return out
+}
+
 type runEntry struct {
 	Name   string        `json:"name"`
 	Index  int           `json:"index"` // 1 = most recent
@@ -75,6 +149,9 @@ func runsList(hist map[string][]pkg.RunRecord, o *options) error {
 			exit = strconv.Itoa(e.Record.Exit)
 		}
 		when := e.Record.End.Format("2006-01-02 15:04:05")
+		if e.Record.Source != "" {
+			when += " (" + e.Record.Source + ")"
+		}
 		fmt.Printf("%-20s %5d %8s %10s %10s  %s\n", e.Name, e.Index, e.Record.Status, exit, durMs(e.Record.DurationMs), when)
 	}
 	return nil
@@ -127,6 +204,12 @@ func runsShow(hist map[string][]pkg.RunRecord, name string, args []string, o *op
 		exit = fmt.Sprintf(" (exit %d)", rec.Exit)
 	}
 	fmt.Printf("%s run %d: %s%s, %s, finished %s\n", name, n, rec.Status, exit, durMs(rec.DurationMs), rec.End.Format("2006-01-02 15:04:05"))
+	if rec.Profile != "" {
+		fmt.Printf("profile: %s\n", rec.Profile)
+	}
+	if rec.Source != "" {
+		fmt.Printf("source: %s\n", rec.Source)
+	}
 	if rec.Error != "" {
 		fmt.Printf("error: %s\n", rec.Error)
 	}
@@ -167,7 +250,7 @@ func runsDiff(hist map[string][]pkg.RunRecord, name string, args []string) error
 		return exitAt(1, "neither record captured output (runs before log capture have none)")
 	}
 
-	fmt.Printf("diff of %s run %d (newer) vs run %d (older)\n", name, aIdx, bIdx)
+	fmt.Printf("diff of %s run %d%s (newer) vs run %d%s (older)\n", name, aIdx, sourceNote(a), bIdx, sourceNote(b))
 	lines := diffLines(strings.Split(b.Log, "\n"), strings.Split(a.Log, "\n"))
 	same := true
 	for _, l := range lines {
@@ -182,6 +265,13 @@ func runsDiff(hist map[string][]pkg.RunRecord, name string, args []string) error
 	return nil
 }
 
+func sourceNote(rec pkg.RunRecord) string {
+	if rec.Source == "" {
+		return ""
+	}
+	return " [" + rec.Source + "]"
+}
+
 // diffLines is a small LCS line diff; inputs are expected to be log-sized,
 // and pathological cases fall back to a coarse whole-file replacement.
 func diffLines(old, new []string) []string {
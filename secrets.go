@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/nicklvsa/construct/pkg"
+)
+
+const secretsUsage = "usage: construct secrets [Constfile] [list | set NAME [VALUE] | rm NAME]"
+
+func runSecrets(args []string, o *options) error {
+	fileName, rest := splitConstfileArgs(args)
+	path := filepath.Join(filepath.Dir(fileName), pkg.SecretsFileName)
+	if len(rest) == 0 {
+		rest = []string{"list"}
+	}
+	switch rest[0] {
+	case "list":
+		if !fileExists(path) {
+			fmt.Printf("(no secrets: %s does not exist)\n", path)
+			return nil
+		}
+		values, _, err := openSecretStore(path, false)
+		if err != nil {
+			return err
+		}
+		for _, name := range slices.Sorted(maps.Keys(values)) {
+			fmt.Println(name)
+		}
+		return nil
+	case "set":
+		if len(rest) < 2 || len(rest) > 3 {
+			return exitAt(2, secretsUsage)
+		}
+		values, pass, err := openSecretStore(path, true)
+		if err != nil {
+			return err
+		}
+		value, err := secretValueArg(rest[1], rest[2:])
+		if err != nil {
+			return err
+		}
+		values[rest[1]] = value
+		if err := pkg.WriteSecretStore(path, pass, values); err != nil {
+			return err
+		}
+		fmt.Printf("set %s in %s\n", rest[1], path)
+		return nil
+	case "rm":
+		if len(rest) != 2 {
+			return exitAt(2, secretsUsage)
+		}
+		values, pass, err := openSecretStore(path, false)
+		if err != nil {
+			return err
+		}
+		if _, ok := values[rest[1]]; !ok {
+			return fmt.Errorf("%s has no entry %q", path, rest[1])
+		}
+		delete(values, rest[1])
+		if err := pkg.WriteSecretStore(path, pass, values); err != nil {
+			return err
+		}
+		fmt.Printf("removed %s from %s\n", rest[1], path)
+		return nil
+	default:
+		return exitAt(2, secretsUsage)
+	}
+}
+
+// openSecretStore decrypts path, or starts an empty store when create is
+// set and the file is new (asking for the passphrase twice).
+func openSecretStore(path string, create bool) (map[string]string, string, error) {
+	exists := fileExists(path)
+	if !exists && !create {
+		return nil, "", fmt.Errorf("%s does not exist", path)
+	}
+	if stdinIsTerminal() {
+		pkg.SetSecretsPrompt(func() (string, error) {
+			pass, err := readPassphrase("Passphrase for " + path + ": ")
+			if err != nil || exists {
+				return pass, err
+			}
+			again, err := readPassphrase("Repeat passphrase: ")
+			if err != nil {
+				return "", err
+			}
+			if again != pass {
+				return "", errors.New("passphrases do not match")
+			}
+			return pass, nil
+		})
+	}
+	pass, err := pkg.SecretsPassphrase()
+	if err != nil {
+		return nil, "", err
+	}
+	if !exists {
+		return map[string]string{}, pass, nil
+	}
+	values, err := pkg.ReadSecretStore(path, pass)
+	return values, pass, err
+}
+
+// secretValueArg takes the value from the command line, a hidden prompt,
+// or piped stdin, in that order.
+func secretValueArg(name string, args []string) (string, error) {
+	if len(args) > 0 {
+		return args[0], nil
+	}
+	if stdinIsTerminal() {
+		return readPassphrase("Value for " + name + ": ")
+	}
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+func stdinIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+func readPassphrase(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	b, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
@@ -20,6 +20,9 @@ func applySince(inputs *ConstructInput, o *options, data *pkg.ParsedData) (bool,
 	if err != nil {
 		return false, err
 	}
+	for _, t := range inputs.Commands {
+		_, _ = data.Instance(t) // a CLI call like build(os=linux) needs its node first
+	}
 	affected := pkg.AffectedCommands(data, changed, absBase)
 
 	targets := inputs.Commands
@@ -38,11 +41,12 @@ func applySince(inputs *ConstructInput, o *options, data *pkg.ParsedData) (bool,
 			run = append(run, t)
 			continue
 		}
-		if _, err := data.GetCommand(t); err != nil {
+		cmd, err := data.GetCommand(t)
+		if err != nil {
 			run = append(run, t)
 			continue
 		}
-		fmt.Printf("(%s not affected since %s — skipping)\n", t, o.since)
+		fmt.Printf("(%s not affected since %s — skipping)\n", cmd.Label(), o.since)
 	}
 	if len(run) == 0 {
 		fmt.Printf("(nothing affected since %s)\n", o.since)
@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/nicklvsa/construct/pkg"
@@ -122,40 +123,58 @@ func runLint(args []string, o *options) error {
 }
 
 func runImport(args []string, o *options) error {
-	input := "Makefile"
+	var input string
 	output := "Constfile"
 	if err := rejectSubcommandFlags(args, "import"); err != nil {
 		return err
 	}
 
-	// `construct import update [specs...]` refreshes remote (git) imports;
-	// a Makefile literally named "update" still converts via an explicit path.
+	// `construct import update|vendor|verify` manage remote imports; a
+	// Makefile literally named "update" still converts via an explicit path.
+	baseDir := "."
+	if fileName := defaultConstfileName(); fileExists(fileName) {
+		baseDir = filepath.Dir(fileName)
+	}
 	if len(args) > 0 && args[0] == "update" && !fileExists("update") {
-		baseDir := "."
-		if fileName := defaultConstfileName(); fileExists(fileName) {
-			baseDir = filepath.Dir(fileName)
-		}
 		if _, err := pkg.UpdateGitImports(baseDir, args[1:]); err != nil {
 			return exitAt(1, "%v", err)
 		}
 		return nil
 	}
+	if len(args) > 0 && args[0] == "vendor" && !fileExists("vendor") {
+		if _, err := pkg.VendorImports(baseDir, args[1:]); err != nil {
+			return exitAt(1, "%v", err)
+		}
+		return nil
+	}
+	if len(args) == 1 && args[0] == "verify" && !fileExists("verify") {
+		failed, err := pkg.VerifyImports(baseDir)
+		if err != nil {
+			return exitAt(1, "%v", err)
+		}
+		if failed > 0 {
+			return exitAt(1, "%d import(s) do not match .construct.lock", failed)
+		}
+		return nil
+	}
 
 	if len(args) > 0 {
 		input = args[0]
+	} else {
+		input = detectImportSource()
 	}
 	if len(args) > 1 {
 		output = args[1]
 	}
 	if len(args) > 2 {
-		return exitAt(2, "usage: construct import [Makefile] [output] | construct import update [specs...]")
+		return exitAt(2, "usage: construct import [Makefile|justfile|Taskfile.yml|package.json|Procfile|docker-compose.yml] [output] | construct import update|vendor [specs...] | construct import verify")
 	}
 
 	content, err := os.ReadFile(input)
 	if err != nil {
 		return fmt.Errorf("cannot read %s: %w", input, err)
 	}
-	res, err := pkg.ImportMakefile(string(content))
+	res, err := importerFor(input)(string(content))
 	if err != nil {
 		return fmt.Errorf("%s: %w", input, err)
 	}
@@ -175,6 +194,39 @@ func runImport(args []string, o *options) error {
 	return nil
 }
 
+// importerFor picks the converter by file name; every other tool's file has
+// a conventional name, so anything else is read as a Makefile.
+func importerFor(input string) func(string) (pkg.ImportResult, error) {
+	base := strings.ToLower(filepath.Base(input))
+	ext := filepath.Ext(base)
+	switch {
+	case strings.HasPrefix(base, "procfile"):
+		return pkg.ImportProcfile
+	case (ext == ".yml" || ext == ".yaml") && (strings.HasPrefix(base, "docker-compose") || strings.HasPrefix(base, "compose")):
+		return pkg.ImportCompose
+	case (ext == ".yml" || ext == ".yaml") && strings.HasPrefix(base, "taskfile"):
+		return pkg.ImportTaskfile
+	case base == "package.json":
+		return pkg.ImportPackageJSON
+	case base == "justfile" || base == ".justfile":
+		return pkg.ImportJustfile
+	}
+	return pkg.ImportMakefile
+}
+
+// importSources are the files `construct import` looks for, in order, when
+// no input is named.
+var importSources = []string{"Makefile", "makefile", "GNUmakefile", "justfile", "Justfile", ".justfile", "Taskfile.yml", "Taskfile.yaml", "package.json", "Procfile", "docker-compose.yml", "compose.yaml"}
+
+func detectImportSource() string {
+	for _, name := range importSources {
+		if fileExists(name) {
+			return name
+		}
+	}
+	return "Makefile"
+}
+
 func runShellCmd(args []string, o *options) error {
 	if err := rejectSubcommandFlags(args, "shell"); err != nil {
 		return err
@@ -322,6 +374,9 @@ func runGraph(args []string, o *options) error {
 		return err
 	}
 
+	for _, t := range targets {
+		_, _ = data.Instance(t) // graph 'build(os=linux)' as its own node
+	}
 	if len(targets) == 0 {
 		targets = graphRoots(data)
 	}
@@ -343,8 +398,11 @@ func graphRoots(data *pkg.ParsedData) []string {
 			continue
 		}
 		names = append(names, cmd.Name)
+	}
+	for _, cmd := range append(slices.Clone(data.Commands), data.Instances()...) {
 		for _, pre := range cmd.Prereqs {
 			referenced[pre] = true
+			referenced[pkg.PrereqBase(pre)] = true // build(os=linux) reaches build
 		}
 	}
 	var roots []string
@@ -395,15 +453,29 @@ func union(m map[string]bool, k string) map[string]bool {
 }
 
 type graphKid struct {
-	name   string
-	label  string
-	isFile bool
+	name      string
+	label     string
+	cond      string // edge only exists when this header condition holds
+	orderOnly bool   // after `|`: sequencing only
+	isFile    bool
 }
 
 func graphChildren(cmd *pkg.Command) []graphKid {
 	var kids []graphKid
 	for _, pre := range cmd.Prereqs {
-		kids = append(kids, graphKid{name: pre, label: pre})
+		label := pre
+		if alias := cmd.PrereqAliases[pre]; alias != "" {
+			label += " as " + alias
+		}
+		cond := cmd.PrereqConds[pre]
+		if cond != "" {
+			label += " (if " + cond + ")"
+		}
+		orderOnly := cmd.PrereqOrderOnly[pre]
+		if orderOnly {
+			label += " (order-only)"
+		}
+		kids = append(kids, graphKid{name: pre, label: label, cond: cond, orderOnly: orderOnly})
 	}
 	for _, dep := range cmd.FileDeps {
 		kids = append(kids, graphKid{label: dep + " (file)", isFile: true})
@@ -440,7 +512,14 @@ func graphDot(data *pkg.ParsedData, targets []string) error {
 			}
 			if !edges[name+"->"+to] {
 				edges[name+"->"+to] = true
-				fmt.Printf("  \"%s\" -> \"%s\";\n", name, to)
+				switch {
+				case kid.cond != "":
+					fmt.Printf("  \"%s\" -> \"%s\" [style=dashed, label=%s];\n", name, to, strconv.Quote("if "+kid.cond))
+				case kid.orderOnly:
+					fmt.Printf("  \"%s\" -> \"%s\" [style=dotted];\n", name, to)
+				default:
+					fmt.Printf("  \"%s\" -> \"%s\";\n", name, to)
+				}
 			}
 			if !kid.isFile {
 				walk(kid.name, union(path, name))
@@ -458,9 +537,12 @@ func graphDot(data *pkg.ParsedData, targets []string) error {
 
 func graphJSON(data *pkg.ParsedData, targets []string) error {
 	type node struct {
-		Name     string   `json:"name"`
-		Prereqs  []string `json:"prereqs,omitempty"`
-		FileDeps []string `json:"file_deps,omitempty"`
+		Name       string            `json:"name"`
+		Guard      string            `json:"guard,omitempty"`
+		Prereqs    []string          `json:"prereqs,omitempty"`
+		Conditions map[string]string `json:"conditions,omitempty"`
+		OrderOnly  []string          `json:"order_only,omitempty"`
+		FileDeps   []string          `json:"file_deps,omitempty"`
 	}
 
 	seen := map[string]bool{}
@@ -478,7 +560,13 @@ func graphJSON(data *pkg.ParsedData, targets []string) error {
 			return
 		}
 
-		out = append(out, node{Name: name, Prereqs: cmd.Prereqs, FileDeps: cmd.FileDeps})
+		var orderOnly []string
+		for _, pre := range cmd.Prereqs {
+			if cmd.PrereqOrderOnly[pre] {
+				orderOnly = append(orderOnly, pre)
+			}
+		}
+		out = append(out, node{Name: name, Guard: cmd.Guard, Prereqs: cmd.Prereqs, Conditions: cmd.PrereqConds, OrderOnly: orderOnly, FileDeps: cmd.FileDeps})
 		path = union(path, name)
 
 		for _, pre := range cmd.Prereqs {
@@ -499,6 +587,86 @@ func graphJSON(data *pkg.ParsedData, targets []string) error {
 	return nil
 }
 
+// runExport writes targets and their prerequisites as a standalone script.
+// Argument flags (--deploy:env prod) and overrides resolve as for a run.
+func runExport(args []string, o *options) error {
+	args = args[:len(args)-len(o.passthrough)]
+	if err := rejectSubcommandFlags(args, "export"); err != nil {
+		return err
+	}
+	if len(args) == 0 || args[0] != "sh" {
+		return exitAt(2, "usage: construct export sh [Constfile] [targets...]")
+	}
+	fileName, targets := splitConstfileArgs(args[1:])
+	p, err := pkg.NewParser(fileName)
+	if err != nil {
+		return err
+	}
+	data, err := p.Parse()
+	if err != nil {
+		return err
+	}
+	if targets, err = data.ExpandTargets(targets); err != nil {
+		return err
+	}
+
+	executor := pkg.NewExecutor(data, false, o.debug)
+	if err := bindArgumentFlags(executor, targets, o); err != nil {
+		return exitAt(2, "%v", err)
+	}
+	if err := applyOverrides(data, o); err != nil {
+		return exitAt(2, "%v", err)
+	}
+
+	script, err := executor.ExportShell(targets)
+	if err != nil {
+		return err
+	}
+	fmt.Print(script)
+	return nil
+}
+
+func runCI(args []string, o *options) error {
+	if err := rejectSubcommandFlags(args, "ci"); err != nil {
+		return err
+	}
+	if len(args) < 2 || args[0] != "generate" || (args[1] != "github" && args[1] != "gitlab") {
+		return exitAt(2, "usage: construct ci generate github|gitlab [Constfile] [targets...]")
+	}
+	provider := args[1]
+	fileName, targets := splitConstfileArgs(args[2:])
+	p, err := pkg.NewParser(fileName)
+	if err != nil {
+		return err
+	}
+	data, err := p.Parse()
+	if err != nil {
+		return err
+	}
+	if targets, err = data.ExpandTargets(targets); err != nil {
+		return err
+	}
+	pipeline, err := pkg.GenerateCI(data, provider, targets, o.overrides)
+	if err != nil {
+		return err
+	}
+	if o.output == "" {
+		fmt.Print(pipeline)
+		return nil
+	}
+	if _, err := os.Stat(o.output); err == nil && !o.force {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", o.output)
+	}
+	if err := os.MkdirAll(filepath.Dir(o.output), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(o.output, []byte(pipeline), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %s — commit and push it\n", o.output)
+	return nil
+}
+
 func runTargets() {
 	fileName := defaultConstfileName()
 	p, err := pkg.NewParser(fileName)
@@ -512,11 +680,16 @@ func runTargets() {
 	}
 
 	for _, cmd := range data.Commands {
-		if cmd.Name == "_" || pkg.IsLazyName(cmd.Name) {
+		if cmd.Name == "_" || cmd.Private || pkg.IsLazyName(cmd.Name) {
 			continue
 		}
 
-		fmt.Println(cmd.Name)
+		fmt.Println(cmd.Label())
+		if cmd.Matrix != nil {
+			for _, cell := range cmd.Prereqs {
+				fmt.Println(cell)
+			}
+		}
 	}
 }
 
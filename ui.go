@@ -49,6 +49,9 @@ func runUI(args []string, o *options) error {
 	if err != nil {
 		return err
 	}
+	// Parsing registers the Constfile's secrets, so dry-run output and
+	// errors sent to the browser are masked.
+	_, _ = parseConstfileOptional(fileName)
 
 	bin, err := os.Executable()
 	if err != nil {
@@ -206,7 +209,7 @@ func (s *uiServer) handleSave(w http.ResponseWriter, _ *http.Request) {
 			})
 			return
 		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, errString(err), http.StatusInternalServerError)
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "saved": saved, "state": st})
@@ -221,7 +224,7 @@ func (s *uiServer) handleReload(w http.ResponseWriter, _ *http.Request) {
 	st := s.doc.State()
 	s.mu.Unlock()
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, errString(err), http.StatusInternalServerError)
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "state": st})
@@ -253,16 +256,19 @@ func (s *uiServer) handleDryRun(w http.ResponseWriter, r *http.Request) {
 	cmd.Dir = dir
 	out, err := cmd.CombinedOutput()
 	writeJSON(w, http.StatusOK, map[string]any{
-		"output": string(out),
+		"output": pkg.MaskSecrets(string(out)),
 		"error":  errString(err),
 	})
 }
 
+// errString masks secrets in an error for the client. Only error and run
+// output strings are masked: the document model carries Constfile text and
+// names that a save writes back verbatim.
 func errString(err error) string {
 	if err == nil {
 		return ""
 	}
-	return err.Error()
+	return pkg.MaskSecrets(err.Error())
 }
 
 func writeJSON(w http.ResponseWriter, code int, v any) {
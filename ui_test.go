@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
 
 	"github.com/nicklvsa/construct/pkg"
@@ -237,3 +238,62 @@ func TestUIRunUIErrors(t *testing.T) {
 		t.Error("extra args accepted")
 	}
 }
+
+func TestUIServerSecretsStayInDocument(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "Constfile")
+	src := "secret user = uitestadmin\nsecret tok = x<y>uitest\n\nuitestadmin_setup {\n    $ echo &user\n}\n"
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := pkg.NewUIDoc(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = parseConstfileOptional(path) // as runUI does
+	if pkg.MaskSecrets("uitestadmin") == "uitestadmin" {
+		t.Fatal("parsing the Constfile did not register its secrets")
+	}
+	s := &uiServer{doc: doc, token: "testtoken"}
+	ts := httptest.NewServer(s.routes())
+	t.Cleanup(ts.Close)
+
+	_, body := uiDo(t, ts, "GET", "/api/state", s.token, nil)
+	st := uiStateOf(t, body)
+	if len(st.Files[0].Commands) != 1 || st.Files[0].Commands[0].Name != "uitestadmin_setup" {
+		t.Fatalf("command names were masked: %+v", st.Files[0].Commands)
+	}
+
+	newBody := "$ echo &user again"
+	_, body = uiDo(t, ts, "POST", "/api/ops", s.token, uiOpsRequest{
+		Ops: []pkg.UIEditOp{{File: path, Kind: "setBody", Name: "uitestadmin_setup", Body: &newBody}},
+	})
+	if body["ok"] != true {
+		t.Fatalf("op failed: %v", body["error"])
+	}
+	if resp, body := uiDo(t, ts, "POST", "/api/save", s.token, nil); resp.StatusCode != http.StatusOK || body["ok"] != true {
+		t.Fatalf("save = %d %v", resp.StatusCode, body)
+	}
+	disk, _ := os.ReadFile(path)
+	for _, want := range []string{"secret user = uitestadmin", "secret tok = x<y>uitest", "uitestadmin_setup {", "$ echo &user again"} {
+		if !bytes.Contains(disk, []byte(want)) {
+			t.Errorf("saved Constfile lost %q:\n%s", want, disk)
+		}
+	}
+	if bytes.Contains(disk, []byte("*****")) {
+		t.Errorf("saved Constfile contains masked text:\n%s", disk)
+	}
+
+	// Run output is masked before encoding, so JSON escaping of <, > and &
+	// cannot hide a secret from the mask.
+	if runtime.GOOS == "windows" {
+		return
+	}
+	bin := filepath.Join(dir, "fake-construct")
+	os.WriteFile(bin, []byte("#!/bin/sh\necho 'tok=x<y>uitest user=uitestadmin'\n"), 0755)
+	s.bin = bin
+	_, body = uiDo(t, ts, "POST", "/api/dryrun", s.token, uiDryRunRequest{Targets: []string{"uitestadmin_setup"}})
+	if out, _ := body["output"].(string); out != "tok=***** user=*****\n" {
+		t.Errorf("dry-run output = %q", out)
+	}
+}
@@ -16,11 +16,13 @@ type options struct {
 	concurrent        bool
 	dryRun            bool
 	showList          bool
+	listProfiles      bool
 	watch             bool
 	choose            bool
 	timing            bool
 	keepGoing         bool
 	noCache           bool
+	noDeps            bool
 	quiet             bool
 	explain           bool
 	json              bool
@@ -31,11 +33,17 @@ type options struct {
 	yes               bool
 	doctor            bool
 	force             bool
+	follow            bool
+	detach            bool
 	template          string
 	fileName          string
 	output            string
 	wait              bool
 	repo              string
+	backend           string
+	into              string
+	fromCloud         string
+	sign              string
 	ref               string
 	workflow          string
 	noInit            bool
@@ -49,6 +57,7 @@ type options struct {
 	envFile           string
 	shell             string
 	overrides         []string
+	passthrough       []string
 	containerOverride string
 	tui               bool
 	dash              *dashboard
@@ -57,6 +66,9 @@ type options struct {
 	since             string
 	hooks             []string
 	uninstall         bool
+	frozenLockfile    bool
+	secretsKeyFile    string
+	profile           string
 }
 
 func printUsage() {
@@ -64,13 +76,21 @@ func printUsage() {
 
 Usage:
   construct [options] [Constfile] [commands...]
-  construct <init|import|shell|doctor|stats|cloud|clean|lint|graph|fmt|completion|ui|runs|mcp|learn|install> [args...]
+  construct <init|import|export|ci|shell|doctor|stats|cloud|clean|lint|graph|fmt|completion|ui|runs|mcp|learn|install|secrets> [args...]
 
 Commands:
   init [template]   Scaffold a Constfile (minimal, go, python, node, rust, monorepo)
-  import [FILE] [OUT]  Convert a Makefile to a Constfile (best-effort, --force)
+  import [FILE] [OUT]  Convert a Makefile, justfile, Taskfile, package.json, Procfile, or compose file
   import update     Refresh remote recipe imports to their ref's latest commit
+  import vendor     Copy locked remote imports into construct_vendor/
+  import verify     Check cached remote imports against .construct.lock
+  export sh [FILE] [targets]  Write targets and their prereqs as a POSIX shell script
+  ci generate github|gitlab [FILE] [targets]  Write a CI pipeline with one job per command (--output FILE)
   dev [services...] Supervise long-running service commands (ports, restarts)
+  dev status        Show the services of a running dev session
+  dev restart|stop SVC  Bounce or stop one service of a running dev session
+  dev logs SVC [--follow]  Print (or follow) a service's recent output
+  dev attach|down   Stream a detached dev session's output, or stop it
   shell [FILE] [cmd]  Start a shell with a command's env, workdir, or container
   doctor            Diagnose the environment, Constfile, tools, and cloud file
   stats             Show per-command timing history
@@ -80,11 +100,12 @@ Commands:
   fmt [files]       Canonicalize Constfile indentation (--check for CI)
   completion SHELL  Emit bash/zsh/fish completions
   ui [Constfile]    Edit the Constfile in the browser (drag and drop; --port, --no-open)
-  runs [FILE]       Show run history: list, show <cmd> [n], diff <cmd> [a b]
+  runs [FILE]       Show run history: list, show <cmd> [n], diff <cmd> [a b] (--from-cloud ID)
   mcp [FILE]        Serve build tools to MCP clients over stdio (for AI agents)
   learn [FILE] [targets]  Discover file deps: trace reads (strace) or unwatched files
   install           Install shell completions (--hook NAME for git hooks, --uninstall)
-  cloud             Manage cloud commands and GitHub Actions jobs (see below)
+  secrets [FILE]    Manage the encrypted .construct-secrets store: list, set NAME [VALUE], rm NAME
+  cloud             Manage cloud commands and GitHub Actions or GitLab CI jobs (see below)
 
 Options:
   -h, --help        Show this help message
@@ -94,6 +115,7 @@ Options:
   --jobs N          Max parallel commands (0 = unlimited, auto = CPU count)
   -k, --keep-going  Continue other targets when one fails
   --no-cache        Ignore the file-dep cache and run everything
+  --no-deps         Run only the named targets, not their prerequisites
   --quiet, -q       Suppress command output, keep errors
   --explain         Print why commands run or are skipped
   --json            Machine-readable output (with --list)
@@ -103,8 +125,10 @@ Options:
   --timing          Print per-command elapsed time
   --dry-run         Show commands without executing them
   --list            List all available commands
+  --list-profiles   List the declared profiles (with --json: name, vars, active)
   -e, --env k=v     Override a variable (repeatable)
   --env-file PATH   Load environment variables from a dotenv-style file
+  --profile NAME    Apply a profile's variables (default: $CONSTRUCT_PROFILE)
   --resume          Rerun commands that failed in the last run (alias: --only-failed)
   --repeat N        Run the whole build N times (flaky detection)
   --flame           Print a per-statement flame graph after the run
@@ -113,24 +137,34 @@ Options:
   --tui             Live dashboard for the run (q detaches, Ctrl-C cancels)
   --container IMG   shell: run in this container image instead of the command's
   --force, -f       Overwrite existing files (init, import)
+  --follow          dev logs: keep printing new output
+  --detach, -d      dev: run the supervisor in the background (see dev attach, dev down)
   --doctor          Diagnose the environment, Constfile, tools, and cloud file
   --template NAME   init: template to scaffold (minimal, go, python, node, rust, monorepo)
   --file PATH       Target file (init, cloud push)
-  --output PATH     Output file (cloud pull)
-  --repo OWNER/REPO GitHub repository for cloud jobs (default: git remote)
+  --sign KEY        cloud push: sign definitions with this ed25519 private key
+  --output PATH     Output file (cloud pull, ci generate)
+  --repo OWNER/REPO GitHub repository or GitLab project for cloud jobs (default: git remote)
+  --backend NAME    Cloud backend: github or gitlab (default: from the git remote host)
   --ref BRANCH      Git ref to dispatch cloud jobs on (default: current branch)
   --workflow NAME   Workflow file name (cloud submit, default: construct.yml)
   --no-init         cloud submit: don't create the workflow file
   --wait            Follow a cloud job and stream its logs
+  --into DIR        cloud artifacts: unpack here (default: cloud-run-<id>)
+  --from-cloud ID   runs: merge the run records of a cloud run first
   --notify          Desktop notification when the run finishes
   --since REF       Only run targets affected by changes since a git ref
+  --frozen-lockfile Fail instead of fetching imports not pinned in .construct.lock
+  --secrets-key-file PATH  Unlock .construct-secrets with this file (default: $CONSTRUCT_SECRETS_KEYFILE)
   --port N          ui: serve on this port (default: random free port)
   --no-open         ui: print the URL without opening a browser
 
 Cloud subcommands:
   cloud list|pull|push                    cloud command definitions
-  cloud submit [targets...]               dispatch a GitHub Actions run
+  cloud keygen <key-file>                 create a key for cloud push --sign
+  cloud submit [targets...]               dispatch a GitHub Actions run or GitLab pipeline
   cloud status|logs|cancel <run-id>       inspect a dispatched run
+  cloud artifacts <run-id> [--into DIR]   download and unpack a run's artifacts
   cloud init-actions                      create .github/workflows/construct.yml
 
 Examples:
@@ -138,12 +172,19 @@ Examples:
   construct build test       Run 'build' and 'test' commands
   construct MyFile build     Run 'build' from MyFile
   construct --list           List available commands
+  construct test -- -run TestFoo ./pkg/...  Pass words to test's variadic argument
   construct --flame build    Run 'build' and show a timing flame graph
   construct cloud submit --wait test     Run 'test' on GitHub Actions
   construct import Makefile  Convert a Makefile to ./Constfile
+  construct import Procfile  Convert Procfile processes to services
+  construct export sh deploy > deploy.sh  Run 'deploy' where construct isn't installed
+  construct ci generate github --output .github/workflows/ci.yml test  Map 'test' and its prereqs to Actions jobs
   construct shell dev        Drop into the 'dev' command's environment
   construct --since origin/main build  Run 'build' only if affected since origin/main
+  construct //services/...:test  Run 'test' in every workspace package under services/
   construct dev              Supervise service commands (Ctrl-C stops all)
+  construct dev restart api  Restart 'api' in the dev session running in another terminal
+  construct dev -d           Start the dev session in the background (construct dev down stops it)
   construct install          Install shell completions
   construct install --hook pre-push -- build test  Install a git hook
 `)
@@ -157,6 +198,7 @@ func defineFlags(fs *flag.FlagSet, o *options) {
 	fs.BoolVarP(&o.showHelp, "help", "h", false, "Show help message")
 	fs.BoolVarP(&o.showVersion, "version", "v", false, "Show version")
 	fs.BoolVar(&o.showList, "list", false, "List commands")
+	fs.BoolVar(&o.listProfiles, "list-profiles", false, "List profiles")
 	fs.BoolVar(&o.debug, "debug", false, "Debug mode")
 	fs.BoolVar(&o.concurrent, "concurrent", false, "Run concurrently")
 	fs.BoolVar(&o.dryRun, "dry-run", false, "Dry run")
@@ -165,9 +207,10 @@ func defineFlags(fs *flag.FlagSet, o *options) {
 	fs.BoolVar(&o.timing, "timing", false, "Print per-command elapsed time")
 	fs.BoolVarP(&o.keepGoing, "keep-going", "k", false, "Continue other targets when one fails")
 	fs.BoolVar(&o.noCache, "no-cache", false, "Ignore the file-dep cache and run everything")
+	fs.BoolVar(&o.noDeps, "no-deps", false, "Run only the named targets, not their prerequisites")
 	fs.BoolVarP(&o.quiet, "quiet", "q", false, "Suppress command output, keep errors")
 	fs.BoolVar(&o.explain, "explain", false, "Print why commands run or are skipped")
-	fs.BoolVar(&o.json, "json", false, "Machine-readable output (with --list)")
+	fs.BoolVar(&o.json, "json", false, "Machine-readable output (with --list or --list-profiles)")
 	fs.BoolVar(&o.resume, "resume", false, "Rerun commands that failed in the last run")
 	fs.BoolVar(&o.resume, "only-failed", false, "Alias for --resume")
 	fs.IntVar(&o.repeat, "repeat", 0, "Run the build N times (flaky detection)")
@@ -176,12 +219,18 @@ func defineFlags(fs *flag.FlagSet, o *options) {
 	fs.BoolVar(&o.yes, "yes", false, "Auto-approve confirmations")
 	fs.BoolVar(&o.doctor, "doctor", false, "Diagnose the environment, Constfile, tools, and cloud file")
 	fs.BoolVarP(&o.force, "force", "f", false, "Overwrite existing files (init)")
+	fs.BoolVar(&o.follow, "follow", false, "dev logs: keep printing new output")
+	fs.BoolVarP(&o.detach, "detach", "d", false, "dev: run the supervisor in the background")
 	fs.StringVar(&o.template, "template", "", "Init template (minimal, go, python, node, rust, monorepo)")
 	fs.StringVar(&o.fileName, "file", "", "Target file (init, cloud push)")
-	fs.StringVar(&o.output, "output", "", "Output file (cloud pull)")
+	fs.StringVar(&o.sign, "sign", "", "cloud push: sign definitions with this ed25519 private key file")
+	fs.StringVar(&o.output, "output", "", "Output file (cloud pull, ci generate)")
 	fs.BoolVar(&o.wait, "wait", false, "Wait for a cloud job and stream its logs")
-	fs.StringVar(&o.repo, "repo", "", "GitHub repository owner/repo (cloud submit)")
+	fs.StringVar(&o.repo, "repo", "", "GitHub repository owner/repo or GitLab project path (cloud)")
+	fs.StringVar(&o.backend, "backend", "", "Cloud backend: github or gitlab (default: from the git remote host)")
 	fs.StringVar(&o.ref, "ref", "", "Git ref to dispatch on (cloud submit)")
+	fs.StringVar(&o.into, "into", "", "Directory to unpack into (cloud artifacts)")
+	fs.StringVar(&o.fromCloud, "from-cloud", "", "runs: merge run records from a cloud run's artifacts first")
 	fs.StringVar(&o.workflow, "workflow", "", "Workflow file name (cloud submit)")
 	fs.BoolVar(&o.noInit, "no-init", false, "Do not create the workflow file (cloud submit)")
 	fs.BoolVar(&o.notify, "notify", false, "Send a desktop notification when the run finishes")
@@ -191,6 +240,7 @@ func defineFlags(fs *flag.FlagSet, o *options) {
 	fs.BoolVar(&o.checkFormat, "check", false, "fmt: exit 1 when files are not formatted")
 	fs.StringVar(&o.jobsStr, "jobs", "", "Max parallel commands (0 = unlimited, auto = CPU count)")
 	fs.StringVar(&o.envFile, "env-file", "", "Load environment from file")
+	fs.StringVar(&o.profile, "profile", "", "Apply a profile's variables (default: $CONSTRUCT_PROFILE)")
 	fs.StringVar(&o.containerOverride, "container", "", "`shell`: run in this container image instead of the command's")
 	fs.BoolVar(&o.tui, "tui", false, "Live dashboard for the run (requires a terminal)")
 	fs.IntVar(&o.uiPort, "port", 0, "`ui`: port to serve on (default: random)")
@@ -198,6 +248,8 @@ func defineFlags(fs *flag.FlagSet, o *options) {
 	fs.StringVar(&o.shell, "shell", "", "Shell to run statements with (default: $SHELL; `install`: shell to install completions for)")
 	fs.StringArrayVarP(&o.overrides, "env", "e", []string{}, "Override variable (key=value)")
 	fs.StringVar(&o.since, "since", "", "Only run targets affected by changes since a git ref (e.g. origin/main)")
+	fs.BoolVar(&o.frozenLockfile, "frozen-lockfile", false, "Fail instead of fetching imports not pinned in .construct.lock")
+	fs.StringVar(&o.secretsKeyFile, "secrets-key-file", "", "Unlock .construct-secrets with this file")
 	fs.StringArrayVar(&o.hooks, "hook", []string{}, "install: git hook(s) to install (pre-commit, pre-push, ...); targets follow `--`")
 	fs.BoolVar(&o.uninstall, "uninstall", false, "install: remove installed completions or hooks")
 }